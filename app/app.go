@@ -25,13 +25,18 @@ THE SOFTWARE.
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/andrejacobs/ajtweet-cli/internal/hooks"
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
 	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
 	"github.com/fatih/color"
 	"github.com/google/uuid"
@@ -39,12 +44,28 @@ import (
 
 var (
 	ErrLockfileExists = errors.New("another instance is running and have acquired the lock file")
+	ErrNotApprover    = errors.New("not allowed to approve tweets")
 )
 
 // The main "context" used in the application.
 type Application struct {
-	config Config
-	tweets tweet.TweetList
+	config  Config
+	tweets  tweet.TweetList
+	logger  *logging.Logger // nil discards the log entries.
+	metrics *Metrics        // nil does not record any metrics.
+
+	hookRunner  *hooks.Runner   // The hooks run while sending, nil when none are configured.
+	credentials credentialCache // The credentials resolved while sending.
+}
+
+// Set the Logger used to log what the Application is doing, e.g. the tweets that were sent.
+func (app *Application) SetLogger(logger *logging.Logger) {
+	app.logger = logger
+}
+
+// Set the Metrics used to record the state of the queue and what happened while sending.
+func (app *Application) SetMetrics(metrics *Metrics) {
+	app.metrics = metrics
 }
 
 // Configure and load any existing tweets to be used by the Application.
@@ -57,9 +78,28 @@ func (app *Application) Configure(config Config) error {
 	return nil
 }
 
+// Return the configuration used by the Application.
+func (app *Application) Config() Config {
+	return app.config
+}
+
+// Reload the tweets from the datastore, discarding the tweets currently loaded.
+// Used by long running commands to pick up the changes made by the other commands.
+func (app *Application) Reload() error {
+	tweets := tweet.TweetList{}
+	if err := tweets.Load(app.config.Datastore.Filepath); err != nil {
+		return err
+	}
+	app.tweets = tweets
+	return nil
+}
+
 // Save any changes made by the Application.
 func (app *Application) Save() error {
 	//AJ### TODO: Need to make the save atomic so that we corrupt good data
+	if err := ensureParentDir(app.config.Datastore.Filepath); err != nil {
+		return err
+	}
 	if err := app.tweets.Save(app.config.Datastore.Filepath); err != nil {
 		return err
 	}
@@ -69,27 +109,181 @@ func (app *Application) Save() error {
 // Add a new scheduled tweet to the Application.
 // The scheduledTimeString must be in the RFC 3339 standard, e.g. 2006-03-05T10:42:01Z
 func (app *Application) Add(message string, scheduledTimeString string) error {
+	return app.AddWith(message, scheduledTimeString, AddOptions{})
+}
+
+// AddOptions specifies the optional values used when adding a new tweet.
+type AddOptions struct {
+	Draft         bool // Add the tweet as a draft that will not be sent until it is resumed.
+	NeedsApproval bool // The tweet needs to be approved before it will be sent. Always true when the configuration requires approval.
+
+	Account      string   // Reference to the configured account to send the tweet from, e.g. mastodon:product. The default Twitter account is used when empty.
+	Destinations []string // References to the accounts to cross-post the tweet to. Can not be combined with Account.
+	Media        []string // Paths of the media files to attach to the tweet.
+	InReplyTo    string   // Identifier of the post (on the account's service) the tweet is a reply to.
+	Tags         []string // Tags used for organising the tweets, e.g. a campaign name.
+}
+
+var (
+	ErrInvalidDestinations = errors.New("invalid destinations")
+)
+
+// Add a new tweet to the Application using the specified options.
+// The scheduledTimeString must be in the RFC 3339 standard, e.g. 2006-03-05T10:42:01Z
+func (app *Application) AddWith(message string, scheduledTimeString string, options AddOptions) error {
+	_, err := app.AddTweet(message, scheduledTimeString, options)
+	return err
+}
+
+// Add a new tweet to the Application using the specified options and return the tweet that was added.
+// The scheduledTimeString must be in the RFC 3339 standard, e.g. 2006-03-05T10:42:01Z
+func (app *Application) AddTweet(message string, scheduledTimeString string, options AddOptions) (tweet.Tweet, error) {
 	scheduledTime, err := parseTime(scheduledTimeString)
 	if err != nil {
-		return err
+		return tweet.Tweet{}, err
 	}
 
-	tweet := tweet.New(message, scheduledTime)
-	if err := app.tweets.Add(tweet); err != nil {
-		return err
+	if options.Account != "" && len(options.Destinations) > 0 {
+		return tweet.Tweet{}, fmt.Errorf("%w: an account can not be combined with destinations", ErrInvalidDestinations)
 	}
-	return nil
+
+	if options.InReplyTo != "" && len(options.Destinations) > 1 {
+		return tweet.Tweet{}, fmt.Errorf("%w: a reply can only be sent to a single destination", ErrInvalidDestinations)
+	}
+
+	account, err := app.account(options.Account)
+	if err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	if err := validateMessage(account, message); err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	destinations, err := app.resolveDestinations(message, options.Destinations)
+	if err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	media := make([]string, 0, len(options.Media))
+	for _, filePath := range options.Media {
+		// The media is only uploaded when the tweet is sent, possibly from another working directory
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return tweet.Tweet{}, err
+		}
+
+		if _, err := os.Stat(absPath); err != nil {
+			return tweet.Tweet{}, err
+		}
+		media = append(media, absPath)
+	}
+
+	tw := tweet.New(message, scheduledTime)
+	tw.Account = account.Ref()
+	if len(destinations) > 0 {
+		tw.Destinations = tweet.NewDestinations(destinations...)
+	}
+	tw.InReplyTo = options.InReplyTo
+	if len(media) > 0 {
+		tw.Media = media
+	}
+	if options.Draft {
+		tw.Status = tweet.StatusDraft
+	}
+	if options.NeedsApproval || app.config.Approval.Required {
+		tw.RequireApproval()
+	}
+	if len(options.Tags) > 0 {
+		tw.Tags = options.Tags
+	}
+
+	if err := app.tweets.Add(tw); err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	app.logger.Info("Tweet added", logging.F("tweet_id", tw.Id), logging.F("account", strings.Join(tw.Accounts(), ",")),
+		logging.F("scheduled_time", tw.ScheduledTime), logging.F("status", tw.Status))
+	return tw, nil
+}
+
+// Resolve the references of the accounts the message will be cross-posted to, e.g. product to mastodon:product.
+// The message is validated for each of the accounts and each account can only be specified once.
+func (app *Application) resolveDestinations(message string, refs []string) ([]string, error) {
+	destinations := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		destination, err := app.account(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := validateMessage(destination, message); err != nil {
+			return nil, fmt.Errorf("%s: %w", destination.Ref(), err)
+		}
+
+		for _, existing := range destinations {
+			if existing == destination.Ref() {
+				return nil, fmt.Errorf("%w: %q was specified more than once", ErrInvalidDestinations, existing)
+			}
+		}
+		destinations = append(destinations, destination.Ref())
+	}
+	return destinations, nil
+}
+
+// Pause the scheduled tweet matching the specified identifier so that it will not be sent until it is resumed.
+func (app *Application) Pause(id uuid.UUID) error {
+	return app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		return tw.Pause()
+	})
+}
+
+// Resume the draft, paused, failed or expired tweet matching the specified identifier so that it will be sent.
+func (app *Application) Resume(id uuid.UUID) error {
+	return app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		return tw.Resume()
+	})
+}
+
+// Approve the tweet matching the specified identifier on behalf of the approver.
+// If the configuration specifies a list of approvers then the approver must be one of them.
+func (app *Application) Approve(id uuid.UUID, approver string) error {
+	if !app.isApprover(approver) {
+		return fmt.Errorf("%w: %q", ErrNotApprover, approver)
+	}
+
+	return app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		return tw.Approve(approver, time.Now())
+	})
+}
+
+func (app *Application) isApprover(name string) bool {
+	if len(app.config.Approval.Approvers) == 0 {
+		return true
+	}
+
+	for _, approver := range app.config.Approval.Approvers {
+		if strings.EqualFold(approver, strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
 }
 
 // Write the list of scheduled tweets that still need to be sent to the specified io.Writer.
 func (app *Application) List(out io.Writer) error {
+	return writeTweets(out, app.tweets.List(), app.idFormatter(false))
+}
+
+// Write the tweets in a human readable form to the specified io.Writer.
+func writeTweets(out io.Writer, tweets []tweet.Tweet, formatId idFormatter) error {
 
 	whiteBold := color.New(color.FgWhite, color.Bold).SprintFunc()
 	greenBold := color.New(color.FgGreen, color.Bold).SprintFunc()
 	cyan := color.New(color.FgCyan).SprintFunc()
 
-	for _, tw := range app.tweets.List() {
-		if _, err := fmt.Fprintf(out, "id: %s\n", cyan(tw.Id)); err != nil {
+	for _, tw := range tweets {
+		if _, err := fmt.Fprintf(out, "id: %s\n", cyan(formatId(tw.Id))); err != nil {
 			return err
 		}
 
@@ -97,12 +291,62 @@ func (app *Application) List(out io.Writer) error {
 			return err
 		}
 
-		if tw.SendNow() {
+		if tw.IsScheduled() && tw.SendNow() {
 			if _, err := fmt.Fprintf(out, " %s", greenBold("[send now!]")); err != nil {
 				return err
 			}
 		}
 
+		if _, err := fmt.Fprintf(out, "\nstatus: %s", tw.Status); err != nil {
+			return err
+		}
+
+		if tw.Error != "" {
+			if _, err := fmt.Fprintf(out, "\nerror: %s (attempts: %d)", tw.Error, tw.Attempts); err != nil {
+				return err
+			}
+		}
+
+		if tw.Approval != nil {
+			if _, err := fmt.Fprintf(out, "\napproved: %s (%s)", tw.Approval.By, tw.Approval.Time.Format(time.RFC3339)); err != nil {
+				return err
+			}
+		} else if tw.NeedsApproval {
+			if _, err := fmt.Fprint(out, "\napproved: no"); err != nil {
+				return err
+			}
+		}
+
+		if tw.Account != "" {
+			if _, err := fmt.Fprintf(out, "\naccount: %s", tw.Account); err != nil {
+				return err
+			}
+		}
+
+		for _, destination := range tw.Destinations {
+			if _, err := fmt.Fprintf(out, "\nto: %s (%s)", destination.Account, destinationDetails(destination)); err != nil {
+				return err
+			}
+		}
+
+		if len(tw.Tags) > 0 {
+			if _, err := fmt.Fprintf(out, "\ntags: %s", strings.Join(tw.Tags, ", ")); err != nil {
+				return err
+			}
+		}
+
+		if len(tw.Media) > 0 {
+			if _, err := fmt.Fprintf(out, "\nmedia: %s", strings.Join(tw.Media, ", ")); err != nil {
+				return err
+			}
+		}
+
+		if tw.InReplyTo != "" {
+			if _, err := fmt.Fprintf(out, "\nreply to: %s", tw.InReplyTo); err != nil {
+				return err
+			}
+		}
+
 		if _, err := fmt.Fprintf(out, "\ntweet: %s\n\n", whiteBold(tw.Message)); err != nil {
 			return err
 		}
@@ -111,9 +355,26 @@ func (app *Application) List(out io.Writer) error {
 	return nil
 }
 
+// Describe the delivery status of the destination, e.g. "sent: 101".
+func destinationDetails(destination tweet.Destination) string {
+	switch {
+	case destination.Status == tweet.DestinationSent && destination.URL != "":
+		return fmt.Sprintf("%s: %s", destination.Status, destination.URL)
+	case destination.Status == tweet.DestinationSent && destination.RemoteId != "":
+		return fmt.Sprintf("%s: %s", destination.Status, destination.RemoteId)
+	case destination.Error != "":
+		return fmt.Sprintf("%s: %s", destination.Status, destination.Error)
+	}
+	return string(destination.Status)
+}
+
 // Write the list of scheduled tweets that still need to be sent in a JSON encoding to the specified io.Writer.
 func (app *Application) ListJSON(out io.Writer) error {
-	tweets := app.tweets.List()
+	return writeTweetsJSON(out, app.tweets.List())
+}
+
+// Write the tweets in a JSON encoding to the specified io.Writer.
+func writeTweetsJSON(out io.Writer, tweets []tweet.Tweet) error {
 	jsonData, err := json.Marshal(tweets)
 	if err != nil {
 		return err
@@ -123,19 +384,29 @@ func (app *Application) ListJSON(out io.Writer) error {
 	return nil
 }
 
-// Delete the tweet matching the specified identifier.
+// Delete the tweet matching the specified identifier or unique prefix of an identifier.
 func (app *Application) Delete(idString string) error {
-	id, err := uuid.Parse(idString)
+	id, err := app.tweets.Resolve(idString)
 	if err != nil {
 		return err
 	}
 
-	return app.tweets.Delete(id)
+	if err := app.tweets.Delete(id); err != nil {
+		return err
+	}
+
+	app.logger.Info("Tweet deleted", logging.F("tweet_id", id))
+	return nil
 }
 
 // Delete all the tweets.
 func (app *Application) DeleteAll() error {
-	return app.tweets.DeleteAll()
+	if err := app.tweets.DeleteAll(); err != nil {
+		return err
+	}
+
+	app.logger.Info("All tweets deleted")
+	return nil
 }
 
 var (
@@ -144,73 +415,256 @@ var (
 )
 
 // Send any scheduled tweets.
-func (app *Application) Send(out io.Writer, dryRun bool) error {
+// Each tweet is sent from the account it was added with, or the default Twitter account.
+// Sending is stopped when the context is cancelled or the send.deadline has been reached.
+func (app *Application) Send(ctx context.Context, out io.Writer, dryRun bool) error {
+	_, err := app.SendWith(ctx, out, SendOptions{DryRun: dryRun})
+	return err
+}
 
-	var client *twitterClient
+// SendOptions specifies how the tweets will be sent.
+type SendOptions struct {
+	DryRun bool // Tweets will not be sent and also not be deleted.
+}
 
-	configure := func(out io.Writer, dryRun bool) error {
-		if app.config.Send.Authentication.APIKey == "" {
-			return fmt.Errorf("%w: API Key", ErrMissingAuth)
-		}
+// Send any scheduled tweets and return the report describing what happened to each of the tweets.
+// The report is returned even when an error is returned.
+func (app *Application) SendWith(ctx context.Context, out io.Writer, options SendOptions) (*SendReport, error) {
+	dryRun := options.DryRun
+	report := newSendReport(dryRun)
 
-		if app.config.Send.Authentication.APISecret == "" {
-			return fmt.Errorf("%w: API Secret", ErrMissingAuth)
-		}
+	if app.config.Send.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(app.config.Send.Deadline)*time.Second)
+		defer cancel()
+	}
 
-		if app.config.Send.Authentication.OAuth1.Token == "" {
-			return fmt.Errorf("%w: OAuth 1 User token", ErrMissingAuth)
-		}
+	var senders *senderPool
 
-		if app.config.Send.Authentication.OAuth1.Secret == "" {
-			return fmt.Errorf("%w: OAuth 1 User secret", ErrMissingAuth)
-		}
+	configure := func(out io.Writer, dryRun bool) error {
+		// The credentials that could not be resolved the previous time (e.g. by the daemon) are tried again
+		app.credentials.forgetErrors()
 
 		var err error
-		client, err = newOAuth1Client(app.config.Send.Authentication)
-		if err != nil {
+		if senders, err = app.newSenderPool(); err != nil {
 			return err
 		}
+		app.hookRunner, err = app.configureHooks()
+		return err
+	}
 
-		return nil
+	actual := func(out io.Writer, dryRun bool, tweet tweet.Tweet) error {
+		return app.deliver(ctx, out, dryRun, senders, tweet, report)
 	}
 
+	err := app.send(ctx, out, dryRun, report, configure, actual)
+	report.finish(err)
+
+	now := time.Now()
+	app.metrics.observeQueue(&app.tweets, now)
+	app.metrics.observeSendFinished(now)
+	return report, err
+}
+
+var (
+	ErrDeliveryFailed = errors.New("failed to send to some of the destinations")
+)
+
+// Send the tweet to each of its destinations that it has not been sent to yet.
+// The delivery to each destination is recorded so that only the failed destinations will be retried.
+func (app *Application) deliver(ctx context.Context, out io.Writer, dryRun bool, senders *senderPool, tw tweet.Tweet,
+	report *SendReport) error {
 	greenBold := color.New(color.FgHiGreen, color.Bold).SprintFunc()
 
-	actual := func(out io.Writer, dryRun bool, tweet tweet.Tweet) error {
+	targets := tw.Targets()
+	crossPost := len(tw.Destinations) > 0
+	var failures []string
+	var lastErr error
+
+	for _, destination := range targets {
+		if destination.Status == tweet.DestinationSent {
+			continue
+		}
+
+		if crossPost {
+			fmt.Fprintf(out, "to: %s\n", destination.Account)
+		}
+
+		account, err := app.account(destination.Account)
+		entry := DestinationReport{Account: destination.Account, Service: serviceKey(account)}
+
 		if dryRun {
-			return nil
+			// The credentials are not resolved, e.g. a password manager is not asked for them
+			if err == nil {
+				err = senders.check(account)
+			}
+			if err != nil {
+				return err
+			}
+			entry.Outcome = OutcomeDryRun
+			report.destination(entry)
+			app.metrics.observeDestination(entry, 0)
+			continue
 		}
 
-		id, err := sendTweet(client, tweet.Message)
-		if err != nil {
+		logger := app.logger.With(logging.F("tweet_id", tw.Id), logging.F("account", destination.Account),
+			logging.F("attempt", tw.Attempts+1))
+
+		var sender Sender
+		var result PostResult
+		start := time.Now()
+		if err == nil {
+			sender, err = senders.sender(ctx, account)
+		}
+		if err == nil {
+			result, err = post(ctx, sender, tw, destination.Account)
+		}
+		duration := time.Since(start)
+
+		entry.DurationMs = duration.Milliseconds()
+		if sender != nil {
+			entry.RateLimit = sender.RateLimit()
+		}
+
+		if err != nil && ctx.Err() != nil {
+			// Sending was stopped, the destinations that have not been sent to will be retried the next time
+			logger.Warn("Sending tweet interrupted", logging.F("duration", duration), logging.F("error", err))
+			entry.Outcome, entry.Error = OutcomeSkipped, err.Error()
+			report.destination(entry)
+			app.metrics.observeDestination(entry, duration)
 			return err
 		}
 
-		fmt.Fprintf(out, "Twitter identifier: %s\n", greenBold(id))
+		if err != nil {
+			logger.Warn("Failed to send tweet", logging.F("duration", duration), logging.F("error", err))
+			failures = append(failures, fmt.Sprintf("%s: %s", destination.Account, err))
+			lastErr = err
+			entry.Outcome, entry.Error = OutcomeFailed, err.Error()
+			app.tweets.Update(tw.Id, func(t *tweet.Tweet) error {
+				t.DeliveryFailed(destination.Account, err)
+				return nil
+			})
+			app.runHooks(ctx, out, hooks.Payload{Event: hooks.EventSendFailed, Tweet: hookTweet(tw),
+				Account: destination.Account, Service: entry.Service, Error: err.Error()})
+		} else {
+			logger.Info("Tweet sent", logging.F("duration", duration), logging.F(remoteIdKey(account), result.Id),
+				logging.F("url", result.URL))
+			entry.Outcome, entry.RemoteId, entry.URL = OutcomeSent, result.Id, result.URL
+			fmt.Fprintf(out, "%s identifier: %s\n", serviceName(account), greenBold(result.Id))
+			if result.URL != "" {
+				fmt.Fprintf(out, "URL: %s\n", result.URL)
+			}
+			app.tweets.Update(tw.Id, func(t *tweet.Tweet) error {
+				t.Delivered(destination.Account, result.Id, result.URL)
+				return nil
+			})
+			app.runHooks(ctx, out, hooks.Payload{Event: hooks.EventAfterSend, Tweet: hookTweet(tw),
+				Account: destination.Account, Service: entry.Service, RemoteId: result.Id, URL: result.URL})
+		}
+
+		report.destination(entry)
+		app.metrics.observeDestination(entry, duration)
+
+		if crossPost {
+			if err := app.Save(); err != nil {
+				return err
+			}
+		}
+	}
 
+	if len(failures) == 0 {
 		return nil
 	}
 
-	return app.send(out, dryRun, configure, actual)
+	if !crossPost {
+		return lastErr
+	}
+
+	return fmt.Errorf("%w (%d of %d failed): %s", ErrDeliveryFailed, len(failures), len(targets), strings.Join(failures, "; "))
+}
+
+// Return the service of the account, e.g. twitter.
+func serviceKey(account Account) string {
+	if account.Service == "" {
+		return ServiceTwitter
+	}
+	return strings.ToLower(account.Service)
+}
+
+// Return the log field key for the identifier assigned by the account's service, e.g. twitter_id.
+func remoteIdKey(account Account) string {
+	return serviceKey(account) + "_id"
 }
 
 type sendConfigure func(out io.Writer, dryRun bool) error
 type sendActual func(out io.Writer, dryRun bool, tweet tweet.Tweet) error
 
-func (app *Application) send(out io.Writer, dryRun bool,
+// The report (which may be nil) is used to record what happened to each of the tweets.
+func (app *Application) send(ctx context.Context, out io.Writer, dryRun bool, report *SendReport,
 	configure sendConfigure, actual sendActual) error {
 
 	if err := configure(out, dryRun); err != nil {
 		return err
 	}
 
-	sendable := app.tweets.ToSend(app.config.Send.Max, time.Now())
+	now := time.Now()
+
+	// The lock is held, so the tweets still being sent were left behind by a send that was killed. They might have
+	// been posted, which is why they are marked as failed to be checked and resumed instead of being sent again.
+	// The cross-posts that were recorded as sent to all of their destinations are kept as sent.
+	if !dryRun {
+		sent, failed := app.tweets.RecoverSending(errSendKilled)
+		for _, tw := range sent {
+			fmt.Fprintf(out, "Sent tweet with identifier: %q, it was sent to all of its destinations before the send was killed\n",
+				tw.Id.String())
+			app.logger.Warn("Tweet was left being sent after it was delivered", logging.F("tweet_id", tw.Id))
+		}
+		for _, tw := range failed {
+			fmt.Fprintf(out, "Failed tweet with identifier: %q, %s\n", tw.Id.String(), errSendKilled)
+			app.logger.Warn("Tweet was left being sent", logging.F("tweet_id", tw.Id))
+		}
+
+		if len(sent)+len(failed) > 0 {
+			if err := app.Save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if app.config.Send.ExpireAfter > 0 {
+		expireBefore := now.Add(-time.Duration(app.config.Send.ExpireAfter) * time.Hour)
+		expired := app.tweets.Expire(expireBefore)
+		for _, tw := range expired {
+			fmt.Fprintf(out, "Expired tweet with identifier: %q\n", tw.Id.String())
+			report.expired(tw)
+			for _, destination := range tw.Targets() {
+				account, _ := app.account(destination.Account)
+				app.metrics.observeExpired(serviceKey(account))
+			}
+			app.logger.Warn("Tweet expired", logging.F("tweet_id", tw.Id), logging.F("scheduled_time", tw.ScheduledTime))
+		}
+
+		if len(expired) > 0 && !dryRun {
+			if err := app.Save(); err != nil {
+				return err
+			}
+		}
+	}
+
+	sendable := app.tweets.ToSend(app.config.Send.Max, now)
 	sendCount := len(sendable)
+	app.logger.Info("Sending tweets", logging.F("count", sendCount), logging.F("dry_run", dryRun))
+	defer report.skipRemaining(sendable)
 
 	whiteBold := color.New(color.FgWhite, color.Bold).SprintFunc()
 	cyan := color.New(color.FgCyan).SprintFunc()
 
+	sent := 0
 	for i, tweet := range sendable {
+		if ctx.Err() != nil {
+			return app.sendStopped(ctx, out, sent, sendCount)
+		}
+
 		fmt.Fprintf(out, "Sending %d of %d\n", i+1, sendCount)
 
 		if _, err := fmt.Fprintf(out, "id: %s\n", cyan(tweet.Id)); err != nil {
@@ -221,10 +675,50 @@ func (app *Application) send(out io.Writer, dryRun bool,
 			return err
 		}
 
+		report.begin(tweet)
+		if !dryRun {
+			// The before_send hooks can veto sending the tweet, it remains scheduled and will be checked again the next time
+			if err := app.runHooks(ctx, out, hooks.Payload{Event: hooks.EventBeforeSend, Tweet: hookTweet(tweet),
+				Account: tweet.Account}); err != nil {
+				report.end(OutcomeSkipped, err)
+				fmt.Fprintln(out)
+				continue
+			}
+
+			// Mark the tweet as being sent so that it will not be sent again should the app be terminated
+			// before the tweet could be removed.
+			if err := app.sendStarted(tweet.Id); err != nil {
+				return err
+			}
+		}
+
 		if err := actual(out, dryRun, tweet); err != nil {
+			if ctx.Err() != nil {
+				// Sending was stopped and the tweet did not fail, it will be sent the next time
+				report.end(OutcomeSkipped, ctx.Err())
+				if !dryRun {
+					if err := app.sendInterrupted(tweet.Id); err != nil {
+						return err
+					}
+				}
+				return app.sendStopped(ctx, out, sent, sendCount)
+			}
+
+			report.end(OutcomeFailed, err)
+			if !dryRun {
+				if err := app.sendFailed(tweet.Id, err); err != nil {
+					return err
+				}
+			}
 			return err
 		}
 
+		if dryRun {
+			report.end(OutcomeDryRun, nil)
+		} else {
+			report.end(OutcomeSent, nil)
+		}
+
 		if err := app.tweets.Delete(tweet.Id); err != nil {
 			return err
 		}
@@ -234,16 +728,99 @@ func (app *Application) send(out io.Writer, dryRun bool,
 				return err
 			}
 		}
+		sent++
 
 		if (app.config.Send.Delay > 0) && (i < sendCount-1) {
 			fmt.Fprintf(out, "Delaying for %d seconds ...\n", app.config.Send.Delay)
-			time.Sleep(time.Duration(app.config.Send.Delay) * time.Second)
+
+			delay := time.NewTimer(time.Duration(app.config.Send.Delay) * time.Second)
+			select {
+			case <-ctx.Done():
+				delay.Stop()
+				return app.sendStopped(ctx, out, sent, sendCount)
+			case <-delay.C:
+			}
 		}
 	}
 
+	app.logger.Info("Sending finished", logging.F("sent", sent), logging.F("count", sendCount))
+
+	if !dryRun && sent > 0 && app.queueEmpty() {
+		app.runHooks(ctx, out, hooks.Payload{Event: hooks.EventQueueEmpty})
+	}
 	return nil
 }
 
+// Return true when there are no tweets left waiting to be sent.
+func (app *Application) queueEmpty() bool {
+	for _, tw := range app.tweets.Tweets {
+		switch tw.Status {
+		case tweet.StatusScheduled, tweet.StatusPending, tweet.StatusSending:
+			return false
+		}
+	}
+	return true
+}
+
+// Write the summary of a send that was stopped because the context was cancelled (e.g. interrupted) or
+// the deadline was reached. Return the error describing why sending was stopped.
+func (app *Application) sendStopped(ctx context.Context, out io.Writer, sent int, count int) error {
+	reason := "interrupted"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		reason = "the deadline was reached"
+	}
+
+	app.logger.Warn("Sending stopped", logging.F("reason", reason), logging.F("sent", sent), logging.F("count", count))
+
+	fmt.Fprintf(out, "Stopped sending, %s. Sent %d of %d tweet(s), the remaining tweets will be sent the next time.\n",
+		reason, sent, count)
+	return fmt.Errorf("sending stopped after %d of %d tweet(s): %w", sent, count, ctx.Err())
+}
+
+// The error of the tweets that were still being sent when a previous send was killed.
+const errSendKilled = "the send was killed while sending the tweet, check if it was posted before resuming it"
+
+// Mark the tweet as being sent and save the change.
+func (app *Application) sendStarted(id uuid.UUID) error {
+	if err := app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		tw.Status = tweet.StatusSending
+		return nil
+	}); err != nil {
+		return err
+	}
+	return app.Save()
+}
+
+// Return the tweet to the scheduled status (without counting it as a failed attempt) and save the change.
+func (app *Application) sendInterrupted(id uuid.UUID) error {
+	if err := app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		tw.Status = tweet.StatusScheduled
+		return nil
+	}); err != nil {
+		return err
+	}
+	return app.Save()
+}
+
+// Record the failed attempt at sending the tweet and save the change.
+// The tweet will be tried again the next time unless the maximum number of attempts has been reached.
+func (app *Application) sendFailed(id uuid.UUID, sendErr error) error {
+	if err := app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		tw.Attempts++
+		tw.Error = sendErr.Error()
+		tw.Status = tweet.StatusScheduled
+		if tw.Attempts >= app.config.Send.MaxAttempts {
+			tw.Status = tweet.StatusFailed
+			app.logger.Error("Tweet failed", logging.F("tweet_id", tw.Id), logging.F("attempt", tw.Attempts),
+				logging.F("error", sendErr))
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	return app.Save()
+}
+
 func parseTime(timeString string) (time.Time, error) {
 	return time.Parse(time.RFC3339, timeString)
 }
@@ -260,6 +837,10 @@ func (app *Application) AcquireLock() error {
 		return fmt.Errorf("%w: %s", ErrLockfileExists, app.config.Lockfile)
 	}
 
+	if err := ensureParentDir(app.config.Lockfile); err != nil {
+		return err
+	}
+
 	pid := fmt.Sprintf("pid: %d\n", os.Getpid())
 	if err := os.WriteFile(app.config.Lockfile, []byte(pid), 0644); err != nil {
 		return err
@@ -271,3 +852,21 @@ func (app *Application) AcquireLock() error {
 func (app *Application) ReleaseLock() error {
 	return os.Remove(app.config.Lockfile)
 }
+
+// Acquire the lock, reload the tweets from the datastore and then call fn before releasing the lock again.
+// Used by long running commands to share the datastore safely with the other commands.
+func (app *Application) WithLock(fn func() error) error {
+	if err := app.AcquireLock(); err != nil {
+		return err
+	}
+
+	err := app.Reload()
+	if err == nil {
+		err = fn()
+	}
+
+	if releaseErr := app.ReleaseLock(); err == nil {
+		err = releaseErr
+	}
+	return err
+}
@@ -23,10 +23,12 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -138,22 +140,26 @@ func TestList(t *testing.T) {
 	}
 
 	expectedTweets := app.tweets.List()
+	shortIds := app.tweets.ShortIds(tweet.ShortIdLength)
 	expected := fmt.Sprintf(
 		`id: %s
 time: %s [send now!]
+status: scheduled
 tweet: %s
 
 id: %s
 time: %s [send now!]
+status: scheduled
 tweet: %s
 
 id: %s
 time: %s
+status: scheduled
 tweet: %s
 
-`, expectedTweets[0].Id, expectedTweets[0].ScheduledTime.Format(time.RFC3339), expectedTweets[0].Message,
-		expectedTweets[1].Id, expectedTweets[1].ScheduledTime.Format(time.RFC3339), expectedTweets[1].Message,
-		expectedTweets[2].Id, expectedTweets[2].ScheduledTime.Format(time.RFC3339), expectedTweets[2].Message)
+`, shortIds[expectedTweets[0].Id], expectedTweets[0].ScheduledTime.Format(time.RFC3339), expectedTweets[0].Message,
+		shortIds[expectedTweets[1].Id], expectedTweets[1].ScheduledTime.Format(time.RFC3339), expectedTweets[1].Message,
+		shortIds[expectedTweets[2].Id], expectedTweets[2].ScheduledTime.Format(time.RFC3339), expectedTweets[2].Message)
 
 	result := buffer.String()
 	if result != expected {
@@ -178,7 +184,7 @@ func TestListJSON(t *testing.T) {
 	}
 
 	expectedTweets := app.tweets.List()
-	expected := fmt.Sprintf(`[{"id":"%s","message":"%s","scheduledTime":"%s"},{"id":"%s","message":"%s","scheduledTime":"%s"}]`,
+	expected := fmt.Sprintf(`[{"id":"%s","message":"%s","scheduledTime":"%s","status":"scheduled"},{"id":"%s","message":"%s","scheduledTime":"%s","status":"scheduled"}]`,
 		expectedTweets[0].Id, expectedTweets[0].Message, expectedTweets[0].ScheduledTime.Format(time.RFC3339),
 		expectedTweets[1].Id, expectedTweets[1].Message, expectedTweets[1].ScheduledTime.Format(time.RFC3339))
 
@@ -219,6 +225,16 @@ func TestDelete(t *testing.T) {
 		t.Fatal("Expected an error since the item does not exist in the list")
 	}
 
+	// Delete using a unique prefix of the identifier
+	app.Add("Tweet 3", time.Now().Format(time.RFC3339))
+	if err := app.Delete(app.tweets.Tweets[0].Id.String()[:8]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(app.tweets.Tweets) != 0 {
+		t.Fatal("Expected all tweets to have been deleted")
+	}
+
 }
 
 func TestDeleteAll(t *testing.T) {
@@ -269,7 +285,7 @@ func TestSend(t *testing.T) {
 	}
 
 	var buffer bytes.Buffer
-	if err := app.send(&buffer, false, configure, actual); err != nil {
+	if err := app.send(context.Background(), &buffer, false, nil, configure, actual); err != nil {
 		t.Fatal(err)
 	}
 
@@ -331,7 +347,7 @@ func TestSendMaxAndEmpty(t *testing.T) {
 	}
 
 	// Send first batch
-	if err := app.send(io.Discard, false, configure, actual); err != nil {
+	if err := app.send(context.Background(), io.Discard, false, nil, configure, actual); err != nil {
 		t.Fatal(err)
 	}
 
@@ -340,7 +356,7 @@ func TestSendMaxAndEmpty(t *testing.T) {
 	}
 
 	// Send second batch
-	if err := app.send(io.Discard, false, configure, actual); err != nil {
+	if err := app.send(context.Background(), io.Discard, false, nil, configure, actual); err != nil {
 		t.Fatal(err)
 	}
 
@@ -350,7 +366,7 @@ func TestSendMaxAndEmpty(t *testing.T) {
 
 	// Send when there is nothing to send
 	var buffer bytes.Buffer
-	if err := app.send(&buffer, false, configure, actual); err != nil {
+	if err := app.send(context.Background(), &buffer, false, nil, configure, actual); err != nil {
 		t.Fatal(err)
 	}
 
@@ -359,12 +375,395 @@ func TestSendMaxAndEmpty(t *testing.T) {
 	}
 }
 
+func TestPauseAndResume(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet", time.Now().Format(time.RFC3339))
+	id := app.tweets.Tweets[0].Id
+
+	if err := app.Pause(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(app.tweets.ToSend(100, time.Now())); count != 0 {
+		t.Fatalf("Expected a paused tweet not to be sent. Result: %d", count)
+	}
+
+	if err := app.Pause(id); !errors.Is(err, tweet.ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", tweet.ErrInvalidTransition, err)
+	}
+
+	if err := app.Resume(id); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(app.tweets.ToSend(100, time.Now())); count != 1 {
+		t.Fatalf("Expected a resumed tweet to be sent. Result: %d", count)
+	}
+
+	if err := app.Resume(uuid.New()); !errors.Is(err, tweet.ErrNotExists) {
+		t.Fatalf("Expected error: %q. Result: %q", tweet.ErrNotExists, err)
+	}
+}
+
+func TestAddDraft(t *testing.T) {
+	app := Application{}
+	if err := app.AddWith("Draft", time.Now().Format(time.RFC3339), AddOptions{Draft: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := app.tweets.Tweets[0].Status; status != tweet.StatusDraft {
+		t.Fatalf("Expected status: %q. Result: %q", tweet.StatusDraft, status)
+	}
+
+	if count := len(app.tweets.ToSend(100, time.Now())); count != 0 {
+		t.Fatalf("Expected a draft not to be sent. Result: %d", count)
+	}
+}
+
+func TestSendFailedAttempts(t *testing.T) {
+	app := Application{}
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 100
+	app.config.Send.MaxAttempts = 2
+
+	app.Add("Tweet", time.Now().Format(time.RFC3339))
+
+	configure := func(out io.Writer, dryRun bool) error {
+		return nil
+	}
+
+	sendErr := errors.New("service unavailable")
+	actual := func(out io.Writer, dryRun bool, tweet tweet.Tweet) error {
+		return sendErr
+	}
+
+	// First attempt fails and the tweet will be tried again
+	if err := app.send(context.Background(), io.Discard, false, nil, configure, actual); !errors.Is(err, sendErr) {
+		t.Fatalf("Expected error: %q. Result: %q", sendErr, err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if tw.Status != tweet.StatusScheduled || tw.Attempts != 1 || tw.Error != sendErr.Error() {
+		t.Fatalf("Expected the failed attempt to be recorded. Result: %s", tw)
+	}
+
+	// Second attempt fails and the tweet will not be tried again
+	if err := app.send(context.Background(), io.Discard, false, nil, configure, actual); !errors.Is(err, sendErr) {
+		t.Fatalf("Expected error: %q. Result: %q", sendErr, err)
+	}
+
+	tw = app.tweets.Tweets[0]
+	if tw.Status != tweet.StatusFailed || tw.Attempts != 2 {
+		t.Fatalf("Expected the tweet to have failed. Result: %s", tw)
+	}
+
+	// The failed status was saved
+	loaded := Application{}
+	if err := loaded.tweets.Load(tempFile); err != nil {
+		t.Fatal(err)
+	}
+	if status := loaded.tweets.Tweets[0].Status; status != tweet.StatusFailed {
+		t.Fatalf("Expected status: %q. Result: %q", tweet.StatusFailed, status)
+	}
+
+	if err := app.send(context.Background(), io.Discard, false, nil, configure, actual); err != nil {
+		t.Fatalf("Expected failed tweets not to be sent. Result: %q", err)
+	}
+}
+
+func TestSendInterrupted(t *testing.T) {
+	app := Application{}
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 100
+	app.config.Send.MaxAttempts = 3
+
+	now := time.Now()
+	app.Add("First", now.Add(-2*time.Minute).Format(time.RFC3339))
+	app.Add("Second", now.Add(-time.Minute).Format(time.RFC3339))
+	app.Add("Third", now.Format(time.RFC3339))
+
+	configure := func(out io.Writer, dryRun bool) error {
+		return nil
+	}
+
+	// The second tweet is interrupted while it is being sent
+	ctx, cancel := context.WithCancel(context.Background())
+	actual := func(out io.Writer, dryRun bool, tw tweet.Tweet) error {
+		if tw.Message == "Second" {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if err := app.send(ctx, &buffer, false, nil, configure, actual); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the send to be cancelled. Result: %v", err)
+	}
+
+	if !strings.Contains(buffer.String(), "Sent 1 of 3 tweet(s)") {
+		t.Fatalf("Expected a summary. Result: %q", buffer.String())
+	}
+
+	// The interrupted tweet is not a failed attempt and the state was saved
+	loaded := Application{}
+	if err := loaded.tweets.Load(tempFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(loaded.tweets.Tweets); count != 2 {
+		t.Fatalf("Expected 2 tweets to remain. Result: %d", count)
+	}
+
+	for _, tw := range loaded.tweets.Tweets {
+		if tw.Status != tweet.StatusScheduled || tw.Attempts != 0 {
+			t.Fatalf("Expected the tweet to still be scheduled. Result: %s", tw)
+		}
+	}
+}
+
+func TestSendInterruptedWhileDelaying(t *testing.T) {
+	app := Application{}
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 100
+	app.config.Send.Delay = 60
+
+	now := time.Now()
+	app.Add("First", now.Add(-time.Minute).Format(time.RFC3339))
+	app.Add("Second", now.Format(time.RFC3339))
+
+	configure := func(out io.Writer, dryRun bool) error {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	actual := func(out io.Writer, dryRun bool, tw tweet.Tweet) error {
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return nil
+	}
+
+	start := time.Now()
+	if err := app.send(ctx, io.Discard, false, nil, configure, actual); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the send to be cancelled. Result: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("Expected the delay to be interrupted. Result: %s", elapsed)
+	}
+
+	if count := len(app.tweets.Tweets); count != 1 || app.tweets.Tweets[0].Message != "Second" {
+		t.Fatalf("Expected only the second tweet to remain. Result: %v", app.tweets.Tweets)
+	}
+}
+
+func TestSendExpires(t *testing.T) {
+	app := Application{}
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 100
+	app.config.Send.ExpireAfter = 24
+
+	app.Add("Old", time.Now().Add(-48*time.Hour).Format(time.RFC3339))
+	app.Add("New", time.Now().Format(time.RFC3339))
+	expiredId := app.tweets.Tweets[0].Id
+
+	configure := func(out io.Writer, dryRun bool) error {
+		return nil
+	}
+
+	var sent []string
+	actual := func(out io.Writer, dryRun bool, tweet tweet.Tweet) error {
+		sent = append(sent, tweet.Message)
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if err := app.send(context.Background(), &buffer, false, nil, configure, actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != 1 || sent[0] != "New" {
+		t.Fatalf("Expected only the new tweet to be sent. Result: %v", sent)
+	}
+
+	if !strings.Contains(buffer.String(), fmt.Sprintf("Expired tweet with identifier: %q", expiredId)) {
+		t.Fatalf("Expected the expired tweet to be reported. Result: %q", buffer.String())
+	}
+
+	if len(app.tweets.Tweets) != 1 || app.tweets.Tweets[0].Status != tweet.StatusExpired {
+		t.Fatalf("Expected the old tweet to have expired. Result: %v", app.tweets.Tweets)
+	}
+}
+
+func TestSendFailsTweetsLeftSending(t *testing.T) {
+	app := Application{}
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 100
+
+	app.Add("Killed", time.Now().Format(time.RFC3339))
+	app.Add("Scheduled", time.Now().Format(time.RFC3339))
+	killedId := app.tweets.Tweets[0].Id
+	app.tweets.Tweets[0].Status = tweet.StatusSending
+
+	// Killed after it was sent to all of its destinations
+	delivered := tweet.New("Delivered", time.Now())
+	delivered.Status = tweet.StatusSending
+	delivered.Destinations = tweet.NewDestinations("mastodon:product", "bluesky:news")
+	delivered.Delivered("mastodon:product", "1", "")
+	delivered.Delivered("bluesky:news", "2", "")
+	app.tweets.Add(delivered)
+
+	configure := func(out io.Writer, dryRun bool) error {
+		return nil
+	}
+
+	var sent []string
+	actual := func(out io.Writer, dryRun bool, tweet tweet.Tweet) error {
+		sent = append(sent, tweet.Message)
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if err := app.send(context.Background(), &buffer, false, nil, configure, actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sent) != 1 || sent[0] != "Scheduled" {
+		t.Fatalf("Expected only the scheduled tweet to be sent. Result: %v", sent)
+	}
+
+	if len(app.tweets.Tweets) != 2 || app.tweets.Tweets[0].Status != tweet.StatusFailed || app.tweets.Tweets[0].Error == "" {
+		t.Fatalf("Expected the tweet left being sent to have failed. Result: %v", app.tweets.Tweets)
+	}
+
+	if !strings.Contains(buffer.String(), fmt.Sprintf("Failed tweet with identifier: %q", killedId)) {
+		t.Fatalf("Expected the failed tweet to be reported. Result: %q", buffer.String())
+	}
+
+	if tw := app.tweets.Tweets[1]; tw.Id != delivered.Id || tw.Status != tweet.StatusSent || tw.Error != "" {
+		t.Fatalf("Expected the delivered tweet to be kept as sent. Result: %v", tw)
+	}
+	if !strings.Contains(buffer.String(), fmt.Sprintf("Sent tweet with identifier: %q", delivered.Id)) {
+		t.Fatalf("Expected the sent tweet to be reported. Result: %q", buffer.String())
+	}
+
+	// It can be resumed once it has been checked
+	if err := app.Resume(killedId); err != nil || app.tweets.Tweets[0].Status != tweet.StatusScheduled {
+		t.Fatalf("Expected the tweet to be resumed. Result: %v, error: %v", app.tweets.Tweets[0], err)
+	}
+}
+
+func TestApprove(t *testing.T) {
+	app := Application{}
+	app.config.Approval.Required = true
+	app.config.Approval.Approvers = []string{"Alice"}
+
+	if err := app.Add("Tweet", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if tw.Status != tweet.StatusPending || !tw.NeedsApproval {
+		t.Fatalf("Expected the tweet to need approval. Result: %s", tw)
+	}
+
+	if count := len(app.tweets.ToSend(100, time.Now())); count != 0 {
+		t.Fatalf("Expected an unapproved tweet not to be sent. Result: %d", count)
+	}
+
+	if err := app.Approve(tw.Id, "bob"); !errors.Is(err, ErrNotApprover) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNotApprover, err)
+	}
+
+	if err := app.Approve(tw.Id, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	tw = app.tweets.Tweets[0]
+	if tw.Status != tweet.StatusScheduled || tw.Approval == nil || tw.Approval.By != "alice" {
+		t.Fatalf("Expected the approval to be recorded. Result: %s", tw)
+	}
+
+	if count := len(app.tweets.ToSend(100, time.Now())); count != 1 {
+		t.Fatalf("Expected an approved tweet to be sent. Result: %d", count)
+	}
+}
+
+func TestAddNeedsApproval(t *testing.T) {
+	app := Application{}
+	if err := app.AddWith("Tweet", time.Now().Format(time.RFC3339), AddOptions{NeedsApproval: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := app.tweets.Tweets[0].Status; status != tweet.StatusPending {
+		t.Fatalf("Expected status: %q. Result: %q", tweet.StatusPending, status)
+	}
+
+	// Anyone may approve when no approvers are configured
+	if err := app.Approve(app.tweets.Tweets[0].Id, "bob"); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestSendChecksForCredentials(t *testing.T) {
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
 	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
 
+	// The credentials are only needed when there is something to send
 	var buffer bytes.Buffer
-	if err := app.Send(&buffer, false); err == nil {
-		t.Fatal("Expected that Send would raise an error because of missing authentication values")
+	if err := app.Send(context.Background(), &buffer, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Send(context.Background(), &buffer, true); !errors.Is(err, ErrMissingAuth) {
+		t.Fatalf("Expected error: %q. Result: %v", ErrMissingAuth, err)
+	}
+	if err := app.Send(context.Background(), &buffer, false); !errors.Is(err, ErrMissingAuth) {
+		t.Fatalf("Expected error: %q. Result: %v", ErrMissingAuth, err)
 	}
 }
 
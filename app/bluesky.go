@@ -0,0 +1,457 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sender implementation for Bluesky using the AT Protocol XRPC API.
+// https://docs.bsky.app/docs/advanced-guides/posts
+type blueskySender struct {
+	config     Bluesky
+	httpClient *http.Client
+	baseURL    string
+	session    *blueskySession
+	blobs      map[string]json.RawMessage // Uploaded blobs keyed by their CID.
+	rateLimits *rateLimitRecorder
+}
+
+const (
+	defaultBlueskyPDS    = "https://bsky.social"
+	blueskyPostURL       = "https://bsky.app/profile/%s/post/%s"
+	blueskyPostType      = "app.bsky.feed.post"
+	blueskyMaxGraphemes  = 300
+	blueskyMaxImageCount = 4
+)
+
+func newBlueskySender(config Bluesky) *blueskySender {
+	return &blueskySender{config: config}
+}
+
+func (s *blueskySender) RateLimit() *RateLimit {
+	return s.rateLimits.RateLimit()
+}
+
+// BlueskyError is returned when the XRPC API responds with an error.
+type BlueskyError struct {
+	StatusCode int    // The HTTP status code.
+	Code       string // The error name, e.g. InvalidRequest.
+	Message    string // The error message returned by the API.
+}
+
+func (e *BlueskyError) Error() string {
+	return strings.TrimSpace(fmt.Sprintf("Bluesky API error (%d): %s %s", e.StatusCode, e.Code, e.Message))
+}
+
+func (s *blueskySender) Configure() error {
+	if s.config.Handle == "" {
+		return fmt.Errorf("%w: Bluesky handle", ErrMissingAuth)
+	}
+
+	if s.config.AppPassword == "" {
+		return fmt.Errorf("%w: Bluesky app password", ErrMissingAuth)
+	}
+
+	pds := s.config.PDS
+	if pds == "" {
+		pds = defaultBlueskyPDS
+	}
+
+	pdsURL, err := url.Parse(pds)
+	if err != nil || (pdsURL.Scheme != "https" && pdsURL.Scheme != "http") || pdsURL.Host == "" {
+		return fmt.Errorf("invalid Bluesky PDS URL: %q", pds)
+	}
+
+	if s.httpClient == nil {
+		s.httpClient = http.DefaultClient
+	}
+
+	s.baseURL = strings.TrimSuffix(pdsURL.String(), "/")
+	s.blobs = make(map[string]json.RawMessage)
+	return nil
+}
+
+// Session created using the app password.
+type blueskySession struct {
+	AccessJwt string `json:"accessJwt"`
+	Did       string `json:"did"`
+	Handle    string `json:"handle"`
+}
+
+// Create a session the first time it is needed.
+func (s *blueskySender) login(ctx context.Context) error {
+	if s.session != nil {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"identifier": s.config.Handle,
+		"password":   s.config.AppPassword,
+	})
+	if err != nil {
+		return err
+	}
+
+	var session blueskySession
+	if err := s.call(ctx, http.MethodPost, "com.atproto.server.createSession", nil,
+		"application/json", bytes.NewReader(body), &session); err != nil {
+		return err
+	}
+
+	s.session = &session
+	return nil
+}
+
+// A strong reference to a record.
+type blueskyRef struct {
+	URI string `json:"uri"`
+	CID string `json:"cid"`
+}
+
+type blueskyReply struct {
+	Root   blueskyRef `json:"root"`
+	Parent blueskyRef `json:"parent"`
+}
+
+type blueskyImage struct {
+	Alt   string          `json:"alt"`
+	Image json.RawMessage `json:"image"`
+}
+
+type blueskyEmbed struct {
+	Type   string         `json:"$type"`
+	Images []blueskyImage `json:"images"`
+}
+
+type blueskyPost struct {
+	Type      string         `json:"$type"`
+	Text      string         `json:"text"`
+	CreatedAt string         `json:"createdAt"`
+	Facets    []blueskyFacet `json:"facets,omitempty"`
+	Embed     *blueskyEmbed  `json:"embed,omitempty"`
+	Reply     *blueskyReply  `json:"reply,omitempty"`
+}
+
+func (s *blueskySender) Post(ctx context.Context, text string, mediaIds []string) (PostResult, error) {
+	return s.createPost(ctx, text, mediaIds, nil)
+}
+
+// Reply to the post with the specified at:// URI.
+func (s *blueskySender) PostReply(ctx context.Context, inReplyToId string, text string, mediaIds []string) (PostResult, error) {
+	if err := s.login(ctx); err != nil {
+		return PostResult{}, err
+	}
+
+	reply, err := s.replyTo(ctx, inReplyToId)
+	if err != nil {
+		return PostResult{}, err
+	}
+	return s.createPost(ctx, text, mediaIds, reply)
+}
+
+func (s *blueskySender) createPost(ctx context.Context, text string, mediaIds []string, reply *blueskyReply) (PostResult, error) {
+	if len(mediaIds) > blueskyMaxImageCount {
+		return PostResult{}, fmt.Errorf("Bluesky posts can have at most %d images", blueskyMaxImageCount)
+	}
+
+	if err := s.login(ctx); err != nil {
+		return PostResult{}, err
+	}
+
+	facets, err := s.facets(ctx, text)
+	if err != nil {
+		return PostResult{}, err
+	}
+
+	post := blueskyPost{
+		Type:      blueskyPostType,
+		Text:      text,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		Facets:    facets,
+		Reply:     reply,
+	}
+
+	if len(mediaIds) > 0 {
+		post.Embed = &blueskyEmbed{Type: "app.bsky.embed.images"}
+		for _, id := range mediaIds {
+			blob, exists := s.blobs[id]
+			if !exists {
+				return PostResult{}, fmt.Errorf("unknown Bluesky blob: %q", id)
+			}
+			post.Embed.Images = append(post.Embed.Images, blueskyImage{Image: blob})
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"repo":       s.session.Did,
+		"collection": blueskyPostType,
+		"record":     post,
+	})
+	if err != nil {
+		return PostResult{}, err
+	}
+
+	var ref blueskyRef
+	if err := s.call(ctx, http.MethodPost, "com.atproto.repo.createRecord", nil,
+		"application/json", bytes.NewReader(body), &ref); err != nil {
+		return PostResult{}, err
+	}
+
+	result := PostResult{Id: ref.URI}
+	if _, _, rkey, err := parseATURI(ref.URI); err == nil {
+		result.URL = fmt.Sprintf(blueskyPostURL, s.session.Handle, rkey)
+	}
+	return result, nil
+}
+
+// Build the reply references for the post with the specified at:// URI.
+// The root is the parent's root when the parent is itself a reply.
+func (s *blueskySender) replyTo(ctx context.Context, uri string) (*blueskyReply, error) {
+	repo, collection, rkey, err := parseATURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("repo", repo)
+	query.Set("collection", collection)
+	query.Set("rkey", rkey)
+
+	var parent struct {
+		blueskyRef
+		Value struct {
+			Reply *blueskyReply `json:"reply"`
+		} `json:"value"`
+	}
+	if err := s.call(ctx, http.MethodGet, "com.atproto.repo.getRecord", query, "", nil, &parent); err != nil {
+		return nil, err
+	}
+
+	reply := &blueskyReply{Root: parent.blueskyRef, Parent: parent.blueskyRef}
+	if parent.Value.Reply != nil {
+		reply.Root = parent.Value.Reply.Root
+	}
+	return reply, nil
+}
+
+// Parse an at://repo/collection/rkey URI.
+func parseATURI(uri string) (repo string, collection string, rkey string, err error) {
+	parts := strings.Split(strings.TrimPrefix(uri, "at://"), "/")
+	if !strings.HasPrefix(uri, "at://") || len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid AT URI: %q", uri)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// Upload the file as a blob and return its CID to be used when posting.
+func (s *blueskySender) UploadMedia(ctx context.Context, filePath string) (string, error) {
+	if err := s.login(ctx); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(filePath))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+
+	var result struct {
+		Blob json.RawMessage `json:"blob"`
+	}
+	if err := s.call(ctx, http.MethodPost, "com.atproto.repo.uploadBlob", nil,
+		contentType, bytes.NewReader(data), &result); err != nil {
+		return "", err
+	}
+
+	var blob struct {
+		Ref struct {
+			Link string `json:"$link"`
+		} `json:"ref"`
+	}
+	if err := json.Unmarshal(result.Blob, &blob); err != nil {
+		return "", err
+	}
+	if blob.Ref.Link == "" {
+		return "", errors.New("Bluesky did not return a reference to the uploaded blob")
+	}
+
+	s.blobs[blob.Ref.Link] = result.Blob
+	return blob.Ref.Link, nil
+}
+
+// Rich text annotation of a part of the text.
+// https://docs.bsky.app/docs/advanced-guides/post-richtext
+type blueskyFacet struct {
+	Index    blueskyByteSlice `json:"index"`
+	Features []blueskyFeature `json:"features"`
+}
+
+// The UTF-8 byte offsets of the annotated text, end is exclusive.
+type blueskyByteSlice struct {
+	ByteStart int `json:"byteStart"`
+	ByteEnd   int `json:"byteEnd"`
+}
+
+type blueskyFeature struct {
+	Type string `json:"$type"`
+	URI  string `json:"uri,omitempty"`
+	Did  string `json:"did,omitempty"`
+}
+
+var (
+	blueskyURLRegexp     = regexp.MustCompile(`(?:^|[\s(])(https?://[^\s]+)`)
+	blueskyMentionRegexp = regexp.MustCompile(`(?:^|[\s(])(@(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)`)
+)
+
+// Create the facets for the URLs and mentions in the text.
+// Mentions of handles that can not be resolved are left as plain text.
+func (s *blueskySender) facets(ctx context.Context, text string) ([]blueskyFacet, error) {
+	facets := linkFacets(text)
+
+	for _, match := range blueskyMentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+		did, err := s.resolveHandle(ctx, text[start+1:end])
+		if err != nil {
+			var blueskyErr *BlueskyError
+			if errors.As(err, &blueskyErr) && blueskyErr.StatusCode == http.StatusBadRequest {
+				continue
+			}
+			return nil, err
+		}
+
+		facets = append(facets, blueskyFacet{
+			Index:    blueskyByteSlice{ByteStart: start, ByteEnd: end},
+			Features: []blueskyFeature{{Type: "app.bsky.richtext.facet#mention", Did: did}},
+		})
+	}
+
+	sort.SliceStable(facets, func(i, j int) bool {
+		return facets[i].Index.ByteStart < facets[j].Index.ByteStart
+	})
+	return facets, nil
+}
+
+// Create the facets for the URLs in the text.
+func linkFacets(text string) []blueskyFacet {
+	var facets []blueskyFacet
+
+	for _, match := range blueskyURLRegexp.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+
+		// Trailing punctuation is most likely not part of the URL
+		link := strings.TrimRight(text[start:end], ".,;:!?\"'")
+		for strings.HasSuffix(link, ")") && strings.Count(link, ")") > strings.Count(link, "(") {
+			link = strings.TrimRight(strings.TrimSuffix(link, ")"), ".,;:!?\"'")
+		}
+		end = start + len(link)
+
+		facets = append(facets, blueskyFacet{
+			Index:    blueskyByteSlice{ByteStart: start, ByteEnd: end},
+			Features: []blueskyFeature{{Type: "app.bsky.richtext.facet#link", URI: link}},
+		})
+	}
+
+	return facets
+}
+
+// Resolve the handle (e.g. alice.bsky.social) to a DID.
+func (s *blueskySender) resolveHandle(ctx context.Context, handle string) (string, error) {
+	query := url.Values{}
+	query.Set("handle", handle)
+
+	var result struct {
+		Did string `json:"did"`
+	}
+	if err := s.call(ctx, http.MethodGet, "com.atproto.identity.resolveHandle", query, "", nil, &result); err != nil {
+		return "", err
+	}
+	return result.Did, nil
+}
+
+// Call the XRPC method and decode the JSON response into result.
+// The session's access token is used once the session has been created.
+func (s *blueskySender) call(ctx context.Context, method string, nsid string, query url.Values,
+	contentType string, body io.Reader, result interface{}) error {
+
+	endpoint := s.baseURL + "/xrpc/" + nsid
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.session != nil {
+		req.Header.Set("Authorization", "Bearer "+s.session.AccessJwt)
+	}
+
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		blueskyErr := &BlueskyError{StatusCode: res.StatusCode}
+
+		var errBody struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(data, &errBody); err == nil && errBody.Error != "" {
+			blueskyErr.Code = errBody.Error
+			blueskyErr.Message = errBody.Message
+		} else {
+			blueskyErr.Message = strings.TrimSpace(string(data))
+		}
+		return blueskyErr
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
@@ -0,0 +1,319 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePDS is a minimal stand-in for a Bluesky Personal Data Server.
+type fakePDS struct {
+	server  *httptest.Server
+	records map[string]blueskyPost // Created posts keyed by their URI.
+	uploads int
+}
+
+const (
+	fakePDSHandle   = "product.bsky.social"
+	fakePDSPassword = "app-pass-word"
+	fakePDSDid      = "did:plc:product"
+	fakePDSToken    = "access-jwt"
+)
+
+func newFakePDS(t *testing.T) *fakePDS {
+	fake := &fakePDS{records: make(map[string]blueskyPost)}
+
+	xrpcError := func(w http.ResponseWriter, status int, code string, message string) {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "message": message})
+	}
+
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("Authorization") != "Bearer "+fakePDSToken {
+			xrpcError(w, http.StatusUnauthorized, "AuthMissing", "Authentication Required")
+			return false
+		}
+		return true
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xrpc/com.atproto.server.createSession", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["identifier"] != fakePDSHandle || body["password"] != fakePDSPassword {
+			xrpcError(w, http.StatusUnauthorized, "AuthenticationRequired", "Invalid identifier or password")
+			return
+		}
+		json.NewEncoder(w).Encode(blueskySession{AccessJwt: fakePDSToken, Did: fakePDSDid, Handle: fakePDSHandle})
+	})
+
+	mux.HandleFunc("/xrpc/com.atproto.identity.resolveHandle", func(w http.ResponseWriter, r *http.Request) {
+		switch handle := r.URL.Query().Get("handle"); handle {
+		case "alice.bsky.social":
+			json.NewEncoder(w).Encode(map[string]string{"did": "did:plc:alice"})
+		default:
+			xrpcError(w, http.StatusBadRequest, "InvalidRequest", "Unable to resolve handle")
+		}
+	})
+
+	mux.HandleFunc("/xrpc/com.atproto.repo.uploadBlob", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		fake.uploads++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"blob": map[string]interface{}{
+				"$type":    "blob",
+				"ref":      map[string]string{"$link": "bafkreiblob"},
+				"mimeType": r.Header.Get("Content-Type"),
+				"size":     len(data),
+			},
+		})
+	})
+
+	mux.HandleFunc("/xrpc/com.atproto.repo.createRecord", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+
+		var body struct {
+			Repo       string      `json:"repo"`
+			Collection string      `json:"collection"`
+			Record     blueskyPost `json:"record"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Repo != fakePDSDid ||
+			body.Collection != blueskyPostType || body.Record.Type != blueskyPostType {
+			xrpcError(w, http.StatusBadRequest, "InvalidRequest", "Invalid record")
+			return
+		}
+
+		uri := "at://" + fakePDSDid + "/" + blueskyPostType + "/rkey" + strconv.Itoa(len(fake.records)+1)
+		fake.records[uri] = body.Record
+		json.NewEncoder(w).Encode(blueskyRef{URI: uri, CID: "cid-" + uri})
+	})
+
+	mux.HandleFunc("/xrpc/com.atproto.repo.getRecord", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		uri := "at://" + query.Get("repo") + "/" + query.Get("collection") + "/" + query.Get("rkey")
+		record, exists := fake.records[uri]
+		if !exists {
+			xrpcError(w, http.StatusBadRequest, "RecordNotFound", "Could not locate record")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"uri": uri, "cid": "cid-" + uri, "value": record})
+	})
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fake *fakePDS) sender(t *testing.T) *blueskySender {
+	sender := newBlueskySender(Bluesky{Handle: fakePDSHandle, AppPassword: fakePDSPassword, PDS: fake.server.URL})
+	if err := sender.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestBlueskyConfigure(t *testing.T) {
+	if err := newBlueskySender(Bluesky{AppPassword: "x"}).Configure(); !errors.Is(err, ErrMissingAuth) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMissingAuth, err)
+	}
+
+	if err := newBlueskySender(Bluesky{Handle: "x"}).Configure(); !errors.Is(err, ErrMissingAuth) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMissingAuth, err)
+	}
+
+	sender := newBlueskySender(Bluesky{Handle: "x", AppPassword: "y"})
+	if err := sender.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	if sender.baseURL != defaultBlueskyPDS {
+		t.Fatalf("Expected the default PDS %q. Result: %q", defaultBlueskyPDS, sender.baseURL)
+	}
+}
+
+func TestBlueskyPostWithFacets(t *testing.T) {
+	fake := newFakePDS(t)
+	sender := fake.sender(t)
+
+	text := "✨ Hello @alice.bsky.social and @unknown.example.com, see https://example.com/launch."
+	result, err := sender.Post(context.Background(), text, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.URL != "https://bsky.app/profile/"+fakePDSHandle+"/post/rkey1" {
+		t.Fatalf("Unexpected URL: %q", result.URL)
+	}
+
+	post := fake.records[result.Id]
+	if post.Text != text || post.CreatedAt == "" {
+		t.Fatalf("Unexpected post: %v", post)
+	}
+
+	// The offsets are in UTF-8 bytes and ✨ is 3 bytes long
+	mentionStart := strings.Index(text, "@alice")
+	linkStart := strings.Index(text, "https://")
+	expected := []blueskyFacet{
+		{
+			Index:    blueskyByteSlice{ByteStart: mentionStart, ByteEnd: mentionStart + len("@alice.bsky.social")},
+			Features: []blueskyFeature{{Type: "app.bsky.richtext.facet#mention", Did: "did:plc:alice"}},
+		},
+		{
+			Index:    blueskyByteSlice{ByteStart: linkStart, ByteEnd: linkStart + len("https://example.com/launch")},
+			Features: []blueskyFeature{{Type: "app.bsky.richtext.facet#link", URI: "https://example.com/launch"}},
+		},
+	}
+
+	if len(post.Facets) != len(expected) {
+		t.Fatalf("Expected facets: %v. Result: %v", expected, post.Facets)
+	}
+	for i := range expected {
+		if post.Facets[i].Index != expected[i].Index || post.Facets[i].Features[0] != expected[i].Features[0] {
+			t.Fatalf("Expected facet: %v. Result: %v", expected[i], post.Facets[i])
+		}
+	}
+
+	if mentionStart != 10 {
+		t.Fatalf("Expected the mention to start at byte 10. Result: %d", mentionStart)
+	}
+}
+
+func TestBlueskyLinkFacets(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"https://example.com", []string{"https://example.com"}},
+		{"(see https://example.com/a_(b))", []string{"https://example.com/a_(b)"}},
+		{"(see https://example.com)", []string{"https://example.com"}},
+		{"Visit http://a.com, http://b.com!", []string{"http://a.com", "http://b.com"}},
+		{"nohttps://example.com", nil},
+	}
+
+	for _, test := range tests {
+		facets := linkFacets(test.text)
+		if len(facets) != len(test.expected) {
+			t.Fatalf("%q: Expected %v. Result: %v", test.text, test.expected, facets)
+		}
+		for i, facet := range facets {
+			link := test.text[facet.Index.ByteStart:facet.Index.ByteEnd]
+			if link != test.expected[i] || facet.Features[0].URI != test.expected[i] {
+				t.Fatalf("%q: Expected %q. Result: %q", test.text, test.expected[i], link)
+			}
+		}
+	}
+}
+
+func TestBlueskyReplyWithImage(t *testing.T) {
+	fake := newFakePDS(t)
+	sender := fake.sender(t)
+	ctx := context.Background()
+
+	root, err := sender.Post(ctx, "Root", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent, err := sender.PostReply(ctx, root.Id, "Parent", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(filePath, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mediaId, err := sender.UploadMedia(ctx, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := sender.PostReply(ctx, parent.Id, "Reply", []string{mediaId})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := fake.records[reply.Id]
+	if post.Reply == nil || post.Reply.Root.URI != root.Id || post.Reply.Parent.URI != parent.Id {
+		t.Fatalf("Expected the reply to reference the root and parent. Result: %v", post.Reply)
+	}
+
+	if post.Embed == nil || len(post.Embed.Images) != 1 || !strings.Contains(string(post.Embed.Images[0].Image), "bafkreiblob") {
+		t.Fatalf("Expected the image to be embedded. Result: %v", post.Embed)
+	}
+
+	if _, err := sender.PostReply(ctx, "https://bsky.app/profile/x/post/y", "Reply", nil); err == nil {
+		t.Fatal("Expected an invalid AT URI to fail")
+	}
+}
+
+func TestBlueskyInvalidPassword(t *testing.T) {
+	fake := newFakePDS(t)
+	sender := newBlueskySender(Bluesky{Handle: fakePDSHandle, AppPassword: "wrong", PDS: fake.server.URL})
+	if err := sender.Configure(); err != nil {
+		t.Fatal(err)
+	}
+
+	var blueskyErr *BlueskyError
+	if _, err := sender.Post(context.Background(), "Hello", nil); !errors.As(err, &blueskyErr) ||
+		blueskyErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestBlueskyGraphemeLimit(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Bluesky = map[string]Bluesky{"sky": {}}
+	now := time.Now().Format(time.RFC3339)
+
+	// A family emoji is a single grapheme made up of 5 code points
+	family := strings.Repeat("👨‍👩‍👧", blueskyMaxGraphemes)
+	if err := app.AddWith(family, now, AddOptions{Account: "sky"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.AddWith(strings.Repeat("a", blueskyMaxGraphemes+1), now, AddOptions{Account: "sky"}); !errors.Is(err, ErrMessageTooLong) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMessageTooLong, err)
+	}
+
+	// The limit only applies to Bluesky
+	if err := app.AddWith(strings.Repeat("a", blueskyMaxGraphemes+1), now, AddOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -28,6 +28,12 @@ import "os"
 type Config struct {
 	Datastore Datastore
 	Send      Send
+	Approval  Approval
+	Accounts  Accounts
+	Log       Log
+	Hooks     []Hook
+	Serve     Serve
+	Secrets   Secrets
 
 	Lockfile string // File path of where the lock file will be created.
 }
@@ -37,14 +43,82 @@ type Datastore struct {
 	Filepath string // File path of where the tweets should be stored.
 }
 
+// Log configures the structured log written in addition to the output of the commands.
+type Log struct {
+	Level      string // The minimum level of the entries: debug, info, warn, error or off. Default is info when a file is specified, otherwise off.
+	Format     string // The format of the entries: text or json. Default is text.
+	File       string // The path of the file to log to. Default is stderr.
+	MaxSize    int    `mapstructure:"max_size"`    // The size in megabytes after which the log file is rotated (0 = never).
+	MaxBackups int    `mapstructure:"max_backups"` // The number of rotated log files to keep.
+}
+
 // Send parameters
 type Send struct {
 	Max   int // The maximum number of tweets to send in this call of the app.
 	Delay int // The number of seconds to delay between each sending of a tweet.
 
+	MaxAttempts int `mapstructure:"max_attempts"` // The number of failed attempts after which a tweet is marked as failed.
+	ExpireAfter int `mapstructure:"expire_after"` // The number of hours after which an unsent tweet expires (0 = never).
+
+	APIBaseURL string `mapstructure:"api_base_url"` // Send the Twitter API requests to this server instead, e.g. http://localhost:8080 for ajtweet mock-server.
+	Proxy      string // The URL of the HTTP(S) proxy used for the API requests. Default is to use the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	CABundle   string `mapstructure:"ca_bundle"` // Path to a PEM file with additional CA certificates to trust, e.g. for a corporate proxy.
+	Timeout    int    // The number of seconds after which a single API request is cancelled (0 = no timeout).
+	Deadline   int    // The number of seconds after which sending is stopped (0 = no deadline).
+
 	Authentication Authentication
 }
 
+// Hook that is run for a send event, either a webhook (URL) or a local executable (Command).
+type Hook struct {
+	Event   string   // The event: before_send, after_send, send_failed or queue_empty.
+	URL     string   // The URL the JSON payload is posted to.
+	Secret  string   // The secret used to sign the payload with HMAC-SHA256 (X-Ajtweet-Signature header). Optional.
+	Retries int      // The number of times a failed webhook is retried.
+	Command string   // The executable that receives the JSON payload on stdin.
+	Args    []string // The arguments passed to the executable.
+	Timeout int      // The number of seconds the hook is allowed to run (default 10).
+}
+
+// Serve configures the REST API served by ajtweet serve.
+type Serve struct {
+	Listen string // The address to listen on, e.g. 127.0.0.1:8080
+	Token  string // The bearer token the API clients must use.
+}
+
+// Secrets configures the encrypted file of the secrets referenced as secret://name, see ajtweet secrets.
+type Secrets struct {
+	File    string // The path of the encrypted secrets file.
+	KeyFile string `mapstructure:"key_file"` // The path of the file containing the passphrase. Default is to use AJTWEET_SECRETS_PASSPHRASE.
+}
+
+// Approval of tweets before they will be sent
+type Approval struct {
+	Required  bool     // All new tweets need to be approved before they will be sent.
+	Approvers []string // The names of the people allowed to approve tweets. Anyone may approve when empty.
+}
+
+// Accounts tweets can be sent from, keyed by the account name for each service.
+// Accounts are referenced as service:name (e.g. mastodon:product) or only by name when the name is unique.
+type Accounts struct {
+	Twitter  map[string]Authentication
+	Mastodon map[string]Mastodon
+	Bluesky  map[string]Bluesky
+}
+
+// Mastodon account settings
+type Mastodon struct {
+	Instance    string // The URL of the Mastodon instance, e.g. https://mastodon.social
+	AccessToken string `mapstructure:"access_token"` // Access token of an application with the write:statuses and write:media scopes.
+}
+
+// Bluesky account settings
+type Bluesky struct {
+	Handle      string // The account's handle, e.g. alice.bsky.social
+	AppPassword string `mapstructure:"app_password"` // App password created in Settings > App Passwords.
+	PDS         string // The URL of the account's Personal Data Server. Default is https://bsky.social
+}
+
 // Authentication details for the Twitter API
 type Authentication struct {
 	APIKey    string `mapstructure:"api_key"`    // Consumer / API Key
@@ -64,18 +138,30 @@ const (
 	envAPISecret    = "AJTWEET_API_SECRET"
 	envOAuth1Token  = "AJTWEET_ACCESS_TOKEN"
 	envOAuth1Secret = "AJTWEET_ACCESS_SECRET"
+	envServeToken   = "AJTWEET_SERVE_TOKEN"
+
+	envSecretsPassphrase = "AJTWEET_SECRETS_PASSPHRASE"
 
-	defaultSendMax      = 10
-	defaultSendDelay    = 1
-	defaultSendLockfile = "./ajtweet.lock"
+	defaultSendMax         = 10
+	defaultSendDelay       = 1
+	defaultSendMaxAttempts = 3
+	defaultSendTimeout     = 30
+	defaultLogMaxSize      = 10
+	defaultLogMaxBackups   = 3
 )
 
 // Create a new Config and set the default values required
 func NewConfig() Config {
 	var config Config
+	config.Datastore.Filepath = DefaultDatastore()
 	config.Send.Max = defaultSendMax
 	config.Send.Delay = defaultSendDelay
-	config.Lockfile = defaultSendLockfile
+	config.Send.MaxAttempts = defaultSendMaxAttempts
+	config.Send.Timeout = defaultSendTimeout
+	config.Log.MaxSize = defaultLogMaxSize
+	config.Log.MaxBackups = defaultLogMaxBackups
+	config.Secrets.File = DefaultSecretsFile()
+	config.Lockfile = DefaultLockfile()
 	return config
 }
 
@@ -96,4 +182,8 @@ func (config *Config) PopulateFromEnv() {
 	if value, present := os.LookupEnv(envOAuth1Secret); present {
 		config.Send.Authentication.OAuth1.Secret = value
 	}
+
+	if value, present := os.LookupEnv(envServeToken); present {
+		config.Serve.Token = value
+	}
 }
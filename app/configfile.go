@@ -0,0 +1,374 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The value displayed instead of a credential.
+const maskedValue = "********"
+
+// The names of the configuration values that contain credentials.
+var credentialNames = []string{"api_key", "api_secret", "token", "secret", "access_token", "app_password"}
+
+var (
+	ErrUnsupportedConfig = errors.New("unsupported configuration file")
+)
+
+// Check if the configuration key contains a credential, e.g. send.authentication.api_key
+func IsCredentialKey(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	for _, credential := range credentialNames {
+		if name == credential {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if the value references a credential (secret://, cmd:, file: or env:) instead of being the credential.
+func IsCredentialReference(value string) bool {
+	for _, prefix := range []string{"secret://", credentialCmd, credentialFile, credentialEnv} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the value masked when the key contains a credential that is not a reference.
+func MaskCredential(key string, value string) string {
+	if value == "" || !IsCredentialKey(key) || IsCredentialReference(value) {
+		return value
+	}
+	return maskedValue
+}
+
+// Return a copy of the configuration with the credentials masked, except for the references to credentials.
+func (config Config) Masked() Config {
+	masked := config
+	masked.Accounts.Twitter = copyMap(config.Accounts.Twitter)
+	masked.Accounts.Mastodon = copyMap(config.Accounts.Mastodon)
+	masked.Accounts.Bluesky = copyMap(config.Accounts.Bluesky)
+	masked.Hooks = append([]Hook(nil), config.Hooks...)
+
+	masked.forEachCredential(func(path string, value *string) error {
+		if *value != "" && !IsCredentialReference(*value) {
+			*value = maskedValue
+		}
+		return nil
+	})
+	return masked
+}
+
+func copyMap[V any](m map[string]V) map[string]V {
+	if m == nil {
+		return nil
+	}
+	result := make(map[string]V, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
+
+// Encode the configuration as YAML using the configuration keys, e.g. max_attempts.
+func (config Config) EncodeYAML() ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(yamlNode(reflect.ValueOf(config))); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func yamlNode(value reflect.Value) *yaml.Node {
+	switch value.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			name := strings.ToLower(field.Name)
+			if tag := field.Tag.Get("mapstructure"); tag != "" {
+				name = tag
+			}
+			node.Content = append(node.Content, scalarNode("!!str", name), yamlNode(value.Field(i)))
+		}
+		return node
+
+	case reflect.Map:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		keys := make([]string, 0, value.Len())
+		for _, key := range value.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			node.Content = append(node.Content, scalarNode("!!str", key), yamlNode(value.MapIndex(reflect.ValueOf(key))))
+		}
+		return node
+
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if value.Type().Elem().Kind() == reflect.String {
+			node.Style = yaml.FlowStyle
+		}
+		for i := 0; i < value.Len(); i++ {
+			node.Content = append(node.Content, yamlNode(value.Index(i)))
+		}
+		return node
+
+	case reflect.Int:
+		return scalarNode("!!int", strconv.FormatInt(value.Int(), 10))
+	case reflect.Bool:
+		return scalarNode("!!bool", strconv.FormatBool(value.Bool()))
+	}
+	return scalarNode("!!str", value.String())
+}
+
+func scalarNode(tag string, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
+
+// Return the YAML node for the value of the configuration key, e.g. a number for send.max
+func configValueNode(key string, value string) (*yaml.Node, error) {
+	typ := typeString
+	found := false
+	for _, configKey := range ConfigKeys() {
+		if configKey.Key == profileKey(key) {
+			typ, found = configKey.Type, true
+			break
+		}
+	}
+	if (!found && !IsKnownKey(key)) || profileKey(key) == "hooks" {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+	}
+
+	switch typ {
+	case typeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return nil, fmt.Errorf("%w: %s: expected a value of type int, got %q", ErrInvalidConfig, key, value)
+		}
+		return scalarNode("!!int", value), nil
+
+	case typeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: expected a value of type bool, got %q", ErrInvalidConfig, key, value)
+		}
+		return scalarNode("!!bool", strconv.FormatBool(b)), nil
+
+	case typeList:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				node.Content = append(node.Content, scalarNode("!!str", item))
+			}
+		}
+		return node, nil
+	}
+	return scalarNode("!!str", value), nil
+}
+
+// Set the value of the configuration key in the YAML configuration file, keeping the comments and the order of the
+// other values. The value is checked against the type of the key, e.g. send.max must be a number.
+func SetConfigValue(filePath string, key string, value string) error {
+	key = strings.ToLower(key)
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml", "":
+	default:
+		return fmt.Errorf("%w: %q, only YAML files can be changed", ErrUnsupportedConfig, filePath)
+	}
+
+	valueNode, err := configValueNode(key, value)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return fmt.Errorf("%w: %s", ErrUnsupportedConfig, err)
+	}
+	if document.Kind == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if len(document.Content) != 1 || document.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("%w: %q does not contain a mapping", ErrUnsupportedConfig, filePath)
+	}
+
+	mapping := document.Content[0]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		existing := mappingValue(mapping, part)
+		last := i == len(parts)-1
+
+		if last {
+			if existing != nil {
+				// Keep the comments of the value that is replaced
+				valueNode.HeadComment = existing.HeadComment
+				valueNode.LineComment = existing.LineComment
+				valueNode.FootComment = existing.FootComment
+				*existing = *valueNode
+			} else {
+				mapping.Content = append(mapping.Content, scalarNode("!!str", part), valueNode)
+			}
+			break
+		}
+
+		if existing == nil {
+			existing = &yaml.Node{Kind: yaml.MappingNode}
+			mapping.Content = append(mapping.Content, scalarNode("!!str", part), existing)
+		} else if existing.Kind != yaml.MappingNode {
+			return fmt.Errorf("%w: %q is not a mapping", ErrUnsupportedConfig, strings.Join(parts[:i+1], "."))
+		}
+		mapping = existing
+	}
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(detectIndent(data))
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	return writeConfigFile(filePath, buffer.Bytes())
+}
+
+// Return the value node of the key (matched ignoring case) in the mapping, or nil when it does not exist.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if strings.EqualFold(mapping.Content[i].Value, key) {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// Return the number of spaces used to indent the YAML, default is 2.
+func detectIndent(data []byte) int {
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if indent := len(line) - len(trimmed); indent > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") &&
+			!strings.HasPrefix(trimmed, "-") {
+			return indent
+		}
+	}
+	return 2
+}
+
+// Atomically replace the configuration file, keeping the permissions of the existing file.
+// New files are only readable by the owner since they can contain credentials.
+func writeConfigFile(filePath string, data []byte) error {
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+// Return the YAML configuration file with the credentials masked, keeping the comments.
+func MaskConfigFile(data []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedConfig, err)
+	}
+	if document.Kind == 0 {
+		return data, nil
+	}
+
+	maskNode(&document, "")
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(detectIndent(data))
+	if err := encoder.Encode(&document); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func maskNode(node *yaml.Node, key string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			maskNode(child, key)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := node.Content[i].Value
+			if key != "" {
+				childKey = key + "." + childKey
+			}
+			maskNode(node.Content[i+1], childKey)
+		}
+	case yaml.ScalarNode:
+		node.Value = MaskCredential(key, node.Value)
+	}
+}
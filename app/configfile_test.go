@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), ".ajtweet.yaml")
+	data := `# ajtweet configuration
+send:
+    # Keep within the rate limit
+    max: 10 # per run
+    delay: 5
+`
+	if err := os.WriteFile(filePath, []byte(data), 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := [][2]string{
+		{"send.max", "20"},
+		{"approval.required", "true"},
+		{"approval.approvers", "alice, bob"},
+		{"accounts.mastodon.product.access_token", "secret://mastodon-token"},
+		{"lockfile", "123"},
+	}
+	for _, change := range changes {
+		if err := SetConfigValue(filePath, change[0], change[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"# ajtweet configuration", "    # Keep within the rate limit", "    max: 20 # per run", "    delay: 5"} {
+		if !strings.Contains(string(result), expected) {
+			t.Fatalf("Expected %q to be kept. Result:\n%s", expected, result)
+		}
+	}
+
+	if info, err := os.Stat(filePath); err != nil || info.Mode().Perm() != 0640 {
+		t.Fatalf("Expected the permissions to be kept. Result: %v, error: %v", info.Mode(), err)
+	}
+
+	v := viper.New()
+	v.SetConfigFile(filePath)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	config := NewConfig()
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Send.Max != 20 || config.Send.Delay != 5 || !config.Approval.Required ||
+		strings.Join(config.Approval.Approvers, ",") != "alice,bob" || config.Lockfile != "123" ||
+		config.Accounts.Mastodon["product"].AccessToken != "secret://mastodon-token" {
+		t.Fatalf("Unexpected configuration: %+v", config)
+	}
+
+	if err := SetConfigValue(filePath, "send.max", "ten"); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected error: %q. Result: %v", ErrInvalidConfig, err)
+	}
+	if err := SetConfigValue(filePath, "sned.max", "1"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Expected error: %q. Result: %v", ErrUnknownKey, err)
+	}
+	if err := SetConfigValue(filePath+".toml", "send.max", "1"); !errors.Is(err, ErrUnsupportedConfig) {
+		t.Fatalf("Expected error: %q. Result: %v", ErrUnsupportedConfig, err)
+	}
+}
+
+func TestMaskedConfig(t *testing.T) {
+	config := NewConfig()
+	config.Send.Authentication = Authentication{APIKey: "key", APISecret: "secret://api-secret"}
+	config.Accounts.Bluesky = map[string]Bluesky{"news": {Handle: "news.bsky.social", AppPassword: "password"}}
+	config.Hooks = []Hook{{Event: "after_send", URL: "https://example.com", Secret: "hook-secret"}}
+
+	masked := config.Masked()
+	if masked.Send.Authentication.APIKey != maskedValue || masked.Send.Authentication.APISecret != "secret://api-secret" ||
+		masked.Accounts.Bluesky["news"].AppPassword != maskedValue || masked.Hooks[0].Secret != maskedValue {
+		t.Fatalf("Unexpected masked configuration: %+v", masked)
+	}
+
+	if config.Accounts.Bluesky["news"].AppPassword != "password" || config.Hooks[0].Secret != "hook-secret" {
+		t.Fatal("Expected the original configuration not to be changed")
+	}
+
+	data, err := masked.EncodeYAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "app_password: password") || strings.Contains(string(data), "hook-secret") ||
+		!strings.Contains(string(data), "max_attempts: 3") {
+		t.Fatalf("Unexpected YAML:\n%s", data)
+	}
+
+	var decoded map[string]interface{}
+	if err := yaml.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaskConfigFile(t *testing.T) {
+	data := `send:
+  authentication:
+    api_key: key # the key
+    api_secret: cmd:pass show twitter/api-secret
+hooks:
+  - url: https://example.com
+    secret: hook-secret
+`
+	masked, err := MaskConfigFile([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"api_key: '********' # the key", "api_secret: cmd:pass show twitter/api-secret", "secret: '********'"} {
+		if !strings.Contains(string(masked), expected) {
+			t.Fatalf("Expected %q. Result:\n%s", expected, masked)
+		}
+	}
+}
+
+func TestInitialConfig(t *testing.T) {
+	for _, secrets := range []bool{false, true} {
+		options := DefaultInitOptions()
+		options.Max = 25
+		options.Secrets = secrets
+
+		data, err := InitialConfig(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v := viper.New()
+		v.SetConfigType("yaml")
+		if err := v.ReadConfig(strings.NewReader(string(data))); err != nil {
+			t.Fatal(err)
+		}
+
+		if unknown := UnknownKeys(v); len(unknown) != 0 {
+			t.Fatalf("Unexpected unknown keys: %v", unknown)
+		}
+
+		config := NewConfig()
+		if err := v.Unmarshal(&config); err != nil {
+			t.Fatal(err)
+		}
+
+		if errs := config.Validate(); len(errs) != 0 {
+			t.Fatalf("Expected the configuration to be valid. Result: %v", errs)
+		}
+
+		if config.Send.Max != 25 || config.Datastore.Filepath != DefaultDatastore() {
+			t.Fatalf("Unexpected configuration: %+v", config)
+		}
+
+		if secrets != (config.Send.Authentication.APIKey == "secret://twitter-api-key") {
+			t.Fatalf("Unexpected authentication: %+v", config.Send.Authentication)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// InitOptions are the values written to a new configuration file by InitialConfig.
+type InitOptions struct {
+	Datastore string // File path of where the tweets should be stored.
+	Lockfile  string // File path of where the lock file will be created.
+	Max       int    // The maximum number of tweets to send each time.
+	Delay     int    // The number of seconds to delay between each tweet.
+	Secrets   bool   // Reference the Twitter credentials in the encrypted secrets file instead of leaving them out.
+}
+
+// Return the options with the default values used by NewConfig.
+func DefaultInitOptions() InitOptions {
+	config := NewConfig()
+	return InitOptions{
+		Datastore: config.Datastore.Filepath,
+		Lockfile:  config.Lockfile,
+		Max:       config.Send.Max,
+		Delay:     config.Send.Delay,
+	}
+}
+
+var initialConfigTemplate = template.Must(template.New("config").Parse(`# ajtweet configuration, see "ajtweet --help" for all the values.
+# Every value can also be set using an environment variable, see "ajtweet config env".
+
+datastore:
+  # File path of where the tweets are stored.
+  filepath: {{printf "%q" .Datastore}}
+
+# File path of the lock file that prevents ajtweet from running more than once at the same time.
+lockfile: {{printf "%q" .Lockfile}}
+
+send:
+{{- if .Secrets}}
+  # The credentials of the Twitter API are read from the encrypted secrets file,
+  # store them using "ajtweet secrets set twitter-api-key" etc.
+  authentication:
+    api_key: secret://twitter-api-key
+    api_secret: secret://twitter-api-secret
+    oauth1:
+      token: secret://twitter-access-token
+      secret: secret://twitter-access-secret
+{{- else}}
+  # The credentials of the Twitter API. Instead of storing them in this file
+  # they can be read from the environment (AJTWEET_API_KEY etc.), the encrypted
+  # secrets file (secret://name), a command (cmd:), a file (file:) or another
+  # environment variable (env:).
+  # authentication:
+  #   api_key: your_consumer_api_key
+  #   api_secret: your_consumer_api_secret
+  #   oauth1:
+  #     token: your_oauth_user_token
+  #     secret: your_oauth_user_secret
+{{- end}}
+
+  # The maximum number of tweets sent each time "ajtweet send" is run.
+  max: {{.Max}}
+  # The number of seconds to wait after each tweet was sent.
+  delay: {{.Delay}}
+`))
+
+// Return the contents of a new YAML configuration file with comments describing the values.
+func InitialConfig(options InitOptions) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := initialConfigTemplate.Execute(&buffer, options); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
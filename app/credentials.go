@@ -0,0 +1,215 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Prefixes of the credential values that are read from somewhere else when the credentials are needed.
+const (
+	credentialCmd  = "cmd:"  // Run the command (using the shell) and use its output, e.g. cmd:pass show twitter/api-key
+	credentialFile = "file:" // Read the file, e.g. file:/run/secrets/twitter-api-key
+	credentialEnv  = "env:"  // Read the environment variable, e.g. env:TWITTER_API_KEY
+)
+
+// The time a credential command is allowed to run.
+const credentialCommandTimeout = 30 * time.Second
+
+var (
+	ErrCredential = errors.New("failed to resolve the credential")
+)
+
+// credentialCache keeps the values of the credentials that have been resolved, so that e.g. a password manager is
+// only asked once for each credential. The credentials that could not be resolved are kept until forgetErrors.
+// A nil credentialCache resolves the credentials every time.
+type credentialCache struct {
+	values map[string]string
+	errs   map[string]error
+}
+
+// Return the value of the credential, resolving it when it has not been resolved before.
+func (cache *credentialCache) resolve(ctx context.Context, value string) (string, error) {
+	if cache == nil || !isCredentialReference(value) {
+		return resolveCredential(ctx, value)
+	}
+
+	if resolved, exists := cache.values[value]; exists {
+		return resolved, nil
+	}
+	if err, exists := cache.errs[value]; exists {
+		return "", err
+	}
+
+	resolved, err := resolveCredential(ctx, value)
+	if err != nil {
+		// A command that was stopped is run again the next time
+		if ctx.Err() == nil {
+			if cache.errs == nil {
+				cache.errs = make(map[string]error)
+			}
+			cache.errs[value] = err
+		}
+		return "", err
+	}
+
+	if cache.values == nil {
+		cache.values = make(map[string]string)
+	}
+	cache.values[value] = resolved
+	return resolved, nil
+}
+
+// Forget the credentials that could not be resolved so that they will be tried again, e.g. the next time tweets
+// are sent.
+func (cache *credentialCache) forgetErrors() {
+	cache.errs = nil
+}
+
+// Return the authentication with the credentials that use the cmd:, file: or env: prefixes replaced by the values
+// they refer to. The prefix is the configuration path used to report errors, e.g. send.authentication
+func resolveAuthentication(ctx context.Context, cache *credentialCache, prefix string,
+	auth Authentication) (Authentication, error) {
+	err := auth.forEachCredential(prefix, func(path string, value *string) error {
+		return resolveCredentialValue(ctx, cache, path, value)
+	})
+	return auth, err
+}
+
+// Return the account with the credentials of its service that use the cmd:, file: or env: prefixes replaced by
+// the values they refer to.
+func resolveAccount(ctx context.Context, cache *credentialCache, account Account) (Account, error) {
+	prefix := "accounts." + account.Service + "." + account.Name
+
+	var err error
+	switch account.Service {
+	case ServiceTwitter:
+		account.Authentication, err = resolveAuthentication(ctx, cache, prefix, account.Authentication)
+	case ServiceMastodon:
+		err = resolveCredentialValue(ctx, cache, prefix+".access_token", &account.AccessToken)
+	case ServiceBluesky:
+		err = resolveCredentialValue(ctx, cache, prefix+".app_password", &account.AppPassword)
+	}
+	return account, err
+}
+
+// Replace the value with the credential it refers to. The path is the configuration path used to report errors.
+func resolveCredentialValue(ctx context.Context, cache *credentialCache, path string, value *string) error {
+	resolved, err := cache.resolve(ctx, *value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	*value = resolved
+	return nil
+}
+
+// Return the bearer token required by the REST API with the cmd:, file: or env: prefix resolved.
+func (config Config) ServeToken(ctx context.Context) (string, error) {
+	token := config.Serve.Token
+	err := resolveCredentialValue(ctx, nil, "serve.token", &token)
+	return token, err
+}
+
+// Return true when the value refers to a credential using one of the cmd:, file: or env: prefixes.
+func isCredentialReference(value string) bool {
+	return strings.HasPrefix(value, credentialCmd) || strings.HasPrefix(value, credentialFile) ||
+		strings.HasPrefix(value, credentialEnv)
+}
+
+// Return the value of the credential. Values without one of the credential prefixes are returned as is.
+// The errors never contain the value that was read.
+func resolveCredential(ctx context.Context, value string) (string, error) {
+	var resolved string
+
+	switch {
+	case strings.HasPrefix(value, credentialCmd):
+		output, err := runCredentialCommand(ctx, strings.TrimPrefix(value, credentialCmd))
+		if err != nil {
+			return "", err
+		}
+		resolved = output
+
+	case strings.HasPrefix(value, credentialFile):
+		filePath := strings.TrimSpace(strings.TrimPrefix(value, credentialFile))
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrCredential, err)
+		}
+		resolved = string(data)
+
+	case strings.HasPrefix(value, credentialEnv):
+		name := strings.TrimSpace(strings.TrimPrefix(value, credentialEnv))
+		env, present := os.LookupEnv(name)
+		if !present {
+			return "", fmt.Errorf("%w: the environment variable %q is not set", ErrCredential, name)
+		}
+		resolved = env
+
+	default:
+		return value, nil
+	}
+
+	resolved = strings.TrimSpace(resolved)
+	if resolved == "" {
+		return "", fmt.Errorf("%w: %q resolved to an empty value", ErrCredential, value)
+	}
+	return resolved, nil
+}
+
+// Run the command line using the shell and return its output.
+func runCredentialCommand(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, credentialCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%w: the command %q did not finish: %s", ErrCredential, command, ctx.Err())
+		}
+		// Only the error output is reported, the output could contain (part of) the secret
+		if message := strings.TrimSpace(strings.SplitN(stderr.String(), "\n", 2)[0]); message != "" {
+			return "", fmt.Errorf("%w: the command %q failed: %s: %s", ErrCredential, command, err, message)
+		}
+		return "", fmt.Errorf("%w: the command %q failed: %s", ErrCredential, command, err)
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveCredential(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The commands require sh")
+	}
+
+	filePath := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(filePath, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AJTWEET_TEST_CREDENTIAL", "from-env")
+
+	tests := []struct {
+		value    string
+		expected string
+		err      error
+	}{
+		{"literal", "literal", nil},
+		{"", "", nil},
+		{"cmd:echo from-cmd", "from-cmd", nil},
+		{"cmd:printf 'from-%s' pipe | tr a-z A-Z", "FROM-PIPE", nil},
+		{"cmd:exit 3", "", ErrCredential},
+		{"cmd:true", "", ErrCredential},
+		{"file:" + filePath, "from-file", nil},
+		{"file:" + filePath + ".missing", "", ErrCredential},
+		{"env:AJTWEET_TEST_CREDENTIAL", "from-env", nil},
+		{"env:AJTWEET_TEST_MISSING", "", ErrCredential},
+	}
+
+	for _, test := range tests {
+		result, err := resolveCredential(context.Background(), test.value)
+		if !errors.Is(err, test.err) || result != test.expected {
+			t.Fatalf("Expected %q, error: %v for %q. Result: %q, error: %v", test.expected, test.err, test.value, result, err)
+		}
+	}
+}
+
+func TestResolveAuthenticationErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The commands require sh")
+	}
+
+	auth := Authentication{
+		APIKey:    "key",
+		APISecret: "cmd:echo top-secret; echo failed >&2; exit 1",
+	}
+
+	_, err := resolveAuthentication(context.Background(), nil, "accounts.twitter.product", auth)
+	if !errors.Is(err, ErrCredential) || !strings.Contains(err.Error(), "accounts.twitter.product.api_secret") {
+		t.Fatalf("Expected the field to be reported. Result: %v", err)
+	}
+
+	if strings.Contains(strings.ReplaceAll(err.Error(), "echo top-secret", ""), "top-secret") {
+		t.Fatalf("Expected the output not to be reported. Result: %v", err)
+	}
+}
+
+func TestCredentialsResolvedWhenSending(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The commands require sh")
+	}
+
+	mock, server := newMockTwitter(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+	app.config.Send.Authentication.APIKey = "cmd:echo run >> " + counter + "; echo key"
+
+	// Nothing is due
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, message := range []string{"Hello Twitter", "Hello again"} {
+		if err := app.Add(message, time.Now().Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := app.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Send(context.Background(), &bytes.Buffer{}, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(counter); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the command not to be run before sending. Error: %v", err)
+	}
+	if err := app.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// The resolved credential is kept, e.g. for the next time the daemon sends
+	if err := app.Add("Hello daemon", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := os.ReadFile(counter); err != nil || string(data) != "run\n" {
+		t.Fatalf("Expected the command to be run once. Result: %q, error: %v", data, err)
+	}
+
+	if posts := mock.Posts(); len(posts) != 3 {
+		t.Fatalf("Unexpected posts: %v", posts)
+	}
+
+	if app.config.Send.Authentication.APIKey == "key" {
+		t.Fatal("Expected the configuration to keep the reference")
+	}
+}
+
+func TestAccountCredentialsResolvedWhenSending(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The commands require sh")
+	}
+
+	fake := newFakeMastodon(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	t.Setenv("AJTWEET_TEST_MASTODON_TOKEN", fakeMastodonToken)
+	counter := filepath.Join(t.TempDir(), "counter")
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Accounts.Mastodon = map[string]Mastodon{
+		"product": {Instance: fake.server.URL, AccessToken: "env:AJTWEET_TEST_MASTODON_TOKEN"},
+	}
+	app.config.Accounts.Bluesky = map[string]Bluesky{
+		"news": {Handle: "news.example.com", AppPassword: "cmd:echo run >> " + counter + "; exit 1"},
+	}
+
+	// The broken Bluesky app password is not needed to send from the Mastodon account
+	if err := app.AddWith("Hello Mastodon", time.Now().Format(time.RFC3339), AddOptions{Account: "product"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.statuses) != 1 {
+		t.Fatalf("Expected the status to be posted using the resolved token. Result: %v", fake.statuses)
+	}
+	if _, err := os.Stat(counter); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the command of the unused account not to be run. Error: %v", err)
+	}
+
+	if err := app.AddWith("Hello Bluesky", time.Now().Format(time.RFC3339), AddOptions{Account: "news"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); !errors.Is(err, ErrCredential) ||
+		!strings.Contains(err.Error(), "accounts.bluesky.news.app_password") {
+		t.Fatalf("Expected error: %q. Result: %v", ErrCredential, err)
+	}
+}
+
+func TestServeToken(t *testing.T) {
+	t.Setenv("AJTWEET_TEST_SERVE_TOKEN", "token")
+
+	config := NewConfig()
+	config.Serve.Token = "env:AJTWEET_TEST_SERVE_TOKEN"
+	if token, err := config.ServeToken(context.Background()); err != nil || token != "token" {
+		t.Fatalf("Expected the token to be resolved. Result: %q, error: %v", token, err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAlreadyDelivered = errors.New("the tweet has already been sent to some of its destinations")
+)
+
+// TweetChanges specifies the changes to be made to a tweet. Only the non nil values are changed.
+type TweetChanges struct {
+	Message       *string   // The new message.
+	ScheduledTime *string   // The new scheduled time in the RFC 3339 standard, e.g. 2006-03-05T10:42:01Z
+	Tags          *[]string // The new tags, an empty slice removes all the tags.
+	Status        *string   // Either paused (to pause the tweet) or scheduled (to resume the tweet).
+	Force         bool      // Change the message even though the tweet has already been sent to some of its destinations.
+}
+
+// Return the tweet matching the identifier or the unique prefix of an identifier.
+func (app *Application) Get(idString string) (tweet.Tweet, error) {
+	id, err := app.tweets.Resolve(idString)
+	if err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	_, index := app.tweets.Find(id)
+	return app.tweets.Tweets[index], nil
+}
+
+// Make the changes to the tweet matching the specified identifier and return the changed tweet.
+// The tweet is not changed when any of the changes are invalid.
+// The message of a tweet that has already been sent to some of its destinations can only be changed when forced,
+// since the remaining destinations would receive a different message.
+func (app *Application) Edit(id uuid.UUID, changes TweetChanges) (tweet.Tweet, error) {
+	var edited tweet.Tweet
+	forced := false
+	err := app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		if changes.Message != nil {
+			changed := *changes.Message != tw.Message
+			if changed && tw.IsPartlyDelivered() {
+				if !changes.Force {
+					return fmt.Errorf("%w, the message can only be changed when forced", ErrAlreadyDelivered)
+				}
+				forced = true
+			}
+			tw.Message = *changes.Message
+			if err := app.validateTargets(*tw); err != nil {
+				return err
+			}
+
+			// The approval was given for the previous message, the new message has to be approved again
+			if changed && tw.NeedsApproval {
+				tw.RequireApproval()
+			}
+		}
+
+		if changes.ScheduledTime != nil {
+			scheduledTime, err := parseTime(*changes.ScheduledTime)
+			if err != nil {
+				return err
+			}
+			tw.ScheduledTime = scheduledTime
+		}
+
+		if changes.Tags != nil {
+			tw.Tags = nil
+			if len(*changes.Tags) > 0 {
+				tw.Tags = append([]string(nil), *changes.Tags...)
+			}
+		}
+
+		if changes.Status != nil {
+			status, err := tweet.ParseStatus(*changes.Status)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case status == tw.Status:
+			case status == tweet.StatusPaused:
+				err = tw.Pause()
+			case status == tweet.StatusScheduled:
+				err = tw.Resume()
+			default:
+				err = fmt.Errorf("%w: the status can only be changed to paused or scheduled", tweet.ErrInvalidTransition)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		edited = *tw
+		return nil
+	})
+	if err != nil {
+		return tweet.Tweet{}, err
+	}
+
+	if forced {
+		app.logger.Warn("Message of a tweet that has already been sent to some of its destinations was changed",
+			logging.F("tweet_id", id))
+	}
+	app.logger.Info("Tweet edited", logging.F("tweet_id", id))
+	return edited, nil
+}
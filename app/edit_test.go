@@ -0,0 +1,197 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+func TestEdit(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Bluesky = map[string]Bluesky{"news": {}}
+
+	added, err := app.AddTweet("Hello", "2030-01-01T10:00:00Z", AddOptions{Tags: []string{"campaign-x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := app.Get(added.Id.String()[:8])
+	if err != nil || got.Id != added.Id || len(got.Tags) != 1 {
+		t.Fatalf("Unexpected tweet: %v, error: %v", got, err)
+	}
+
+	message, scheduled, status := "Hello again", "2030-02-01T10:00:00Z", "paused"
+	tags := []string{}
+	edited, err := app.Edit(added.Id, TweetChanges{Message: &message, ScheduledTime: &scheduled, Tags: &tags, Status: &status})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Message != message || edited.ScheduledTime.Format(time.RFC3339) != scheduled || edited.Tags != nil ||
+		edited.Status != tweet.StatusPaused || app.tweets.Tweets[0].Message != message {
+		t.Fatalf("Unexpected tweet: %v", edited)
+	}
+
+	// Invalid changes do not change the tweet
+	invalid := "tomorrow"
+	if _, err := app.Edit(added.Id, TweetChanges{Message: &message, ScheduledTime: &invalid}); err == nil {
+		t.Fatal("Expected an invalid time error")
+	}
+
+	status = "failed"
+	if _, err := app.Edit(added.Id, TweetChanges{Status: &status}); !errors.Is(err, tweet.ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", tweet.ErrInvalidTransition, err)
+	}
+
+	status = "scheduled"
+	if edited, err = app.Edit(added.Id, TweetChanges{Status: &status}); err != nil || edited.Status != tweet.StatusScheduled {
+		t.Fatalf("Expected the tweet to be resumed. Result: %v, error: %v", edited, err)
+	}
+
+	// The message is validated for the account
+	reply, err := app.AddTweet("Hi", "2030-01-01T10:00:00Z", AddOptions{Account: "bluesky:news"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := string(make([]rune, 301))
+	if _, err := app.Edit(reply.Id, TweetChanges{Message: &long}); !errors.Is(err, ErrMessageTooLong) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMessageTooLong, err)
+	}
+
+	if _, err := app.Get("ffffffff-ffff-ffff-ffff-ffffffffffff"); !errors.Is(err, tweet.ErrNotExists) {
+		t.Fatalf("Expected error: %q. Result: %q", tweet.ErrNotExists, err)
+	}
+}
+
+func TestWithLock(t *testing.T) {
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	lockFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Lockfile = lockFile
+
+	err = app.WithLock(func() error {
+		if !app.isLocked() {
+			t.Fatal("Expected the lock to be held")
+		}
+		if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+			return err
+		}
+		return app.Save()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if app.isLocked() {
+		t.Fatal("Expected the lock to be released")
+	}
+
+	// The tweets are reloaded each time
+	app.tweets = tweet.TweetList{}
+	if err := app.WithLock(func() error { return nil }); err != nil || len(app.tweets.Tweets) != 1 {
+		t.Fatalf("Expected the tweets to be reloaded. Result: %v, error: %v", app.tweets.Tweets, err)
+	}
+
+	// Another instance holds the lock
+	if err := app.AcquireLock(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.ReleaseLock()
+
+	if err := app.WithLock(func() error { return nil }); !errors.Is(err, ErrLockfileExists) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrLockfileExists, err)
+	}
+}
+
+func TestEditMessageRequiresApprovalAgain(t *testing.T) {
+	app := Application{}
+
+	added, err := app.AddTweet("Hello", "2030-01-01T10:00:00Z", AddOptions{NeedsApproval: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Approve(added.Id, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changing the other values keeps the approval
+	tags := []string{"launch"}
+	if edited, err := app.Edit(added.Id, TweetChanges{Tags: &tags}); err != nil || !edited.IsApproved() {
+		t.Fatalf("Expected the tweet to remain approved. Result: %v, error: %v", edited, err)
+	}
+
+	message := "Hello, never reviewed"
+	edited, err := app.Edit(added.Id, TweetChanges{Message: &message})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Status != tweet.StatusPending || edited.IsApproved() || app.tweets.Tweets[0].IsApproved() {
+		t.Fatalf("Expected the edited tweet to need approval again. Result: %v", edited)
+	}
+}
+
+func TestEditMessageAfterDelivery(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+	app.config.Accounts.Bluesky = map[string]Bluesky{"news": {}}
+
+	added, err := app.AddTweet("Hello", "2030-01-01T10:00:00Z", AddOptions{Destinations: []string{"mastodon:product", "bluesky:news"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.tweets.Tweets[0].Delivered("mastodon:product", "101", "")
+
+	// The remaining destinations would receive a different message than the ones already sent to
+	message := "Hello again"
+	if _, err := app.Edit(added.Id, TweetChanges{Message: &message}); !errors.Is(err, ErrAlreadyDelivered) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrAlreadyDelivered, err)
+	}
+	if app.tweets.Tweets[0].Message != "Hello" {
+		t.Fatalf("Expected the message to be kept. Result: %q", app.tweets.Tweets[0].Message)
+	}
+
+	// The other values and the same message can still be changed
+	same, tags := "Hello", []string{"launch"}
+	if _, err := app.Edit(added.Id, TweetChanges{Message: &same, Tags: &tags}); err != nil {
+		t.Fatal(err)
+	}
+
+	edited, err := app.Edit(added.Id, TweetChanges{Message: &message, Force: true})
+	if err != nil || edited.Message != message {
+		t.Fatalf("Expected the message to be changed when forced. Result: %v, error: %v", edited, err)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The prefix of the environment variables that override the configuration values, e.g. AJTWEET_SEND_MAX
+const EnvPrefix = "AJTWEET"
+
+// ConfigKey describes a configuration value that can be set in the configuration file or the environment.
+type ConfigKey struct {
+	Key     string   // The configuration path, e.g. send.max
+	Env     string   // The environment variable, e.g. AJTWEET_SEND_MAX
+	Aliases []string // Older environment variables that take precedence over Env, e.g. AJTWEET_API_KEY
+	Type    string   // The type of the value: string, int, bool or list (comma separated).
+	Default string   // The default value set by NewConfig.
+}
+
+// The types of the configuration values.
+const (
+	typeString = "string"
+	typeInt    = "int"
+	typeBool   = "bool"
+	typeList   = "list"
+)
+
+var (
+	ErrInvalidEnv = errors.New("invalid environment variable")
+)
+
+// The environment variables that were supported before every configuration value could be set using the
+// environment, keyed by the configuration path.
+var envAliases = map[string][]string{
+	"send.authentication.api_key":       {envAPIKey},
+	"send.authentication.api_secret":    {envAPISecret},
+	"send.authentication.oauth1.token":  {envOAuth1Token},
+	"send.authentication.oauth1.secret": {envOAuth1Secret},
+}
+
+// Return all the configuration values that can be set using the environment, in the order of the Config fields.
+// The accounts and hooks can only be set in the configuration file.
+func ConfigKeys() []ConfigKey {
+	defaults := NewConfig()
+	return configKeys(reflect.ValueOf(defaults), "")
+}
+
+func configKeys(value reflect.Value, prefix string) []ConfigKey {
+	var keys []ConfigKey
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := strings.ToLower(field.Name)
+		if tag := field.Tag.Get("mapstructure"); tag != "" {
+			name = tag
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fieldValue := value.Field(i)
+		key := ConfigKey{
+			Key:     name,
+			Env:     EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(name, ".", "_")),
+			Aliases: envAliases[name],
+		}
+
+		switch fieldValue.Kind() {
+		case reflect.Struct:
+			keys = append(keys, configKeys(fieldValue, name)...)
+			continue
+		case reflect.String:
+			key.Type, key.Default = typeString, fieldValue.String()
+		case reflect.Int:
+			key.Type, key.Default = typeInt, strconv.FormatInt(fieldValue.Int(), 10)
+		case reflect.Bool:
+			key.Type, key.Default = typeBool, strconv.FormatBool(fieldValue.Bool())
+		case reflect.Slice:
+			if fieldValue.Type().Elem().Kind() != reflect.String {
+				continue
+			}
+			key.Type, key.Default = typeList, strings.Join(fieldValue.Interface().([]string), ",")
+		default:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Bind each of the configuration values to its environment variable, e.g. send.max to AJTWEET_SEND_MAX
+// Values that are only found in the configuration file (e.g. accounts.mastodon.product.access_token) can also be
+// overridden using the same naming convention.
+func BindEnv(v *viper.Viper) error {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	for _, key := range ConfigKeys() {
+		if err := v.BindEnv(key.Key, key.Env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check that the environment variables that are set contain values of the expected types.
+func ValidateEnv() error {
+	for _, key := range ConfigKeys() {
+		value, present := os.LookupEnv(key.Env)
+		if !present {
+			continue
+		}
+
+		var err error
+		switch key.Type {
+		case typeInt:
+			_, err = strconv.Atoi(value)
+		case typeBool:
+			_, err = strconv.ParseBool(value)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%w: %s: expected a value of type %s, got %q", ErrInvalidEnv, key.Env, key.Type, value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestConfigKeys(t *testing.T) {
+	keys := make(map[string]ConfigKey)
+	for _, key := range ConfigKeys() {
+		if expected := "AJTWEET_" + strings.ToUpper(strings.ReplaceAll(key.Key, ".", "_")); key.Env != expected {
+			t.Fatalf("Expected %q for %q. Result: %q", expected, key.Key, key.Env)
+		}
+		keys[key.Key] = key
+	}
+
+	tests := []struct {
+		key      string
+		typ      string
+		defaults string
+	}{
+		{"datastore.filepath", typeString, DefaultDatastore()},
+		{"send.max", typeInt, "10"},
+		{"send.delay", typeInt, "1"},
+		{"send.max_attempts", typeInt, "3"},
+		{"send.timeout", typeInt, "30"},
+		{"send.authentication.oauth1.token", typeString, ""},
+		{"approval.required", typeBool, "false"},
+		{"approval.approvers", typeList, ""},
+		{"log.max_size", typeInt, "10"},
+		{"serve.token", typeString, ""},
+		{"secrets.key_file", typeString, ""},
+		{"lockfile", typeString, DefaultLockfile()},
+	}
+
+	for _, test := range tests {
+		key, exists := keys[test.key]
+		if !exists {
+			t.Fatalf("Expected the key %q", test.key)
+		}
+		if key.Type != test.typ || key.Default != test.defaults {
+			t.Fatalf("Unexpected type or default for %q: %v", test.key, key)
+		}
+	}
+
+	if _, exists := keys["hooks"]; exists {
+		t.Fatal("Expected the hooks not to be bound to the environment")
+	}
+
+	if aliases := keys["send.authentication.api_key"].Aliases; !reflect.DeepEqual(aliases, []string{envAPIKey}) {
+		t.Fatalf("Unexpected aliases: %v", aliases)
+	}
+}
+
+func TestBindEnv(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+		check func(config Config) bool
+	}{
+		{"AJTWEET_SEND_MAX", "25", func(c Config) bool { return c.Send.Max == 25 }},
+		{"AJTWEET_SEND_DELAY", "0", func(c Config) bool { return c.Send.Delay == 0 }},
+		{"AJTWEET_SEND_MAX_ATTEMPTS", "5", func(c Config) bool { return c.Send.MaxAttempts == 5 }},
+		{"AJTWEET_SEND_API_BASE_URL", "http://localhost:8080", func(c Config) bool { return c.Send.APIBaseURL == "http://localhost:8080" }},
+		{"AJTWEET_SEND_AUTHENTICATION_OAUTH1_SECRET", "s", func(c Config) bool { return c.Send.Authentication.OAuth1.Secret == "s" }},
+		{"AJTWEET_DATASTORE_FILEPATH", "/data/tweets.json", func(c Config) bool { return c.Datastore.Filepath == "/data/tweets.json" }},
+		{"AJTWEET_LOCKFILE", "/run/ajtweet.lock", func(c Config) bool { return c.Lockfile == "/run/ajtweet.lock" }},
+		{"AJTWEET_APPROVAL_REQUIRED", "true", func(c Config) bool { return c.Approval.Required }},
+		{"AJTWEET_APPROVAL_APPROVERS", "alice,bob", func(c Config) bool {
+			return reflect.DeepEqual(c.Approval.Approvers, []string{"alice", "bob"})
+		}},
+		{"AJTWEET_LOG_FORMAT", "json", func(c Config) bool { return c.Log.Format == "json" }},
+		{"AJTWEET_SECRETS_KEY_FILE", "/etc/ajtweet/key", func(c Config) bool { return c.Secrets.KeyFile == "/etc/ajtweet/key" }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.env, func(t *testing.T) {
+			t.Setenv(test.env, test.value)
+
+			v := viper.New()
+			if err := BindEnv(v); err != nil {
+				t.Fatal(err)
+			}
+
+			config := NewConfig()
+			if err := v.Unmarshal(&config); err != nil {
+				t.Fatal(err)
+			}
+
+			if !test.check(config) {
+				t.Fatalf("Expected %s=%s to be applied. Result: %+v", test.env, test.value, config)
+			}
+
+			// The other values keep their defaults
+			if test.env != "AJTWEET_SEND_MAX" && config.Send.Max != defaultSendMax {
+				t.Fatalf("Expected send.max == %d. Result %d", defaultSendMax, config.Send.Max)
+			}
+		})
+	}
+}
+
+func TestBindEnvConfigFileKeys(t *testing.T) {
+	t.Setenv("AJTWEET_ACCOUNTS_MASTODON_PRODUCT_ACCESS_TOKEN", "from-env")
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	data := `
+accounts:
+  mastodon:
+    product:
+      instance: https://mastodon.social
+      access_token: from-file
+`
+	if err := v.ReadConfig(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := BindEnv(v); err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewConfig()
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if token := config.Accounts.Mastodon["product"].AccessToken; token != "from-env" {
+		t.Fatalf("Expected the environment to override the config file. Result: %q", token)
+	}
+}
+
+func TestValidateEnv(t *testing.T) {
+	tests := []struct {
+		env   string
+		value string
+		err   error
+	}{
+		{"AJTWEET_SEND_MAX", "10", nil},
+		{"AJTWEET_SEND_MAX", " 10 ", ErrInvalidEnv},
+		{"AJTWEET_SEND_MAX", "ten", ErrInvalidEnv},
+		{"AJTWEET_SEND_DELAY", "1.5", ErrInvalidEnv},
+		{"AJTWEET_APPROVAL_REQUIRED", "yes", ErrInvalidEnv},
+		{"AJTWEET_APPROVAL_REQUIRED", "1", nil},
+		{"AJTWEET_LOG_LEVEL", "anything", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.env+"="+test.value, func(t *testing.T) {
+			t.Setenv(test.env, test.value)
+			if err := ValidateEnv(); !errors.Is(err, test.err) {
+				t.Fatalf("Expected error: %v. Result: %v", test.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"gopkg.in/yaml.v3"
+)
+
+// Write all the scheduled tweets to the specified io.Writer using the specified format.
+func (app *Application) Export(out io.Writer, format Format) error {
+	tweets := app.tweets.List()
+
+	switch format {
+	case FormatCSV:
+		return exportCSV(out, tweets)
+	case FormatJSONL:
+		return exportJSONL(out, tweets)
+	case FormatYAML:
+		return exportYAML(out, tweets)
+	case FormatICS:
+		return exportICS(out, tweets, time.Now())
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// The columns (and keys) match the default ImportMapping so that the exported files can be imported again.
+type exportRecord struct {
+	Id            string   `yaml:"id"`
+	Message       string   `yaml:"message"`
+	ScheduledTime string   `yaml:"scheduledTime"`
+	Account       string   `yaml:"account,omitempty"`
+	Tags          []string `yaml:"tags,omitempty"`
+}
+
+func newExportRecord(tw tweet.Tweet) exportRecord {
+	return exportRecord{
+		Id:            tw.Id.String(),
+		Message:       tw.Message,
+		ScheduledTime: tw.ScheduledTime.Format(time.RFC3339),
+		Account:       strings.Join(tw.Accounts(), ","),
+		Tags:          tw.Tags,
+	}
+}
+
+func exportCSV(out io.Writer, tweets []tweet.Tweet) error {
+	writer := csv.NewWriter(out)
+
+	if err := writer.Write([]string{"id", "message", "scheduledTime", "account", "tags"}); err != nil {
+		return err
+	}
+
+	for _, tw := range tweets {
+		record := newExportRecord(tw)
+		row := []string{record.Id, record.Message, record.ScheduledTime, record.Account, strings.Join(record.Tags, ",")}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportJSONL(out io.Writer, tweets []tweet.Tweet) error {
+	encoder := json.NewEncoder(out)
+	for _, tw := range tweets {
+		if err := encoder.Encode(tw); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportYAML(out io.Writer, tweets []tweet.Tweet) error {
+	records := make([]exportRecord, 0, len(tweets))
+	for _, tw := range tweets {
+		records = append(records, newExportRecord(tw))
+	}
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(records); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+const icsTimeFormat = "20060102T150405Z"
+
+// Write the tweets as an iCalendar (RFC 5545) with one event per tweet.
+// The event's UID is derived from the tweet's identifier so that calendar apps will update
+// existing events instead of creating duplicates.
+func exportICS(out io.Writer, tweets []tweet.Tweet, now time.Time) error {
+	w := icsWriter{out: out}
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:-//andrejacobs//ajtweet//EN")
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.line("X-WR-CALNAME:ajtweet")
+
+	for _, tw := range tweets {
+		w.line("BEGIN:VEVENT")
+		w.line("UID:" + tw.Id.String() + "@ajtweet")
+		w.line("DTSTAMP:" + now.UTC().Format(icsTimeFormat))
+		w.line("DTSTART:" + tw.ScheduledTime.UTC().Format(icsTimeFormat))
+		w.line("SUMMARY:" + icsEscape(tw.Message))
+
+		description := tw.Message
+		if accounts := tw.Accounts(); len(accounts) > 0 {
+			description += "\n\naccount: " + strings.Join(accounts, ", ")
+		}
+		w.line("DESCRIPTION:" + icsEscape(description))
+
+		if len(tw.Tags) > 0 {
+			escaped := make([]string, len(tw.Tags))
+			for i, tag := range tw.Tags {
+				escaped[i] = icsEscape(tag)
+			}
+			w.line("CATEGORIES:" + strings.Join(escaped, ","))
+		}
+		w.line("END:VEVENT")
+	}
+
+	w.line("END:VCALENDAR")
+	return w.err
+}
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func icsEscape(value string) string {
+	return icsEscaper.Replace(value)
+}
+
+// icsWriter writes content lines terminated by CRLF and folds lines longer than 75 octets.
+type icsWriter struct {
+	out io.Writer
+	err error
+}
+
+const icsMaxLineLength = 75
+
+func (w *icsWriter) line(content string) {
+	if w.err != nil {
+		return
+	}
+
+	var b strings.Builder
+	length := 0
+	for _, r := range content {
+		size := utf8.RuneLen(r)
+		if length+size > icsMaxLineLength {
+			// Continuation lines start with a single space which counts towards the length
+			b.WriteString("\r\n ")
+			length = 1
+		}
+		b.WriteRune(r)
+		length += size
+	}
+	b.WriteString("\r\n")
+
+	_, w.err = io.WriteString(w.out, b.String())
+}
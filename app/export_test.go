@@ -0,0 +1,183 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+func TestExportCSVCanBeImported(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+
+	tw := tweet.New("Hello, world", time.Date(2032, 5, 16, 19, 42, 0, 0, time.UTC))
+	tw.Account = "mastodon:product"
+	tw.Tags = []string{"a", "b"}
+	app.tweets.Add(tw)
+
+	var buffer bytes.Buffer
+	if err := app.Export(&buffer, FormatCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf("id,message,scheduledTime,account,tags\n%s,\"Hello, world\",2032-05-16T19:42:00Z,mastodon:product,\"a,b\"\n", tw.Id)
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%q\n\nResult:\n%q\n", expected, buffer.String())
+	}
+
+	imported := Application{}
+	imported.config.Accounts = app.config.Accounts
+	if err := imported.Import(io.Discard, &buffer, FormatCSV, NewImportMapping()); err != nil {
+		t.Fatal(err)
+	}
+
+	result := imported.tweets.Tweets[0]
+	if result.Message != tw.Message || !result.ScheduledTime.Equal(tw.ScheduledTime) ||
+		result.Account != tw.Account || len(result.Tags) != 2 {
+		t.Fatalf("Imported tweet does not match. Expected: %s, Result: %s", tw, result)
+	}
+}
+
+func TestExportYAMLCanBeImported(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet 1", "2032-05-16T19:42:00Z")
+	app.Add("Tweet 2", "2032-05-17T19:42:00Z")
+
+	var buffer bytes.Buffer
+	if err := app.Export(&buffer, FormatYAML); err != nil {
+		t.Fatal(err)
+	}
+
+	imported := Application{}
+	if err := imported.Import(io.Discard, &buffer, FormatYAML, NewImportMapping()); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(imported.tweets.Tweets); count != 2 {
+		t.Fatalf("Expected 2 tweets, Result: %d", count)
+	}
+}
+
+func TestReimportExportIsRejected(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet 1", "2032-05-16T19:42:00Z")
+	app.Add("Tweet 2", "2032-05-17T19:42:00Z")
+
+	for _, format := range []Format{FormatCSV, FormatJSONL, FormatYAML} {
+		var buffer bytes.Buffer
+		if err := app.Export(&buffer, format); err != nil {
+			t.Fatal(err)
+		}
+
+		err := app.Import(io.Discard, &buffer, format, NewImportMapping())
+		var importErr *ImportError
+		if !errors.As(err, &importErr) || len(importErr.Rows) != 2 || !strings.Contains(err.Error(), "already in the queue") {
+			t.Fatalf("%s: Expected the tweets to be rejected. Result: %v", format, err)
+		}
+		if count := len(app.tweets.Tweets); count != 2 {
+			t.Fatalf("%s: Expected the tweets not to be duplicated. Result: %d", format, count)
+		}
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet 1", "2032-05-16T19:42:00Z")
+	app.Add("Tweet 2", "2032-05-17T19:42:00Z")
+
+	var buffer bytes.Buffer
+	if err := app.Export(&buffer, FormatJSONL); err != nil {
+		t.Fatal(err)
+	}
+
+	tweets := app.tweets.List()
+	expected := fmt.Sprintf(`{"id":"%s","message":"Tweet 1","scheduledTime":"2032-05-16T19:42:00Z","status":"scheduled"}
+{"id":"%s","message":"Tweet 2","scheduledTime":"2032-05-17T19:42:00Z","status":"scheduled"}
+`, tweets[0].Id, tweets[1].Id)
+
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%q\n\nResult:\n%q\n", expected, buffer.String())
+	}
+}
+
+func TestExportICS(t *testing.T) {
+	tw := tweet.New("Hello, world; this is a long tweet that will need to be folded across multiple lines", time.Date(2032, 5, 16, 19, 42, 0, 0, time.UTC))
+	tw.Tags = []string{"launch"}
+	now := time.Date(2022, 5, 16, 10, 0, 0, 0, time.UTC)
+
+	var buffer bytes.Buffer
+	if err := exportICS(&buffer, []tweet.Tweet{tw}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"PRODID:-//andrejacobs//ajtweet//EN\r\n" +
+		"CALSCALE:GREGORIAN\r\n" +
+		"METHOD:PUBLISH\r\n" +
+		"X-WR-CALNAME:ajtweet\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:" + tw.Id.String() + "@ajtweet\r\n" +
+		"DTSTAMP:20220516T100000Z\r\n" +
+		"DTSTART:20320516T194200Z\r\n" +
+		"SUMMARY:Hello\\, world\\; this is a long tweet that will need to be folded ac\r\n" +
+		" ross multiple lines\r\n" +
+		"DESCRIPTION:Hello\\, world\\; this is a long tweet that will need to be folde\r\n" +
+		" d across multiple lines\r\n" +
+		"CATEGORIES:launch\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%q\n\nResult:\n%q\n", expected, buffer.String())
+	}
+
+	for _, line := range strings.Split(buffer.String(), "\r\n") {
+		if len(line) > icsMaxLineLength {
+			t.Fatalf("Line exceeds %d octets: %q", icsMaxLineLength, line)
+		}
+	}
+}
+
+func TestExportICSFoldsMultiByteCharacters(t *testing.T) {
+	var buffer bytes.Buffer
+	w := icsWriter{out: &buffer}
+	w.line(strings.Repeat("é", 100))
+
+	for _, line := range strings.Split(buffer.String(), "\r\n") {
+		if len(line) > icsMaxLineLength {
+			t.Fatalf("Line exceeds %d octets: %q", icsMaxLineLength, line)
+		}
+		if !utf8.ValidString(line) {
+			t.Fatalf("Multi-byte character was split: %q", line)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+// Filter specifies the criteria used to select tweets.
+// The zero value selects all the tweets.
+type Filter struct {
+	Before   string   // Scheduled before this time (RFC3339 or YYYY-MM-DD).
+	After    string   // Scheduled after this time (RFC3339 or YYYY-MM-DD).
+	Due      bool     // Only the tweets that need to be sent now.
+	Contains string   // The message contains the text, or matches the regular expression when written as /regex/.
+	Match    string   // The message matches the regular expression.
+	Tags     []string // Has at least one of the tags.
+	Accounts []string // Will be sent from one of the accounts.
+	Statuses []string // Has one of the statuses, e.g. paused.
+	Limit    int      // The maximum number of tweets to select. Zero means no limit.
+}
+
+// Return the tweets matching the filter, ordered by which tweets need to be sent first.
+func (app *Application) Query(filter Filter) ([]tweet.Tweet, error) {
+	predicates, err := filter.predicates(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return app.tweets.Query(filter.Limit, predicates...), nil
+}
+
+// Return true if the filter does not specify any criteria (the limit is not considered a criteria).
+func (filter Filter) IsEmpty() bool {
+	return filter.Before == "" && filter.After == "" && !filter.Due &&
+		filter.Contains == "" && filter.Match == "" &&
+		len(filter.Tags) == 0 && len(filter.Accounts) == 0 && len(filter.Statuses) == 0
+}
+
+func (filter Filter) predicates(now time.Time) ([]tweet.Predicate, error) {
+	var predicates []tweet.Predicate
+
+	if filter.Before != "" {
+		before, err := parseFilterTime(filter.Before)
+		if err != nil {
+			return nil, fmt.Errorf("before: %w", err)
+		}
+		predicates = append(predicates, tweet.Before(before))
+	}
+
+	if filter.After != "" {
+		after, err := parseFilterTime(filter.After)
+		if err != nil {
+			return nil, fmt.Errorf("after: %w", err)
+		}
+		predicates = append(predicates, tweet.After(after))
+	}
+
+	if filter.Due {
+		predicates = append(predicates, tweet.Due(now))
+	}
+
+	if filter.Contains != "" {
+		if len(filter.Contains) > 1 && strings.HasPrefix(filter.Contains, "/") && strings.HasSuffix(filter.Contains, "/") {
+			re, err := regexp.Compile(filter.Contains[1 : len(filter.Contains)-1])
+			if err != nil {
+				return nil, fmt.Errorf("contains: %w", err)
+			}
+			predicates = append(predicates, tweet.Matches(re))
+		} else {
+			predicates = append(predicates, tweet.Contains(filter.Contains))
+		}
+	}
+
+	if filter.Match != "" {
+		re, err := regexp.Compile(filter.Match)
+		if err != nil {
+			return nil, fmt.Errorf("match: %w", err)
+		}
+		predicates = append(predicates, tweet.Matches(re))
+	}
+
+	if len(filter.Tags) > 0 {
+		predicates = append(predicates, tweet.HasTag(filter.Tags...))
+	}
+
+	if len(filter.Accounts) > 0 {
+		predicates = append(predicates, tweet.ForAccount(filter.Accounts...))
+	}
+
+	if len(filter.Statuses) > 0 {
+		statuses := make([]tweet.Status, 0, len(filter.Statuses))
+		for _, value := range filter.Statuses {
+			status, err := tweet.ParseStatus(value)
+			if err != nil {
+				return nil, fmt.Errorf("status: %w", err)
+			}
+			statuses = append(statuses, status)
+		}
+		predicates = append(predicates, tweet.HasStatus(statuses...))
+	}
+
+	return predicates, nil
+}
+
+// Parse either a RFC3339 time or a date (YYYY-MM-DD) in the local time zone.
+func parseFilterTime(timeString string) (time.Time, error) {
+	if t, err := parseTime(timeString); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", timeString, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected the RFC3339 or YYYY-MM-DD format", timeString)
+}
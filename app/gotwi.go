@@ -1,8 +1,19 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/michimani/gotwi"
 	"github.com/michimani/gotwi/tweet/managetweet"
@@ -13,38 +24,239 @@ import (
 // https://github.com/michimani/gotwi/blob/main/_examples/2_post_delete_tweet/main.go
 // https://github.com/andrejacobs/example_twitter_golang
 
-type twitterClient struct {
+const (
+	twitterMediaUploadURL = "https://upload.twitter.com/1.1/media/upload.json"
+	twitterStatusURL      = "https://twitter.com/i/web/status/"
+)
+
+// Sender implementation for Twitter using gotwi.
+type twitterSender struct {
+	auth        Authentication
+	apiBaseURL  string // When set the requests are sent to this server instead, e.g. ajtweet mock-server.
+	httpClient  *http.Client
 	gotwiClient *gotwi.Client
+	rateLimits  *rateLimitRecorder
+}
+
+func newTwitterSender(auth Authentication) *twitterSender {
+	return &twitterSender{auth: auth}
+}
+
+func (s *twitterSender) RateLimit() *RateLimit {
+	return s.rateLimits.RateLimit()
+}
+
+func (s *twitterSender) Configure() error {
+	if s.auth.APIKey == "" {
+		return fmt.Errorf("%w: API Key", ErrMissingAuth)
+	}
+
+	if s.auth.APISecret == "" {
+		return fmt.Errorf("%w: API Secret", ErrMissingAuth)
+	}
+
+	if s.auth.OAuth1.Token == "" {
+		return fmt.Errorf("%w: OAuth 1 User token", ErrMissingAuth)
+	}
+
+	if s.auth.OAuth1.Secret == "" {
+		return fmt.Errorf("%w: OAuth 1 User secret", ErrMissingAuth)
+	}
+
+	httpClient := s.httpClient
+	if s.apiBaseURL != "" {
+		var err error
+		if httpClient, err = redirectedClient(s.httpClient, s.apiBaseURL); err != nil {
+			return err
+		}
+	}
+
+	client, err := newOAuth1Client(s.auth, httpClient)
+	if err != nil {
+		return err
+	}
+
+	s.gotwiClient = client
+	return nil
 }
 
-func newOAuth1Client(auth Authentication) (*twitterClient, error) {
+func newOAuth1Client(auth Authentication, httpClient *http.Client) (*gotwi.Client, error) {
 
+	// gotwi reads the API key and secret from the environment while creating the client
 	os.Setenv(gotwi.APIKeyEnvName, auth.APIKey)
 	os.Setenv(gotwi.APIKeySecretEnvName, auth.APISecret)
 
 	in := &gotwi.NewClientInput{
+		HTTPClient:           httpClient,
 		AuthenticationMethod: gotwi.AuthenMethodOAuth1UserContext,
 		OAuthToken:           auth.OAuth1.Token,
 		OAuthTokenSecret:     auth.OAuth1.Secret,
 	}
 
-	client, err := gotwi.NewClient(in)
+	return gotwi.NewClient(in)
+}
+
+func (s *twitterSender) Post(ctx context.Context, text string, mediaIds []string) (PostResult, error) {
+	return s.create(ctx, &types.CreateInput{Text: gotwi.String(text)}, mediaIds)
+}
+
+func (s *twitterSender) PostReply(ctx context.Context, inReplyToId string, text string, mediaIds []string) (PostResult, error) {
+	p := &types.CreateInput{
+		Text:  gotwi.String(text),
+		Reply: &types.CreateInputReply{InReplyToTweetID: inReplyToId},
+	}
+	return s.create(ctx, p, mediaIds)
+}
+
+func (s *twitterSender) create(ctx context.Context, p *types.CreateInput, mediaIds []string) (PostResult, error) {
+	if len(mediaIds) > 0 {
+		p.Media = &types.CreateInputMedia{MediaIDs: mediaIds}
+	}
+
+	res, err := managetweet.Create(ctx, s.gotwiClient, p)
 	if err != nil {
-		return nil, err
+		return PostResult{}, err
 	}
 
-	return &twitterClient{gotwiClient: client}, nil
+	id := gotwi.StringValue(res.Data.ID)
+	return PostResult{Id: id, URL: twitterStatusURL + id}, nil
 }
 
-func sendTweet(client *twitterClient, text string) (string, error) {
-	p := &types.CreateInput{
-		Text: gotwi.String(text),
+// Upload the media using the v1.1 API since the v2 API does not support media uploads.
+func (s *twitterSender) UploadMedia(ctx context.Context, filePath string) (string, error) {
+	body, contentType, err := multipartFile("media", filePath)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, twitterMediaUploadURL, body)
+	if err != nil {
+		return "", err
 	}
+	req.Header.Set("Content-Type", contentType)
 
-	res, err := managetweet.Create(context.Background(), client.gotwiClient, p)
+	// The multipart body is not part of the OAuth 1.0a signature
+	signature, err := gotwi.CreateOAuthSignature(&gotwi.CreateOAuthSignatureInput{
+		HTTPMethod:       req.Method,
+		RawEndpoint:      req.URL.String(),
+		OAuthConsumerKey: s.gotwiClient.OAuthConsumerKey(),
+		OAuthToken:       s.gotwiClient.OAuthToken(),
+		SigningKey:       s.gotwiClient.SigningKey(),
+		ParameterMap:     map[string]string{},
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return gotwi.StringValue(res.Data.ID), nil
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`OAuth oauth_consumer_key="%s",oauth_nonce="%s",oauth_signature="%s",oauth_signature_method="%s",oauth_timestamp="%s",oauth_token="%s",oauth_version="%s"`,
+		url.QueryEscape(s.gotwiClient.OAuthConsumerKey()),
+		url.QueryEscape(signature.OAuthNonce),
+		url.QueryEscape(signature.OAuthSignature),
+		url.QueryEscape(signature.OAuthSignatureMethod),
+		url.QueryEscape(signature.OAuthTimestamp),
+		url.QueryEscape(s.gotwiClient.OAuthToken()),
+		url.QueryEscape(signature.OAuthVersion),
+	))
+
+	res, err := s.gotwiClient.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return "", fmt.Errorf("Twitter media upload failed with status %s: %s", res.Status, bytes.TrimSpace(message))
+	}
+
+	var result struct {
+		MediaId string `json:"media_id_string"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	return result.MediaId, nil
+}
+
+// Return a copy of the http.Client that sends all requests to the server at the base URL instead.
+// gotwi does not allow the API endpoints to be changed and thus the requests are redirected by the transport.
+func redirectedClient(httpClient *http.Client, baseURL string) (*http.Client, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid send.api_base_url %q: %w", baseURL, err)
+	}
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid send.api_base_url %q: expected an http or https URL", baseURL)
+	}
+
+	client := &http.Client{}
+	if httpClient != nil {
+		*client = *httpClient
+	}
+
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	client.Transport = &redirectTransport{base: base, next: next}
+	return client, nil
+}
+
+// http.RoundTripper that replaces the scheme and host of each request with those of the base URL.
+// The path of the base URL is used as a prefix to the path of the request.
+type redirectTransport struct {
+	base *url.URL
+	next http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redirected := req.Clone(req.Context())
+	redirected.URL.Scheme = t.base.Scheme
+	redirected.URL.Host = t.base.Host
+	redirected.URL.Path = strings.TrimSuffix(t.base.Path, "/") + req.URL.Path
+	redirected.URL.RawPath = ""
+	redirected.Host = ""
+	return t.next.RoundTrip(redirected)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Create a multipart/form-data body containing the file as the specified field.
+// Return the body and the content type to be used for the request.
+func multipartFile(field string, filePath string) (io.Reader, string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	contentType := mime.TypeByExtension(filepath.Ext(filePath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field), quoteEscaper.Replace(filepath.Base(filePath))))
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := io.Copy(part, file); err != nil {
+		return nil, "", err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &body, writer.FormDataContentType(), nil
 }
@@ -0,0 +1,177 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/twittertest"
+)
+
+var testTwitterAuth = Authentication{
+	APIKey:    "key",
+	APISecret: "secret",
+	OAuth1:    OAuth1{Token: "token", Secret: "token-secret"},
+}
+
+func newMockTwitter(t *testing.T) (*twittertest.Server, *httptest.Server) {
+	mock := twittertest.NewServer(twittertest.Options{})
+	server := httptest.NewServer(mock)
+	t.Cleanup(server.Close)
+	return mock, server
+}
+
+func TestSendToTwitter(t *testing.T) {
+	mock, server := newMockTwitter(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+
+	filePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(filePath, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	if err := app.AddWith("Hello Twitter", now, AddOptions{Media: []string{filePath}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.AddWith("Thanks!", now, AddOptions{InReplyTo: "42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := app.Send(context.Background(), &buffer, false); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := mock.Posts()
+	uploads := mock.Uploads()
+	if len(posts) != 2 || len(uploads) != 1 {
+		t.Fatalf("Unexpected posts: %v, uploads: %v", posts, uploads)
+	}
+
+	if posts[0].Text != "Hello Twitter" || len(posts[0].MediaIds) != 1 || posts[0].MediaIds[0] != uploads[0].MediaId {
+		t.Fatalf("Unexpected post: %v", posts[0])
+	}
+
+	if uploads[0].Filename != "image.png" || uploads[0].ContentType != "image/png" {
+		t.Fatalf("Unexpected upload: %v", uploads[0])
+	}
+
+	if posts[1].Text != "Thanks!" || posts[1].InReplyTo != "42" {
+		t.Fatalf("Unexpected reply: %v", posts[1])
+	}
+
+	if !strings.Contains(buffer.String(), "Twitter identifier: "+posts[0].Id) {
+		t.Fatalf("Expected the identifier to be reported. Result: %q", buffer.String())
+	}
+
+	if count := len(app.tweets.Tweets); count != 0 {
+		t.Fatalf("Expected the tweets to be removed. Result: %d", count)
+	}
+}
+
+func TestSendToTwitterFails(t *testing.T) {
+	mock, server := newMockTwitter(t)
+	mock.FailNext(http.StatusTooManyRequests)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+
+	if err := app.Add("Hello Twitter", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = app.Send(context.Background(), &bytes.Buffer{}, false)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("Expected the rate limit error. Result: %v", err)
+	}
+
+	if tw := app.tweets.Tweets[0]; tw.Attempts != 1 || !strings.Contains(tw.Error, "Too Many Requests") {
+		t.Fatalf("Expected the failed attempt to be recorded. Result: %v", tw)
+	}
+
+	if err := app.Send(context.Background(), &bytes.Buffer{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if posts := mock.Posts(); len(posts) != 1 || posts[0].Text != "Hello Twitter" {
+		t.Fatalf("Unexpected posts: %v", posts)
+	}
+}
+
+func TestRedirectedClient(t *testing.T) {
+	if _, err := redirectedClient(nil, "localhost:8080"); err == nil {
+		t.Fatal("Expected an error for a URL without a scheme")
+	}
+
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+	}))
+	defer server.Close()
+
+	client, err := redirectedClient(nil, server.URL+"/twitter/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get("https://api.twitter.com/2/tweets?expansions=author_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if expected := "/twitter/2/tweets?expansions=author_id"; requested != expected {
+		t.Fatalf("Expected %q. Result: %q", expected, requested)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/hooks"
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+// Create the Runner for the configured hooks. Return nil when no hooks have been configured.
+// The secrets using the cmd:, file: or env: prefixes are resolved when the webhook is first called.
+func (app *Application) configureHooks() (*hooks.Runner, error) {
+	if len(app.config.Hooks) == 0 {
+		return nil, nil
+	}
+
+	list := make([]hooks.Hook, 0, len(app.config.Hooks))
+	for i, config := range app.config.Hooks {
+		hook, err := config.hook()
+		if err != nil {
+			return nil, fmt.Errorf("hooks[%d]: %w", i, err)
+		}
+
+		if isCredentialReference(config.Secret) {
+			path, secret := fmt.Sprintf("hooks[%d].secret", i), config.Secret
+			hook.Secret = ""
+			hook.SecretFunc = func(ctx context.Context) (string, error) {
+				value := secret
+				err := resolveCredentialValue(ctx, &app.credentials, path, &value)
+				return value, err
+			}
+		}
+		list = append(list, hook)
+	}
+
+	httpClient, err := newHTTPClient(app.config.Send)
+	if err != nil {
+		return nil, err
+	}
+	return hooks.NewRunner(list, httpClient), nil
+}
+
+// Create the hook described by the configuration and check that it is valid.
+func (config Hook) hook() (hooks.Hook, error) {
+	event, err := hooks.ParseEvent(config.Event)
+	if err != nil {
+		return hooks.Hook{}, err
+	}
+
+	hook := hooks.Hook{
+		Event:   event,
+		URL:     config.URL,
+		Secret:  config.Secret,
+		Retries: config.Retries,
+		Command: config.Command,
+		Args:    config.Args,
+		Timeout: time.Duration(config.Timeout) * time.Second,
+	}
+	if err := hook.Validate(); err != nil {
+		return hooks.Hook{}, err
+	}
+	return hook, nil
+}
+
+// Run the hooks for the event and report the hooks that failed.
+// The error returned for before_send means that the tweet must not be sent.
+func (app *Application) runHooks(ctx context.Context, out io.Writer, payload hooks.Payload) error {
+	errs := app.hookRunner.Run(ctx, payload)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var tweetId string
+	if payload.Tweet != nil {
+		tweetId = payload.Tweet.Id
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+		if payload.Event == hooks.EventBeforeSend {
+			fmt.Fprintf(out, "Not sending, %s\n", err)
+			app.logger.Warn("Tweet vetoed", logging.F("tweet_id", tweetId), logging.F("error", err))
+		} else {
+			fmt.Fprintf(out, "Hook failed: %s\n", err)
+			app.logger.Warn("Hook failed", logging.F("event", payload.Event), logging.F("tweet_id", tweetId),
+				logging.F("error", err))
+		}
+	}
+
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return fmt.Errorf("%w (and %d more): %s", errs[0], len(errs)-1, strings.Join(messages[1:], "; "))
+}
+
+// Return the tweet as described in the payload sent to the hooks.
+func hookTweet(tw tweet.Tweet) *hooks.Tweet {
+	return &hooks.Tweet{
+		Id:            tw.Id.String(),
+		Message:       tw.Message,
+		ScheduledTime: tw.ScheduledTime,
+		Account:       tw.Account,
+		Tags:          tw.Tags,
+		Attempt:       tw.Attempts + 1,
+	}
+}
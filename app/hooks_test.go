@@ -0,0 +1,211 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/hooks"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/andrejacobs/ajtweet-cli/internal/twittertest"
+)
+
+type fakeWebhook struct {
+	mu       sync.Mutex
+	payloads []hooks.Payload
+	veto     string // The message to veto before_send with.
+	server   *httptest.Server
+}
+
+func newFakeWebhook(t *testing.T) *fakeWebhook {
+	fake := &fakeWebhook{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload hooks.Payload
+		json.NewDecoder(r.Body).Decode(&payload)
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		fake.payloads = append(fake.payloads, payload)
+		if payload.Event == hooks.EventBeforeSend && strings.Contains(payload.Tweet.Message, fake.veto) {
+			http.Error(w, "vetoed: "+fake.veto, http.StatusConflict)
+		}
+	}))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fake *fakeWebhook) events() []string {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	var events []string
+	for _, payload := range fake.payloads {
+		events = append(events, string(payload.Event))
+	}
+	return events
+}
+
+func TestSendHooks(t *testing.T) {
+	mock, server := newMockTwitter(t)
+	webhook := newFakeWebhook(t)
+	webhook.veto = "not yet"
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+	for _, event := range []string{"before_send", "after_send", "send_failed", "queue_empty"} {
+		app.config.Hooks = append(app.config.Hooks, Hook{Event: event, URL: webhook.server.URL})
+	}
+
+	now := time.Now()
+	if err := app.Add("Hello", now.Add(-2*time.Minute).Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Add("Please do not send this not yet", now.Add(-time.Minute).Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := app.SendWith(context.Background(), io.Discard, SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The vetoed tweet remains scheduled without counting as an attempt
+	if len(app.tweets.Tweets) != 1 || app.tweets.Tweets[0].Status != tweet.StatusScheduled || app.tweets.Tweets[0].Attempts != 0 {
+		t.Fatalf("Expected the vetoed tweet to remain scheduled. Result: %v", app.tweets.Tweets)
+	}
+
+	if len(mock.Posts()) != 1 || report.Tweets[1].Outcome != OutcomeSkipped || !strings.Contains(report.Tweets[1].Error, "vetoed: not yet") {
+		t.Fatalf("Unexpected report: %+v", report.Tweets)
+	}
+
+	// A vetoed tweet was not sent, so not all the tweets that were due have been sent
+	if report.Totals.Skipped != 1 || report.Result != ResultPartialFailure || report.Result.ExitCode() != 4 {
+		t.Fatalf("Expected a partial failure. Result: %s, totals: %+v", report.Result, report.Totals)
+	}
+
+	expected := "before_send,after_send,before_send"
+	if events := strings.Join(webhook.events(), ","); events != expected {
+		t.Fatalf("Expected events: %s. Result: %s", expected, events)
+	}
+
+	sent := webhook.payloads[1]
+	if sent.Tweet.Message != "Hello" || sent.Service != ServiceTwitter || sent.RemoteId != mock.Posts()[0].Id || sent.URL == "" {
+		t.Fatalf("Unexpected after_send payload: %+v", sent)
+	}
+
+	// Once the last tweet has been sent the queue is empty
+	webhook.veto = "nothing to veto"
+	if report, err = app.SendWith(context.Background(), io.Discard, SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if report.Result != ResultAllSent {
+		t.Fatalf("Expected all the tweets to be sent. Result: %s", report.Result)
+	}
+
+	expected += ",before_send,after_send,queue_empty"
+	if events := strings.Join(webhook.events(), ","); events != expected {
+		t.Fatalf("Expected events: %s. Result: %s", expected, events)
+	}
+}
+
+func TestSendFailedHook(t *testing.T) {
+	webhook := newFakeWebhook(t)
+	server := httptest.NewServer(twittertest.NewServer(twittertest.Options{FailEvery: 1}))
+	defer server.Close()
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+	app.config.Hooks = []Hook{{Event: "send_failed", URL: webhook.server.URL}}
+
+	if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Send(context.Background(), io.Discard, false); err == nil {
+		t.Fatal("Expected sending to fail")
+	}
+
+	if len(webhook.payloads) != 1 || webhook.payloads[0].Event != hooks.EventSendFailed ||
+		!strings.Contains(webhook.payloads[0].Error, "503") || webhook.payloads[0].Tweet.Attempt != 1 {
+		t.Fatalf("Unexpected payloads: %+v", webhook.payloads)
+	}
+}
+
+func TestConfigureHooks(t *testing.T) {
+	app := Application{}
+	if runner, err := app.configureHooks(); runner != nil || err != nil {
+		t.Fatalf("Expected no hooks. Result: %v, %v", runner, err)
+	}
+
+	app.config.Hooks = []Hook{{Event: "after_send", URL: "https://example.com/hook"}, {Event: "sent", Command: "/bin/true"}}
+	if _, err := app.configureHooks(); !errors.Is(err, hooks.ErrUnknownEvent) || !strings.HasPrefix(err.Error(), "hooks[1]") {
+		t.Fatalf("Expected error: %q. Result: %q", hooks.ErrUnknownEvent, err)
+	}
+
+	app.config.Hooks[1] = Hook{Event: "before_send"}
+	if _, err := app.configureHooks(); !errors.Is(err, hooks.ErrInvalidHook) {
+		t.Fatalf("Expected error: %q. Result: %q", hooks.ErrInvalidHook, err)
+	}
+
+	// The secret is resolved like the other credentials, but only when the webhook is called
+	app.config.Hooks = []Hook{{Event: "after_send", URL: "https://example.com/hook", Secret: "env:AJTWEET_TEST_MISSING"}}
+	runner, err := app.configureHooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := runner.Run(context.Background(), hooks.Payload{Event: hooks.EventAfterSend})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrCredential) || !strings.Contains(errs[0].Error(), "hooks[0].secret") {
+		t.Fatalf("Expected error: %q. Result: %v", ErrCredential, errs)
+	}
+}
@@ -0,0 +1,147 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	client, err := newHTTPClient(Send{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get("http://api.example.com/2/tweets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if requested != "http://api.example.com/2/tweets" {
+		t.Fatalf("Expected the request to be sent through the proxy. Result: %q", requested)
+	}
+
+	if _, err := newHTTPClient(Send{Proxy: "proxy.example.com"}); err == nil {
+		t.Fatal("Expected an error for a proxy without a scheme")
+	}
+}
+
+func TestHTTPClientCABundle(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0) // The rejected handshake is expected
+	server.StartTLS()
+	defer server.Close()
+
+	client, err := newHTTPClient(Send{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(server.URL); err == nil {
+		t.Fatal("Expected the self-signed certificate to be rejected")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err = newHTTPClient(Send{CABundle: bundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	invalid := filepath.Join(t.TempDir(), "invalid.pem")
+	if err := os.WriteFile(invalid, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newHTTPClient(Send{CABundle: invalid}); err == nil {
+		t.Fatal("Expected an error for a bundle without certificates")
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	client, err := newHTTPClient(Send{Timeout: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Timeout != 30*time.Second {
+		t.Fatalf("Expected a timeout of 30 seconds. Result: %s", client.Timeout)
+	}
+}
+
+func TestSendCancelled(t *testing.T) {
+	_, server := newMockTwitter(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+
+	if err := app.Add("Hello Twitter", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := app.Send(ctx, io.Discard, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the send to be cancelled. Result: %v", err)
+	}
+
+	if tw := app.tweets.Tweets[0]; !tw.IsScheduled() || tw.Attempts != 0 {
+		t.Fatalf("Expected the tweet to still be scheduled. Result: %v", tw)
+	}
+}
@@ -0,0 +1,488 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Format of the files used to import and export tweets.
+type Format string
+
+const (
+	FormatCSV   Format = "csv"   // Comma separated values with a header row.
+	FormatJSONL Format = "jsonl" // JSON Lines, one JSON object per line.
+	FormatYAML  Format = "yaml"  // YAML sequence of mappings.
+	FormatICS   Format = "ics"   // iCalendar, can only be exported.
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown file format")
+	ErrInvalidImport = errors.New("invalid import")
+)
+
+// Determine the Format from the extension of the specified file path.
+func FormatFromPath(filePath string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		return FormatCSV, nil
+	case ".jsonl", ".ndjson":
+		return FormatJSONL, nil
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".ics":
+		return FormatICS, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, filePath)
+}
+
+// Parse the format from a string, e.g. "csv".
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(value)); format {
+	case FormatCSV, FormatJSONL, FormatYAML, FormatICS:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, value)
+}
+
+// ImportMapping specifies the column (CSV) or key (JSON Lines, YAML) names used for each of the tweet's fields.
+type ImportMapping struct {
+	Id            string
+	Message       string
+	ScheduledTime string
+	Account       string
+	Tags          string
+}
+
+// Create a new ImportMapping using the same names as the JSON encoding of a tweet.
+func NewImportMapping() ImportMapping {
+	return ImportMapping{
+		Id:            "id",
+		Message:       "message",
+		ScheduledTime: "scheduledTime",
+		Account:       "account",
+		Tags:          "tags",
+	}
+}
+
+// Override the default mapping with values in the form of field=column, e.g. message=text.
+func (mapping *ImportMapping) Parse(values []string) error {
+	for _, value := range values {
+		field, column, found := strings.Cut(value, "=")
+		column = strings.TrimSpace(column)
+		if !found || column == "" {
+			return fmt.Errorf("%w: mapping %q must be in the form of field=column", ErrInvalidImport, value)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "id":
+			mapping.Id = column
+		case "message":
+			mapping.Message = column
+		case "scheduledtime", "time":
+			mapping.ScheduledTime = column
+		case "account":
+			mapping.Account = column
+		case "tags":
+			mapping.Tags = column
+		default:
+			return fmt.Errorf("%w: unknown field %q in mapping %q", ErrInvalidImport, field, value)
+		}
+	}
+	return nil
+}
+
+// ImportRowError describes why a single row (record) could not be imported.
+type ImportRowError struct {
+	Line int   // The line number in the file at which the row starts.
+	Err  error // The reason the row is invalid.
+}
+
+func (e *ImportRowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+func (e *ImportRowError) Unwrap() error {
+	return e.Err
+}
+
+// ImportError is returned when one or more rows could not be imported.
+type ImportError struct {
+	Rows []*ImportRowError
+}
+
+func (e *ImportError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid row(s)", len(e.Rows))
+	for _, row := range e.Rows {
+		fmt.Fprintf(&b, "\n  %s", row)
+	}
+	return b.String()
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrInvalidImport
+}
+
+// Import the tweets from the specified io.Reader and write the tweets that were added to the specified io.Writer.
+// The whole input is validated first and either all of the tweets are added or none of them are.
+// The imported tweets are assigned new identifiers. Rows with the identifier of a tweet that is already in the
+// queue (e.g. when importing an export of the same queue) are rejected so that the tweets are not duplicated.
+func (app *Application) Import(out io.Writer, in io.Reader, format Format, mapping ImportMapping) error {
+	records, rowErrs, err := readRecords(in, format)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tweets := make([]tweet.Tweet, 0, len(records))
+	importErr := ImportError{Rows: rowErrs}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, record := range records {
+		if err := app.checkImportId(record, mapping, seen); err != nil {
+			importErr.Rows = append(importErr.Rows, &ImportRowError{Line: record.line, Err: err})
+			continue
+		}
+
+		tw, err := record.tweet(mapping, now)
+		if err != nil {
+			importErr.Rows = append(importErr.Rows, &ImportRowError{Line: record.line, Err: err})
+			continue
+		}
+		if err := app.resolveImportAccounts(&tw); err != nil {
+			importErr.Rows = append(importErr.Rows, &ImportRowError{Line: record.line, Err: err})
+			continue
+		}
+		if err := app.validateTargets(tw); err != nil {
+			importErr.Rows = append(importErr.Rows, &ImportRowError{Line: record.line, Err: err})
+			continue
+		}
+		if app.config.Approval.Required {
+			tw.RequireApproval()
+		}
+		tweets = append(tweets, tw)
+	}
+
+	if len(importErr.Rows) > 0 {
+		// The rows that could not be parsed are reported along with the invalid rows, in the order of the file
+		sort.SliceStable(importErr.Rows, func(i, j int) bool {
+			return importErr.Rows[i].Line < importErr.Rows[j].Line
+		})
+		return &importErr
+	}
+
+	if err := app.tweets.AddAll(tweets); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(out, "Importing %d tweet(s)\n\n", len(tweets)); err != nil {
+		return err
+	}
+
+	return writeTweets(out, tweets, app.idFormatter(false))
+}
+
+// Resolve the account of the imported tweet, or the accounts it will be cross-posted to, in the same way as when
+// adding a tweet, e.g. product to mastodon:product, so that unknown accounts are reported when importing instead
+// of when sending and the same account can not be sent to more than once.
+func (app *Application) resolveImportAccounts(tw *tweet.Tweet) error {
+	if len(tw.Destinations) == 0 {
+		account, err := app.account(tw.Account)
+		if err != nil {
+			return err
+		}
+		tw.Account = account.Ref()
+		return nil
+	}
+
+	refs := make([]string, len(tw.Destinations))
+	for i, destination := range tw.Destinations {
+		refs[i] = destination.Account
+	}
+
+	destinations, err := app.resolveDestinations(tw.Message, refs)
+	if err != nil {
+		return err
+	}
+	tw.Destinations = tweet.NewDestinations(destinations...)
+	return nil
+}
+
+// Check that the identifier of the record (if any) does not belong to a tweet that is already in the queue or
+// to an earlier record.
+func (app *Application) checkImportId(record importRecord, mapping ImportMapping, seen map[uuid.UUID]bool) error {
+	value, err := recordString(record.fields[mapping.Id])
+	if err != nil {
+		return fmt.Errorf("%s: %w", mapping.Id, err)
+	}
+	if value = strings.TrimSpace(value); value == "" {
+		return nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", mapping.Id, err)
+	}
+
+	if found, _ := app.tweets.Find(id); found {
+		return fmt.Errorf("%s: the tweet %s is already in the queue", mapping.Id, id)
+	}
+	if seen[id] {
+		return fmt.Errorf("%s: the tweet %s is imported more than once", mapping.Id, id)
+	}
+	seen[id] = true
+	return nil
+}
+
+// A single record read from the input and the line number at which it starts.
+type importRecord struct {
+	line   int
+	fields map[string]interface{}
+}
+
+// Read the records from the input. The rows that can not be parsed are returned as row errors so that all of
+// them can be reported, while errors reading the input as a whole are returned as the error.
+func readRecords(in io.Reader, format Format) ([]importRecord, []*ImportRowError, error) {
+	switch format {
+	case FormatCSV:
+		return readCSVRecords(in)
+	case FormatJSONL:
+		return readJSONLRecords(in)
+	case FormatYAML:
+		return readYAMLRecords(in)
+	}
+	return nil, nil, fmt.Errorf("%w: %q can not be imported", ErrUnknownFormat, format)
+}
+
+func readCSVRecords(in io.Reader) ([]importRecord, []*ImportRowError, error) {
+	reader := csv.NewReader(in)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	var records []importRecord
+	var rowErrs []*ImportRowError
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// The reader continues with the next row after a malformed row
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			rowErrs = append(rowErrs, &ImportRowError{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		fields := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(row) {
+				fields[strings.TrimSpace(column)] = row[i]
+			}
+		}
+		records = append(records, importRecord{line: line, fields: fields})
+	}
+
+	return records, rowErrs, nil
+}
+
+func readJSONLRecords(in io.Reader) ([]importRecord, []*ImportRowError, error) {
+	var records []importRecord
+	var rowErrs []*ImportRowError
+
+	scanner := bufio.NewScanner(in)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			rowErrs = append(rowErrs, &ImportRowError{Line: line, Err: err})
+			continue
+		}
+		records = append(records, importRecord{line: line, fields: fields})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return records, rowErrs, nil
+}
+
+func readYAMLRecords(in io.Reader) ([]importRecord, []*ImportRowError, error) {
+	var document yaml.Node
+	if err := yaml.NewDecoder(in).Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	if len(document.Content) == 0 {
+		return nil, nil, nil
+	}
+
+	root := document.Content[0]
+	if root.Kind != yaml.SequenceNode {
+		return nil, nil, &ImportRowError{Line: root.Line, Err: errors.New("expected a sequence of tweets")}
+	}
+
+	records := make([]importRecord, 0, len(root.Content))
+	var rowErrs []*ImportRowError
+	for _, node := range root.Content {
+		var fields map[string]interface{}
+		if err := node.Decode(&fields); err != nil {
+			rowErrs = append(rowErrs, &ImportRowError{Line: node.Line, Err: err})
+			continue
+		}
+		records = append(records, importRecord{line: node.Line, fields: fields})
+	}
+
+	return records, rowErrs, nil
+}
+
+// Create a new tweet from the record using the specified mapping.
+// now Is used as the scheduled time when the record does not specify one.
+func (record importRecord) tweet(mapping ImportMapping, now time.Time) (tweet.Tweet, error) {
+	message, err := recordString(record.fields[mapping.Message])
+	if err != nil {
+		return tweet.Tweet{}, fmt.Errorf("%s: %w", mapping.Message, err)
+	}
+	if strings.TrimSpace(message) == "" {
+		return tweet.Tweet{}, fmt.Errorf("%s: a message is required", mapping.Message)
+	}
+
+	scheduledTime := now
+	switch value := record.fields[mapping.ScheduledTime].(type) {
+	case time.Time:
+		scheduledTime = value
+	default:
+		timeString, err := recordString(value)
+		if err != nil {
+			return tweet.Tweet{}, fmt.Errorf("%s: %w", mapping.ScheduledTime, err)
+		}
+		if timeString != "" {
+			if scheduledTime, err = parseTime(timeString); err != nil {
+				return tweet.Tweet{}, fmt.Errorf("%s: %w", mapping.ScheduledTime, err)
+			}
+		}
+	}
+
+	account, err := recordString(record.fields[mapping.Account])
+	if err != nil {
+		return tweet.Tweet{}, fmt.Errorf("%s: %w", mapping.Account, err)
+	}
+
+	tags, err := recordStrings(record.fields[mapping.Tags])
+	if err != nil {
+		return tweet.Tweet{}, fmt.Errorf("%s: %w", mapping.Tags, err)
+	}
+
+	tw := tweet.New(message, scheduledTime)
+	// Multiple accounts separated by commas are the destinations to cross-post to
+	var accounts []string
+	for _, value := range strings.Split(account, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			accounts = append(accounts, value)
+		}
+	}
+	if len(accounts) > 1 {
+		tw.Destinations = tweet.NewDestinations(accounts...)
+	} else {
+		tw.Account = strings.TrimSpace(account)
+	}
+	tw.Tags = tags
+	return tw, nil
+}
+
+func recordString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case int, int64, float64, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("expected a string value, got %T", value)
+}
+
+// Tags can either be specified as a list or as a single string separated by commas or semicolons.
+func recordStrings(value interface{}) ([]string, error) {
+	var values []string
+
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			s, err := recordString(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+	default:
+		s, err := recordString(v)
+		if err != nil {
+			return nil, err
+		}
+		values = strings.FieldsFunc(s, func(r rune) bool {
+			return r == ',' || r == ';'
+		})
+	}
+
+	var result []string
+	for _, s := range values {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,327 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportCSV(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+
+	input := `Text,When,Account,Labels
+"Hello, world",2032-05-16T19:42:00Z,product,"launch, campaign-x"
+Send now,,,
+`
+	mapping := NewImportMapping()
+	if err := mapping.Parse([]string{"message=Text", "scheduledTime=When", "account=Account", "tags=Labels"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Import(io.Discard, strings.NewReader(input), FormatCSV, mapping); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(app.tweets.Tweets); count != 2 {
+		t.Fatalf("Expected 2 tweets, Result: %d", count)
+	}
+
+	tw := app.tweets.Tweets[0]
+	expectedTime, _ := parseTime("2032-05-16T19:42:00Z")
+	if tw.Message != "Hello, world" || tw.ScheduledTime != expectedTime || tw.Account != "mastodon:product" {
+		t.Fatalf("Tweet does not meet expectations. Result: %s", tw)
+	}
+
+	if len(tw.Tags) != 2 || tw.Tags[0] != "launch" || tw.Tags[1] != "campaign-x" {
+		t.Fatalf("Expected tags [launch campaign-x]. Result: %v", tw.Tags)
+	}
+
+	if !app.tweets.Tweets[1].SendNow() {
+		t.Fatal("Expected the tweet without a scheduled time to be sent now")
+	}
+}
+
+func TestImportJSONL(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+
+	input := `{"message":"Tweet 1","scheduledTime":"2032-05-16T19:42:00Z","tags":["a","b"]}
+
+{"message":"Tweet 2","account":"product"}
+`
+	if err := app.Import(io.Discard, strings.NewReader(input), FormatJSONL, NewImportMapping()); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(app.tweets.Tweets); count != 2 {
+		t.Fatalf("Expected 2 tweets, Result: %d", count)
+	}
+
+	if tags := app.tweets.Tweets[0].Tags; len(tags) != 2 {
+		t.Fatalf("Expected 2 tags. Result: %v", tags)
+	}
+
+	if account := app.tweets.Tweets[1].Account; account != "mastodon:product" {
+		t.Fatalf("Expected account %q. Result: %q", "mastodon:product", account)
+	}
+}
+
+func TestImportYAML(t *testing.T) {
+	app := Application{}
+
+	input := `- message: Tweet 1
+  scheduledTime: 2032-05-16T19:42:00Z
+  tags: [a, b]
+- message: Tweet 2
+  tags: c; d
+`
+	if err := app.Import(io.Discard, strings.NewReader(input), FormatYAML, NewImportMapping()); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(app.tweets.Tweets); count != 2 {
+		t.Fatalf("Expected 2 tweets, Result: %d", count)
+	}
+
+	expectedTime, _ := parseTime("2032-05-16T19:42:00Z")
+	if !app.tweets.Tweets[0].ScheduledTime.Equal(expectedTime) {
+		t.Fatalf("Expected time %s. Result: %s", expectedTime, app.tweets.Tweets[0].ScheduledTime)
+	}
+
+	if tags := app.tweets.Tweets[1].Tags; len(tags) != 2 || tags[1] != "d" {
+		t.Fatalf("Expected tags [c d]. Result: %v", tags)
+	}
+}
+
+func TestImportMalformedYAML(t *testing.T) {
+	inputs := []string{
+		"- message: [unclosed\n",
+		"- message: Tweet 1\n  tags: {a\n",
+		"message: Not a sequence\n",
+		// Crashed the parser before gopkg.in/yaml.v3 v3.0.1 (CVE-2022-28948)
+		"0: [:!00 \xef",
+	}
+
+	for _, input := range inputs {
+		app := Application{}
+		if err := app.Import(io.Discard, strings.NewReader(input), FormatYAML, NewImportMapping()); err == nil {
+			t.Fatalf("%q: Expected the malformed YAML to be rejected", input)
+		}
+		if len(app.tweets.Tweets) != 0 {
+			t.Fatalf("%q: Expected no tweets to be imported. Result: %v", input, app.tweets.Tweets)
+		}
+	}
+}
+
+func TestImportIsAllOrNothing(t *testing.T) {
+	app := Application{}
+	app.Add("Existing", time.Now().Format(time.RFC3339))
+
+	input := `- message: Valid
+- message: ""
+- message: Invalid time
+  scheduledTime: tomorrow
+`
+	err := app.Import(io.Discard, strings.NewReader(input), FormatYAML, NewImportMapping())
+	if !errors.Is(err, ErrInvalidImport) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidImport, err)
+	}
+
+	var importErr *ImportError
+	if !errors.As(err, &importErr) {
+		t.Fatalf("Expected an ImportError. Result: %T", err)
+	}
+
+	if count := len(importErr.Rows); count != 2 {
+		t.Fatalf("Expected 2 invalid rows. Result: %d", count)
+	}
+
+	if importErr.Rows[0].Line != 2 || importErr.Rows[1].Line != 3 {
+		t.Fatalf("Expected errors on lines 2 and 3. Result: %s", err)
+	}
+
+	if count := len(app.tweets.Tweets); count != 1 {
+		t.Fatalf("Expected no tweets to be imported. Result: %d", count)
+	}
+}
+
+func TestImportReportsEveryMalformedRow(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+		lines  []int
+	}{
+		{FormatJSONL, `{"message":"Valid"}
+{"message":
+{"message":"Valid"}
+not json
+`, []int{2, 4}},
+		{FormatCSV, `message,account
+Valid,
+"Bare "quote",
+Valid,
+Another "bare" quote,
+`, []int{3, 5}},
+		{FormatYAML, `- message: Valid
+- [not, a, mapping]
+- message: Valid
+- scalar
+`, []int{2, 4}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			app := Application{}
+			err := app.Import(io.Discard, strings.NewReader(test.input), test.format, NewImportMapping())
+
+			var importErr *ImportError
+			if !errors.As(err, &importErr) {
+				t.Fatalf("Expected an ImportError. Result: %v", err)
+			}
+
+			var lines []int
+			for _, row := range importErr.Rows {
+				lines = append(lines, row.Line)
+			}
+			if len(lines) != len(test.lines) || lines[0] != test.lines[0] || lines[1] != test.lines[1] {
+				t.Fatalf("Expected errors on lines %v. Result: %s", test.lines, err)
+			}
+
+			if count := len(app.tweets.Tweets); count != 0 {
+				t.Fatalf("Expected no tweets to be imported. Result: %d", count)
+			}
+		})
+	}
+}
+
+func TestImportDuplicateIds(t *testing.T) {
+	app := Application{}
+
+	input := `{"id":"6f1c1d2e-2b8a-4bd4-8a53-0d5e4c1f7a10","message":"Tweet 1"}
+{"id":"6f1c1d2e-2b8a-4bd4-8a53-0d5e4c1f7a10","message":"Tweet 1 again"}
+{"id":"not-an-id","message":"Tweet 2"}
+`
+	err := app.Import(io.Discard, strings.NewReader(input), FormatJSONL, NewImportMapping())
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Rows) != 2 || importErr.Rows[0].Line != 2 || importErr.Rows[1].Line != 3 {
+		t.Fatalf("Expected errors on lines 2 and 3. Result: %v", err)
+	}
+}
+
+func TestImportDestinations(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Twitter = map[string]Authentication{"product": {}}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}, "support": {}}
+
+	input := `message,account
+Duplicate,"mastodon:product,mastodon:product"
+Same account,"support,mastodon:support"
+Unknown,"mastodon:product,mastodon:sales"
+Unknown account,sales
+Ambiguous account,product
+`
+	err := app.Import(io.Discard, strings.NewReader(input), FormatCSV, NewImportMapping())
+	var importErr *ImportError
+	if !errors.As(err, &importErr) || len(importErr.Rows) != 5 {
+		t.Fatalf("Expected 5 invalid rows. Result: %v", err)
+	}
+	if !errors.Is(importErr.Rows[0], ErrInvalidDestinations) || !errors.Is(importErr.Rows[1], ErrInvalidDestinations) ||
+		!errors.Is(importErr.Rows[2], ErrUnknownAccount) || !errors.Is(importErr.Rows[3], ErrUnknownAccount) ||
+		!errors.Is(importErr.Rows[4], ErrAmbiguousAccount) {
+		t.Fatalf("Unexpected errors: %v", err)
+	}
+
+	input = `message,account
+Cross-post,"support,twitter:product"
+Single account,Support
+Default account,
+`
+	if err := app.Import(io.Discard, strings.NewReader(input), FormatCSV, NewImportMapping()); err != nil {
+		t.Fatal(err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if len(tw.Destinations) != 2 || tw.Destinations[0].Account != "mastodon:support" || tw.Destinations[1].Account != "twitter:product" {
+		t.Fatalf("Expected the destinations to be resolved. Result: %v", tw.Destinations)
+	}
+
+	// The account is stored in the same way as when adding a tweet
+	if account := app.tweets.Tweets[1].Account; account != "mastodon:support" {
+		t.Fatalf("Expected account %q. Result: %q", "mastodon:support", account)
+	}
+	if account := app.tweets.Tweets[2].Account; account != "" {
+		t.Fatalf("Expected the default account. Result: %q", account)
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected Format
+	}{
+		{"campaign.csv", FormatCSV},
+		{"campaign.jsonl", FormatJSONL},
+		{"campaign.ndjson", FormatJSONL},
+		{"campaign.YAML", FormatYAML},
+		{"campaign.yml", FormatYAML},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			format, err := FormatFromPath(tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tc.expected {
+				t.Fatalf("Expected: %q, Result: %q", tc.expected, format)
+			}
+		})
+	}
+
+	if _, err := FormatFromPath("campaign.txt"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownFormat, err)
+	}
+}
+
+func TestImportMappingParse(t *testing.T) {
+	mapping := NewImportMapping()
+
+	if err := mapping.Parse([]string{"message=Text"}); err != nil {
+		t.Fatal(err)
+	}
+	if mapping.Message != "Text" || mapping.ScheduledTime != "scheduledTime" {
+		t.Fatalf("Mapping does not meet expectations. Result: %+v", mapping)
+	}
+
+	if err := mapping.Parse([]string{"unknown=Text"}); err == nil {
+		t.Fatal("Expected an error for an unknown field")
+	}
+
+	if err := mapping.Parse([]string{"message"}); err == nil {
+		t.Fatal("Expected an error for an invalid mapping")
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownOutput = errors.New("unknown output format")
+)
+
+type outputKind int
+
+const (
+	outputDefault outputKind = iota
+	outputJSON
+	outputTable
+	outputWide
+	outputTemplate
+)
+
+// Output specifies how a list of tweets will be displayed.
+// The zero value is the default human readable output used by the list command.
+type Output struct {
+	kind     outputKind
+	template *template.Template
+}
+
+// Parse the output format from a string.
+// Supported values are: default, json, table, wide and template=TEMPLATE where TEMPLATE is a Go text/template,
+// e.g. template='{{.Id}} {{.Message}}'.
+func ParseOutput(value string) (Output, error) {
+	name, text, hasTemplate := strings.Cut(value, "=")
+
+	switch strings.ToLower(name) {
+	case "", "default":
+		return Output{kind: outputDefault}, nil
+	case "json":
+		return Output{kind: outputJSON}, nil
+	case "table":
+		return Output{kind: outputTable}, nil
+	case "wide":
+		return Output{kind: outputWide}, nil
+	case "template":
+		if !hasTemplate || text == "" {
+			return Output{}, fmt.Errorf("%w: expected template=TEMPLATE", ErrUnknownOutput)
+		}
+
+		tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return Output{}, err
+		}
+		return Output{kind: outputTemplate, template: tmpl}, nil
+	}
+
+	return Output{}, fmt.Errorf("%w: %q", ErrUnknownOutput, value)
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"short": uuid.UUID.String,
+	"rfc3339": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+}
+
+// ListOptions specifies which tweets will be listed and how they will be displayed.
+type ListOptions struct {
+	Filter  Filter
+	Output  Output
+	FullIds bool // Display the full identifiers instead of the shortest unique prefixes.
+}
+
+// Write the list of scheduled tweets matching the filter to the specified io.Writer.
+func (app *Application) ListWith(out io.Writer, options ListOptions) error {
+	tweets, err := app.Query(options.Filter)
+	if err != nil {
+		return err
+	}
+
+	formatId := app.idFormatter(options.FullIds)
+
+	switch options.Output.kind {
+	case outputJSON:
+		return writeTweetsJSON(out, tweets)
+	case outputTable:
+		return writeTweetsTable(out, tweets, false, formatId)
+	case outputWide:
+		return writeTweetsTable(out, tweets, true, formatId)
+	case outputTemplate:
+		return writeTweetsTemplate(out, tweets, options.Output.template, formatId)
+	}
+	return writeTweets(out, tweets, formatId)
+}
+
+// Return the string used to display the identifier of a tweet.
+type idFormatter func(id uuid.UUID) string
+
+// Return an idFormatter that displays either the full identifiers or the shortest unique prefixes.
+// The prefixes are unique across all the tweets and not just the tweets being displayed.
+func (app *Application) idFormatter(full bool) idFormatter {
+	if full {
+		return uuid.UUID.String
+	}
+
+	shortIds := app.tweets.ShortIds(tweet.ShortIdLength)
+	return func(id uuid.UUID) string {
+		if short, found := shortIds[id]; found {
+			return short
+		}
+		return id.String()
+	}
+}
+
+const tableMessageLength = 50
+
+// Write the tweets as aligned columns. The wide format includes more columns and does not truncate the message.
+func writeTweetsTable(out io.Writer, tweets []tweet.Tweet, wide bool, formatId idFormatter) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	if wide {
+		fmt.Fprintln(w, "ID\tTIME\tSTATUS\tDUE\tATTEMPTS\tACCOUNT\tTAGS\tMESSAGE")
+	} else {
+		fmt.Fprintln(w, "ID\tTIME\tSTATUS\tACCOUNT\tMESSAGE")
+	}
+
+	for _, tw := range tweets {
+		message := strings.Join(strings.Fields(tw.Message), " ")
+		scheduledTime := tw.ScheduledTime.Format(time.RFC3339)
+
+		if wide {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%d\t%s\t%s\t%s\n", formatId(tw.Id), scheduledTime, tw.Status,
+				tw.IsScheduled() && tw.SendNow(), tw.Attempts,
+				emptyAsDash(strings.Join(tw.Accounts(), ",")), emptyAsDash(strings.Join(tw.Tags, ",")), message)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", formatId(tw.Id), scheduledTime, tw.Status,
+				emptyAsDash(strings.Join(tw.Accounts(), ",")), truncate(message, tableMessageLength))
+		}
+	}
+
+	return w.Flush()
+}
+
+// The template function "short" can be used to display the short identifier, e.g. {{short .Id}}
+func writeTweetsTemplate(out io.Writer, tweets []tweet.Tweet, tmpl *template.Template, formatId idFormatter) error {
+	tmpl = tmpl.Funcs(template.FuncMap{"short": formatId})
+
+	for _, tw := range tweets {
+		if err := tmpl.Execute(out, tw); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Truncate the text to the maximum number of characters (runes) and add an ellipsis when truncated.
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+func emptyAsDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
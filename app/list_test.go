@@ -0,0 +1,184 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestListWithFilter(t *testing.T) {
+	app := Application{}
+	app.Add("Hello world", "2032-05-16T19:42:00Z")
+	app.Add("Launch day", "2032-06-16T19:42:00Z")
+	app.Add("Goodbye world", "2032-07-16T19:42:00Z")
+	app.tweets.Tweets[1].Tags = []string{"launch"}
+
+	testCases := []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{
+		{"All", Filter{}, []string{"Hello world", "Launch day", "Goodbye world"}},
+		{"Before date", Filter{Before: "2032-06-01"}, []string{"Hello world"}},
+		{"After time", Filter{After: "2032-06-16T19:42:00Z"}, []string{"Goodbye world"}},
+		{"Contains", Filter{Contains: "WORLD"}, []string{"Hello world", "Goodbye world"}},
+		{"Regex", Filter{Contains: "/^Good/"}, []string{"Goodbye world"}},
+		{"Tag", Filter{Tags: []string{"launch"}}, []string{"Launch day"}},
+		{"Limit", Filter{Contains: "world", Limit: 1}, []string{"Hello world"}},
+		{"Due", Filter{Due: true}, []string{}},
+	}
+
+	output, err := ParseOutput("template={{.Message}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := app.ListWith(&buffer, ListOptions{Filter: tc.filter, Output: output}); err != nil {
+				t.Fatal(err)
+			}
+
+			expected := ""
+			for _, message := range tc.expected {
+				expected += message + "\n"
+			}
+
+			if buffer.String() != expected {
+				t.Fatalf("Expected:\n%q\n\nResult:\n%q\n", expected, buffer.String())
+			}
+		})
+	}
+}
+
+func TestListWithInvalidFilter(t *testing.T) {
+	app := Application{}
+
+	if err := app.ListWith(&bytes.Buffer{}, ListOptions{Filter: Filter{Before: "tomorrow"}}); err == nil {
+		t.Fatal("Expected an error for an invalid time")
+	}
+
+	if err := app.ListWith(&bytes.Buffer{}, ListOptions{Filter: Filter{Contains: "/[/"}}); err == nil {
+		t.Fatal("Expected an error for an invalid regular expression")
+	}
+}
+
+func TestListTable(t *testing.T) {
+	app := Application{}
+	app.Add("A short message", "2032-05-16T19:42:00Z")
+	app.Add(strings.Repeat("A very long message ", 5), "2032-06-16T19:42:00Z")
+	app.tweets.Tweets[0].Account = "product"
+	app.tweets.Tweets[0].Id = uuid.MustParse("28cf75a1-e7b3-4401-a878-4362bdc4befe")
+	app.tweets.Tweets[1].Id = uuid.MustParse("a2fdb340-0b61-4a89-b52e-82deae2e3aa8")
+
+	output, err := ParseOutput("table")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := app.ListWith(&buffer, ListOptions{Output: output}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `ID       TIME                  STATUS     ACCOUNT  MESSAGE
+28cf75a  2032-05-16T19:42:00Z  scheduled  product  A short message
+a2fdb34  2032-06-16T19:42:00Z  scheduled  -        A very long message A very long message A very lo…
+`
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%s\n\nResult:\n%s\n", expected, buffer.String())
+	}
+
+	// Full identifiers
+	buffer.Reset()
+	if err := app.ListWith(&buffer, ListOptions{Output: output, FullIds: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `ID                                    TIME                  STATUS     ACCOUNT  MESSAGE
+28cf75a1-e7b3-4401-a878-4362bdc4befe  2032-05-16T19:42:00Z  scheduled  product  A short message
+a2fdb340-0b61-4a89-b52e-82deae2e3aa8  2032-06-16T19:42:00Z  scheduled  -        A very long message A very long message A very lo…
+`
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%s\n\nResult:\n%s\n", expected, buffer.String())
+	}
+}
+
+func TestListTemplateShortIds(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet 1", "2032-05-16T19:42:00Z")
+	app.tweets.Tweets[0].Id = uuid.MustParse("28cf75a1-e7b3-4401-a878-4362bdc4befe")
+
+	output, err := ParseOutput("template={{short .Id}} {{.Id}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := app.ListWith(&buffer, ListOptions{Output: output}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "28cf75a 28cf75a1-e7b3-4401-a878-4362bdc4befe\n"
+	if buffer.String() != expected {
+		t.Fatalf("Expected: %q. Result: %q", expected, buffer.String())
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	for _, value := range []string{"", "default", "json", "table", "WIDE", "template={{.Id}}"} {
+		if _, err := ParseOutput(value); err != nil {
+			t.Fatalf("Expected %q to be valid. Error: %s", value, err)
+		}
+	}
+
+	if _, err := ParseOutput("xml"); !errors.Is(err, ErrUnknownOutput) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownOutput, err)
+	}
+
+	if _, err := ParseOutput("template="); !errors.Is(err, ErrUnknownOutput) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownOutput, err)
+	}
+
+	if _, err := ParseOutput("template={{.Id"); err == nil {
+		t.Fatal("Expected an error for an invalid template")
+	}
+}
+
+func TestParseFilterTime(t *testing.T) {
+	expected := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	if result, err := parseFilterTime("2024-01-01"); err != nil || !result.Equal(expected) {
+		t.Fatalf("Expected: %s. Result: %s, %v", expected, result, err)
+	}
+
+	expected = time.Date(2024, 1, 1, 10, 42, 0, 0, time.UTC)
+	if result, err := parseFilterTime("2024-01-01T10:42:00Z"); err != nil || !result.Equal(expected) {
+		t.Fatalf("Expected: %s. Result: %s, %v", expected, result, err)
+	}
+}
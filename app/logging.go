@@ -0,0 +1,61 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"io"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
+)
+
+// Create the Logger described by the configuration.
+// The returned io.Closer must be closed once the Logger is no longer used.
+func NewLogger(config Log) (*logging.Logger, io.Closer, error) {
+	level := logging.LevelOff
+	if config.File != "" {
+		level = logging.LevelInfo
+	}
+
+	if config.Level != "" {
+		var err error
+		if level, err = logging.ParseLevel(config.Level); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	format, err := logging.ParseFormat(config.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config.File == "" {
+		return logging.New(os.Stderr, level, format), io.NopCloser(nil), nil
+	}
+
+	file, err := logging.OpenRotatingFile(config.File, int64(config.MaxSize)*1024*1024, config.MaxBackups)
+	if err != nil {
+		return nil, nil, err
+	}
+	return logging.New(file, level, format), file, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
+)
+
+func TestNewLogger(t *testing.T) {
+	logger, closer, err := NewLogger(Log{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	closer.Close()
+	if logger.Enabled(logging.LevelError) {
+		t.Fatal("Expected logging to be off by default")
+	}
+
+	path := filepath.Join(t.TempDir(), "ajtweet.log")
+	logger, closer, err = NewLogger(Log{File: path, Format: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !logger.Enabled(logging.LevelInfo) || logger.Enabled(logging.LevelDebug) {
+		t.Fatal("Expected the info level when logging to a file")
+	}
+
+	logger.Info("Hello")
+	closer.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"msg":"Hello"`) {
+		t.Fatalf("Expected a JSON entry. Result: %q", data)
+	}
+
+	if _, _, err := NewLogger(Log{Level: "verbose"}); err == nil {
+		t.Fatal("Expected an error for an unknown level")
+	}
+}
+
+func TestSendLogs(t *testing.T) {
+	mock, server := newMockTwitter(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+
+	var log bytes.Buffer
+	app.SetLogger(logging.New(&log, logging.LevelInfo, logging.FormatJSON))
+
+	if err := app.Add("Hello Twitter", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	id := app.tweets.Tweets[0].Id.String()
+
+	if err := app.Send(context.Background(), io.Discard, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Expected a JSON entry. Result: %q", line)
+		}
+		if entry["msg"] == "Tweet sent" {
+			sent = entry
+		}
+	}
+
+	if sent == nil {
+		t.Fatalf("Expected the sent tweet to be logged. Result: %s", log.String())
+	}
+
+	if sent["tweet_id"] != id || sent["twitter_id"] != mock.Posts()[0].Id || sent["attempt"] != 1.0 || sent["duration"] == nil {
+		t.Fatalf("Unexpected entry: %v", sent)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sender implementation for Mastodon using the REST API.
+// https://docs.joinmastodon.org/methods/statuses/
+type mastodonSender struct {
+	config     Mastodon
+	httpClient *http.Client
+	baseURL    string
+	rateLimits *rateLimitRecorder
+}
+
+func newMastodonSender(config Mastodon) *mastodonSender {
+	return &mastodonSender{config: config}
+}
+
+func (s *mastodonSender) RateLimit() *RateLimit {
+	return s.rateLimits.RateLimit()
+}
+
+// MastodonError is returned when the Mastodon API responds with an error.
+type MastodonError struct {
+	StatusCode int    // The HTTP status code.
+	Message    string // The error message returned by the API.
+}
+
+func (e *MastodonError) Error() string {
+	return fmt.Sprintf("Mastodon API error (%d): %s", e.StatusCode, e.Message)
+}
+
+func (s *mastodonSender) Configure() error {
+	if s.config.Instance == "" {
+		return fmt.Errorf("%w: Mastodon instance", ErrMissingAuth)
+	}
+
+	if s.config.AccessToken == "" {
+		return fmt.Errorf("%w: Mastodon access token", ErrMissingAuth)
+	}
+
+	instance, err := url.Parse(s.config.Instance)
+	if err != nil || (instance.Scheme != "https" && instance.Scheme != "http") || instance.Host == "" {
+		return fmt.Errorf("invalid Mastodon instance URL: %q", s.config.Instance)
+	}
+
+	if s.httpClient == nil {
+		s.httpClient = http.DefaultClient
+	}
+
+	s.baseURL = strings.TrimSuffix(instance.String(), "/")
+	return nil
+}
+
+// The parameters of a new status.
+type mastodonStatus struct {
+	Status      string   `json:"status"`
+	MediaIds    []string `json:"media_ids,omitempty"`
+	InReplyToId string   `json:"in_reply_to_id,omitempty"`
+}
+
+func (s *mastodonSender) Post(ctx context.Context, text string, mediaIds []string) (PostResult, error) {
+	return s.postStatus(ctx, mastodonStatus{Status: text, MediaIds: mediaIds})
+}
+
+func (s *mastodonSender) PostReply(ctx context.Context, inReplyToId string, text string, mediaIds []string) (PostResult, error) {
+	return s.postStatus(ctx, mastodonStatus{Status: text, MediaIds: mediaIds, InReplyToId: inReplyToId})
+}
+
+func (s *mastodonSender) postStatus(ctx context.Context, status mastodonStatus) (PostResult, error) {
+	body, err := json.Marshal(status)
+	if err != nil {
+		return PostResult{}, err
+	}
+
+	var result struct {
+		Id  string `json:"id"`
+		URL string `json:"url"`
+	}
+	req, err := s.newRequest(ctx, "/api/v1/statuses", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return PostResult{}, err
+	}
+	// Mastodon returns the status created by an earlier request with the same key instead of posting it again
+	if key := idempotencyKey(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if err := s.do(req, &result); err != nil {
+		return PostResult{}, err
+	}
+
+	return PostResult{Id: result.Id, URL: result.URL}, nil
+}
+
+func (s *mastodonSender) UploadMedia(ctx context.Context, filePath string) (string, error) {
+	body, contentType, err := multipartFile("file", filePath)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Id string `json:"id"`
+	}
+	req, err := s.newRequest(ctx, "/api/v2/media", contentType, body)
+	if err != nil {
+		return "", err
+	}
+	if err := s.do(req, &result); err != nil {
+		return "", err
+	}
+
+	return result.Id, nil
+}
+
+// Create a request that POSTs the body to the API path.
+func (s *mastodonSender) newRequest(ctx context.Context, path string, contentType string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.config.AccessToken)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	return req, nil
+}
+
+// Send the request and decode the JSON response into result.
+func (s *mastodonSender) do(req *http.Request, result interface{}) error {
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return mastodonErrorFrom(res)
+	}
+
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+func mastodonErrorFrom(res *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	message := strings.TrimSpace(string(data))
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		message = body.Error
+	}
+	if message == "" {
+		message = res.Status
+	}
+
+	return &MastodonError{StatusCode: res.StatusCode, Message: message}
+}
@@ -0,0 +1,241 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+)
+
+// fakeMastodon is a minimal stand-in for the Mastodon API.
+type fakeMastodon struct {
+	server   *httptest.Server
+	statuses []mastodonStatus
+	keys     []string // The Idempotency-Key headers of the statuses.
+	uploads  []string // The file names of the uploaded media.
+}
+
+const fakeMastodonToken = "secret-token"
+
+func newFakeMastodon(t *testing.T) *fakeMastodon {
+	fake := &fakeMastodon{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/statuses", func(w http.ResponseWriter, r *http.Request) {
+		if !fake.authorized(w, r) {
+			return
+		}
+
+		var status mastodonStatus
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if status.Status == "" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, `{"error":"Validation failed: Text can't be blank"}`)
+			return
+		}
+
+		fake.statuses = append(fake.statuses, status)
+		fake.keys = append(fake.keys, r.Header.Get("Idempotency-Key"))
+		id := strconv.Itoa(100 + len(fake.statuses))
+		json.NewEncoder(w).Encode(map[string]string{"id": id, "url": fake.server.URL + "/@product/" + id})
+	})
+
+	mux.HandleFunc("/api/v2/media", func(w http.ResponseWriter, r *http.Request) {
+		if !fake.authorized(w, r) {
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file.Close()
+
+		if contentType := header.Header.Get("Content-Type"); contentType != "image/png" {
+			http.Error(w, "unexpected content type "+contentType, http.StatusBadRequest)
+			return
+		}
+
+		fake.uploads = append(fake.uploads, header.Filename)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"id": "media-1"})
+	})
+
+	fake.server = httptest.NewServer(mux)
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fake *fakeMastodon) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+fakeMastodonToken {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":"The access token is invalid"}`)
+		return false
+	}
+	return true
+}
+
+func (fake *fakeMastodon) sender(t *testing.T) *mastodonSender {
+	sender := newMastodonSender(Mastodon{Instance: fake.server.URL + "/", AccessToken: fakeMastodonToken})
+	if err := sender.Configure(); err != nil {
+		t.Fatal(err)
+	}
+	return sender
+}
+
+func TestMastodonConfigure(t *testing.T) {
+	tests := []struct {
+		config   Mastodon
+		expected error
+	}{
+		{Mastodon{AccessToken: "token"}, ErrMissingAuth},
+		{Mastodon{Instance: "https://mastodon.social"}, ErrMissingAuth},
+		{Mastodon{Instance: "mastodon.social", AccessToken: "token"}, errors.New("invalid")},
+		{Mastodon{Instance: "https://mastodon.social", AccessToken: "token"}, nil},
+	}
+
+	for _, test := range tests {
+		err := newMastodonSender(test.config).Configure()
+		if test.expected == nil && err != nil {
+			t.Fatalf("Expected %v to be valid. Error: %q", test.config, err)
+		}
+		if test.expected != nil && err == nil {
+			t.Fatalf("Expected %v to be invalid", test.config)
+		}
+		if errors.Is(test.expected, ErrMissingAuth) && !errors.Is(err, ErrMissingAuth) {
+			t.Fatalf("Expected error: %q. Result: %q", ErrMissingAuth, err)
+		}
+	}
+}
+
+func TestMastodonPost(t *testing.T) {
+	fake := newFakeMastodon(t)
+	sender := fake.sender(t)
+
+	result, err := sender.Post(context.Background(), "Hello world", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Id != "101" || result.URL != fake.server.URL+"/@product/101" {
+		t.Fatalf("Unexpected result: %v", result)
+	}
+
+	result, err = sender.PostReply(context.Background(), result.Id, "Hello again", []string{"media-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := mastodonStatus{Status: "Hello again", MediaIds: []string{"media-1"}, InReplyToId: "101"}
+	status := fake.statuses[1]
+	if status.Status != expected.Status || status.InReplyToId != expected.InReplyToId ||
+		len(status.MediaIds) != 1 || status.MediaIds[0] != expected.MediaIds[0] {
+		t.Fatalf("Expected: %v. Result: %v", expected, status)
+	}
+}
+
+func TestMastodonIdempotencyKey(t *testing.T) {
+	fake := newFakeMastodon(t)
+	sender := fake.sender(t)
+
+	tw := tweet.Tweet{Id: uuid.New(), Message: "Hello world"}
+	for _, account := range []string{"mastodon:product", "mastodon:product", "mastodon:support"} {
+		if _, err := post(context.Background(), sender, tw, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A retry uses the same key, each destination has its own
+	expected := []string{tw.Id.String() + "/mastodon:product", tw.Id.String() + "/mastodon:product", tw.Id.String() + "/mastodon:support"}
+	if !reflect.DeepEqual(fake.keys, expected) {
+		t.Fatalf("Expected: %v. Result: %v", expected, fake.keys)
+	}
+
+	// Without a tweet there is no key
+	if _, err := sender.Post(context.Background(), "Hello again", nil); err != nil {
+		t.Fatal(err)
+	}
+	if key := fake.keys[len(fake.keys)-1]; key != "" {
+		t.Fatalf("Expected no Idempotency-Key. Result: %q", key)
+	}
+}
+
+func TestMastodonErrors(t *testing.T) {
+	fake := newFakeMastodon(t)
+
+	sender := fake.sender(t)
+	_, err := sender.Post(context.Background(), "", nil)
+
+	var mastodonErr *MastodonError
+	if !errors.As(err, &mastodonErr) || mastodonErr.StatusCode != http.StatusUnprocessableEntity ||
+		mastodonErr.Message != "Validation failed: Text can't be blank" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	sender.config.AccessToken = "invalid"
+	if _, err := sender.Post(context.Background(), "Hello", nil); !errors.As(err, &mastodonErr) ||
+		mastodonErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestMastodonUploadMedia(t *testing.T) {
+	fake := newFakeMastodon(t)
+	sender := fake.sender(t)
+
+	filePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(filePath, []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := sender.UploadMedia(context.Background(), filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != "media-1" || len(fake.uploads) != 1 || fake.uploads[0] != "image.png" {
+		t.Fatalf("Unexpected upload. Id: %q, Uploads: %v", id, fake.uploads)
+	}
+}
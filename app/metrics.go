@@ -0,0 +1,120 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/metrics"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+// Metrics describes the state of the queue and what happened while sending, in the Prometheus text format.
+type Metrics struct {
+	registry *metrics.Registry
+
+	queue              *metrics.Gauge
+	due                *metrics.Gauge
+	oldestOverdue      *metrics.Gauge
+	sends              *metrics.Counter
+	apiLatency         *metrics.Histogram
+	rateLimitRemaining *metrics.Gauge
+	lastSend           *metrics.Gauge
+}
+
+// Create the Metrics used by the Application (see Application.SetMetrics).
+func NewMetrics() *Metrics {
+	registry := metrics.NewRegistry()
+	return &Metrics{
+		registry: registry,
+		queue: registry.NewGauge("ajtweet_queue_tweets",
+			"The number of tweets in the datastore by status.", "status"),
+		due: registry.NewGauge("ajtweet_due_tweets",
+			"The number of tweets that are due to be sent but have not been sent yet."),
+		oldestOverdue: registry.NewGauge("ajtweet_oldest_overdue_seconds",
+			"How long the oldest due tweet has been waiting to be sent."),
+		sends: registry.NewCounter("ajtweet_sends_total",
+			"The number of sends to each service by outcome (sent, failed, skipped, dry-run or expired).", "outcome", "service"),
+		apiLatency: registry.NewHistogram("ajtweet_api_request_duration_seconds",
+			"The time taken to post a tweet (including uploading the media) to the service.", metrics.DefaultBuckets, "service"),
+		rateLimitRemaining: registry.NewGauge("ajtweet_rate_limit_remaining",
+			"The number of requests remaining in the current rate limit window as reported by the service.", "account", "service"),
+		lastSend: registry.NewGauge("ajtweet_last_send_timestamp_seconds",
+			"The time the last send finished as a Unix timestamp."),
+	}
+}
+
+// Return the Registry used to expose the metrics over HTTP or to write them to a textfile.
+func (m *Metrics) Registry() *metrics.Registry {
+	return m.registry
+}
+
+// Update the queue metrics from the tweets in the list.
+func (m *Metrics) observeQueue(list *tweet.TweetList, now time.Time) {
+	if m == nil {
+		return
+	}
+
+	stats := list.Stats(now)
+	for status, count := range stats.ByStatus {
+		m.queue.Set(float64(count), string(status))
+	}
+	m.due.Set(float64(stats.Due))
+	m.oldestOverdue.Set(stats.OldestOverdue.Seconds())
+}
+
+// Record the outcome of sending a tweet to one of its destinations.
+// The latency is only recorded when the service responded, i.e. not for a dry-run or when sending was stopped.
+func (m *Metrics) observeDestination(entry DestinationReport, duration time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.sends.Inc(string(entry.Outcome), entry.Service)
+	if entry.Outcome == OutcomeSent || entry.Outcome == OutcomeFailed {
+		m.apiLatency.Observe(duration.Seconds(), entry.Service)
+	}
+	if entry.RateLimit != nil {
+		account := entry.Account
+		if account == "" {
+			account = "default"
+		}
+		m.rateLimitRemaining.Set(float64(entry.RateLimit.Remaining), account, entry.Service)
+	}
+}
+
+// Record a tweet that expired (for the service of one of its destinations) before it could be sent.
+func (m *Metrics) observeExpired(service string) {
+	if m == nil {
+		return
+	}
+	m.sends.Inc(string(OutcomeExpired), service)
+}
+
+// Record that a send has finished.
+func (m *Metrics) observeSendFinished(now time.Time) {
+	if m == nil {
+		return
+	}
+	m.lastSend.Set(float64(now.Unix()))
+}
@@ -0,0 +1,133 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendMetrics(t *testing.T) {
+	_, server := newMockTwitter(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 1
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.ExpireAfter = 24
+	app.config.Send.APIBaseURL = server.URL
+	app.config.Send.Authentication = testTwitterAuth
+
+	metrics := NewMetrics()
+	app.SetMetrics(metrics)
+
+	now := time.Now()
+	for _, scheduled := range []time.Time{now.Add(-48 * time.Hour), now.Add(-time.Hour), now.Add(-time.Minute), now.Add(time.Hour)} {
+		if err := app.Add("Hello "+scheduled.String(), scheduled.Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := app.Send(context.Background(), io.Discard, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := metrics.Registry().Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	result := buffer.String()
+
+	expected := []string{
+		`ajtweet_queue_tweets{status="scheduled"} 2` + "\n",
+		`ajtweet_queue_tweets{status="failed"} 0` + "\n",
+		"ajtweet_due_tweets 1\n",
+		`ajtweet_sends_total{outcome="sent",service="twitter"} 1` + "\n",
+		`ajtweet_sends_total{outcome="expired",service="twitter"} 1` + "\n",
+		`ajtweet_api_request_duration_seconds_count{service="twitter"} 1` + "\n",
+		`ajtweet_rate_limit_remaining{account="default",service="twitter"} 199` + "\n",
+		"ajtweet_last_send_timestamp_seconds ",
+	}
+	for _, line := range expected {
+		if !strings.Contains(result, line) {
+			t.Fatalf("Expected %q. Result:\n%s", line, result)
+		}
+	}
+
+	// The tweet scheduled a minute ago is the only one still due
+	if strings.Contains(result, "ajtweet_oldest_overdue_seconds 0\n") {
+		t.Fatalf("Expected the oldest overdue age. Result:\n%s", result)
+	}
+}
+
+func TestReload(t *testing.T) {
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+
+	if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes made by another instance
+	other := Application{}
+	if err := other.Configure(app.config); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Add("World", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete(other.tweets.Tweets[0].Id.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(app.tweets.Tweets) != 1 || app.tweets.Tweets[0].Message != "World" {
+		t.Fatalf("Expected only the tweet added by the other instance. Result: %v", app.tweets.Tweets)
+	}
+}
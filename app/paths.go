@@ -0,0 +1,270 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// The name of the directory created inside the XDG base directories, e.g. $XDG_DATA_HOME/ajtweet
+const appDirName = "ajtweet"
+
+const (
+	datastoreFileName = "ajtweets-data.json"
+	lockFileName      = "ajtweet.lock"
+	secretsFileName   = "secrets.json"
+
+	// The files created in the current working directory by the versions before the XDG base directories were used.
+	legacyDatastore   = "./" + datastoreFileName
+	legacyLockfile    = "./" + lockFileName
+	legacySecretsFile = "./ajtweet-secrets.json"
+)
+
+// Return the directory used for the data files, e.g. the datastore and the encrypted secrets file.
+// $XDG_DATA_HOME/ajtweet or $HOME/.local/share/ajtweet when XDG_DATA_HOME is not set.
+// An empty string is returned when neither can be determined.
+func DataDir() string {
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// Return the directory used for the lock file.
+// $XDG_STATE_HOME/ajtweet or $HOME/.local/state/ajtweet when XDG_STATE_HOME is not set.
+// $XDG_RUNTIME_DIR is not used since it is only set in login sessions, e.g. not when run by cron, and every
+// process using the datastore has to use the same lock file.
+// An empty string is returned when neither can be determined.
+func StateDir() string {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// Return the directory searched for the configuration file.
+// $XDG_CONFIG_HOME/ajtweet or $HOME/.config/ajtweet when XDG_CONFIG_HOME is not set.
+// An empty string is returned when neither can be determined.
+func ConfigDir() string {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// Return the default path of the datastore. Falls back to the current working directory when the
+// data directory can not be determined.
+func DefaultDatastore() string {
+	if dir := DataDir(); dir != "" {
+		return filepath.Join(dir, datastoreFileName)
+	}
+	return legacyDatastore
+}
+
+// Return the default path of the lock file. Falls back to the current working directory when the
+// state directory can not be determined.
+func DefaultLockfile() string {
+	if dir := StateDir(); dir != "" {
+		return filepath.Join(dir, lockFileName)
+	}
+	return legacyLockfile
+}
+
+// Return the default path of the encrypted secrets file. Falls back to the current working directory when the
+// data directory can not be determined.
+func DefaultSecretsFile() string {
+	if dir := DataDir(); dir != "" {
+		return filepath.Join(dir, secretsFileName)
+	}
+	return legacySecretsFile
+}
+
+// Return the directory specified by the XDG environment variable or the fallback inside the home directory.
+func xdgDir(name string, fallback ...string) string {
+	if dir := xdgEnv(name); dir != "" {
+		return filepath.Join(dir, appDirName)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), appDirName)...)
+}
+
+// Return the value of the XDG environment variable.
+// Relative paths are ignored as required by the XDG Base Directory Specification.
+func xdgEnv(name string) string {
+	if dir := os.Getenv(name); filepath.IsAbs(dir) {
+		return dir
+	}
+	return ""
+}
+
+// Create the parent directory of the file if it does not exist yet.
+func ensureParentDir(filePath string) error {
+	return os.MkdirAll(filepath.Dir(filePath), 0700)
+}
+
+// Return the configuration files with the base name that exist in the directories, in the order of the
+// directories, e.g. ./.ajtweet.yaml and $HOME/.ajtweet.ini
+func FindConfigFiles(dirs []string, name string, extensions []string) []string {
+	var files []string
+	for _, dir := range dirs {
+		for _, extension := range extensions {
+			filePath := filepath.Join(dir, name+"."+extension)
+			if info, err := os.Stat(filePath); err == nil && !info.IsDir() {
+				files = append(files, filePath)
+			}
+		}
+	}
+	return files
+}
+
+// Move the datastore created in the current working directory by the previous versions to the default
+// datastore path. This is only done once: when the default datastore is used and does not exist yet and
+// no older version holds the legacy lock file.
+// Returns the path the datastore was moved from, or an empty string when nothing was migrated.
+func (config Config) MigrateLegacyDatastore() (string, error) {
+	if fileExists(legacyLockfile) {
+		return "", nil
+	}
+
+	moved, err := migrateLegacyFile(legacyDatastore, config.Datastore.Filepath, DefaultDatastore())
+	if err != nil || !moved {
+		return "", err
+	}
+	return legacyDatastore, nil
+}
+
+// Move the encrypted secrets file created in the current working directory by the previous versions to the
+// default secrets file path. This is only done once: when the default secrets file is used and does not exist yet.
+// Returns the path the secrets file was moved from, or an empty string when nothing was migrated.
+func (config Config) MigrateLegacySecrets() (string, error) {
+	moved, err := migrateLegacyFile(legacySecretsFile, config.Secrets.File, DefaultSecretsFile())
+	if err != nil || !moved {
+		return "", err
+	}
+	return legacySecretsFile, nil
+}
+
+// Move the legacy file to the target when the target is the default path and does not exist yet.
+// Returns true when the file was moved.
+func migrateLegacyFile(legacy, target, defaultPath string) (bool, error) {
+	if target != defaultPath || samePath(target, legacy) {
+		return false, nil
+	}
+
+	if !fileExists(legacy) || fileExists(target) {
+		return false, nil
+	}
+
+	if err := ensureParentDir(target); err != nil {
+		return false, err
+	}
+	if err := moveFile(legacy, target); err != nil {
+		return false, fmt.Errorf("failed to move %q to %q: %w", legacy, target, err)
+	}
+	return true, nil
+}
+
+// Return warnings about the files in the current working directory that were created by the previous
+// versions and are not used, e.g. a datastore that exists next to the default datastore.
+func (config Config) PathWarnings() []string {
+	var warnings []string
+
+	if fileExists(legacyDatastore) && !samePath(config.Datastore.Filepath, legacyDatastore) {
+		warnings = append(warnings, fmt.Sprintf("the datastore %q in the current directory is not used, the tweets are stored in %q. "+
+			"Set datastore.filepath to use it or move it away", legacyDatastore, config.Datastore.Filepath))
+	}
+
+	if fileExists(legacySecretsFile) && !samePath(config.Secrets.File, legacySecretsFile) {
+		warnings = append(warnings, fmt.Sprintf("the secrets file %q in the current directory is not used, the secrets are stored in %q. "+
+			"Set secrets.file to use it or move it away", legacySecretsFile, config.Secrets.File))
+	}
+
+	if fileExists(legacyLockfile) && !samePath(config.Lockfile, legacyLockfile) {
+		warnings = append(warnings, fmt.Sprintf("the lock file %q in the current directory is not used, the lock file is %q. "+
+			"An older version of ajtweet might be running, otherwise delete it", legacyLockfile, config.Lockfile))
+	}
+
+	// Lock files were created in $XDG_RUNTIME_DIR/ajtweet by some of the previous versions
+	if dir := xdgEnv("XDG_RUNTIME_DIR"); dir != "" {
+		runtimeLockfile := filepath.Join(dir, appDirName, lockFileName)
+		if fileExists(runtimeLockfile) && !samePath(config.Lockfile, runtimeLockfile) {
+			warnings = append(warnings, fmt.Sprintf("the lock file %q is not used, the lock file is %q. "+
+				"An older version of ajtweet might be running, otherwise delete it", runtimeLockfile, config.Lockfile))
+		}
+	}
+
+	return warnings
+}
+
+// Check if both paths refer to the same file.
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+func fileExists(filePath string) bool {
+	_, err := os.Stat(filePath)
+	return err == nil
+}
+
+// Move the file, copying it when it can not be renamed, e.g. to a different file system.
+func moveFile(source, target string) error {
+	err := os.Rename(source, target)
+	if err == nil {
+		return nil
+	}
+
+	var linkErr *os.LinkError
+	if !errors.As(err, &linkErr) {
+		return err
+	}
+
+	if err := copyFile(source, target); err != nil {
+		return err
+	}
+	return os.Remove(source)
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(target)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,274 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Change the current working directory to a temporary directory for the duration of the test.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+func TestXDGDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	if dir := DataDir(); dir != filepath.Join(home, ".local", "share", "ajtweet") {
+		t.Fatalf("Unexpected data directory: %q", dir)
+	}
+	if dir := StateDir(); dir != filepath.Join(home, ".local", "state", "ajtweet") {
+		t.Fatalf("Unexpected state directory: %q", dir)
+	}
+	if dir := ConfigDir(); dir != filepath.Join(home, ".config", "ajtweet") {
+		t.Fatalf("Unexpected config directory: %q", dir)
+	}
+
+	t.Setenv("XDG_DATA_HOME", "/data")
+	t.Setenv("XDG_STATE_HOME", "/state")
+	t.Setenv("XDG_CONFIG_HOME", "/config")
+	if DefaultDatastore() != filepath.Join("/data", "ajtweet", "ajtweets-data.json") ||
+		DefaultSecretsFile() != filepath.Join("/data", "ajtweet", "secrets.json") ||
+		DefaultLockfile() != filepath.Join("/state", "ajtweet", "ajtweet.lock") ||
+		ConfigDir() != filepath.Join("/config", "ajtweet") {
+		t.Fatalf("Unexpected paths: %q, %q, %q, %q", DefaultDatastore(), DefaultSecretsFile(), DefaultLockfile(), ConfigDir())
+	}
+
+	// The lock file does not depend on the session, e.g. cron does not set XDG_RUNTIME_DIR
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if lockfile := DefaultLockfile(); lockfile != filepath.Join("/state", "ajtweet", "ajtweet.lock") {
+		t.Fatalf("Unexpected lock file: %q", lockfile)
+	}
+
+	// Relative paths are ignored
+	t.Setenv("XDG_STATE_HOME", "state")
+	t.Setenv("XDG_DATA_HOME", "data")
+	if DefaultLockfile() != filepath.Join(home, ".local", "state", "ajtweet", "ajtweet.lock") ||
+		DefaultDatastore() != filepath.Join(home, ".local", "share", "ajtweet", "ajtweets-data.json") {
+		t.Fatalf("Unexpected paths: %q, %q", DefaultDatastore(), DefaultLockfile())
+	}
+}
+
+func TestFindConfigFiles(t *testing.T) {
+	first := t.TempDir()
+	second := t.TempDir()
+	for _, filePath := range []string{filepath.Join(first, ".ajtweet.yaml"), filepath.Join(second, ".ajtweet.ini")} {
+		if err := os.WriteFile(filePath, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := FindConfigFiles([]string{first, t.TempDir(), second}, ".ajtweet", []string{"json", "yaml", "ini"})
+	expected := []string{filepath.Join(first, ".ajtweet.yaml"), filepath.Join(second, ".ajtweet.ini")}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("Expected %v. Result: %v", expected, files)
+	}
+}
+
+func TestMigrateLegacyDatastore(t *testing.T) {
+	chdirTemp(t)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	config := NewConfig()
+	if from, err := config.MigrateLegacyDatastore(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+
+	data := []byte(`{"tweets":[]}`)
+	if err := os.WriteFile(legacyDatastore, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not migrated while an older version might be running
+	if err := os.WriteFile(legacyLockfile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := config.MigrateLegacyDatastore(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+	os.Remove(legacyLockfile)
+
+	// Not migrated when the datastore is configured
+	configured := config
+	configured.Datastore.Filepath = "./tweets.json"
+	if from, err := configured.MigrateLegacyDatastore(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+
+	from, err := config.MigrateLegacyDatastore()
+	if err != nil || from != legacyDatastore {
+		t.Fatalf("Expected the datastore to be migrated. Result: %q, %v", from, err)
+	}
+	if moved, err := os.ReadFile(config.Datastore.Filepath); err != nil || string(moved) != string(data) {
+		t.Fatalf("Unexpected datastore: %s, error: %v", moved, err)
+	}
+	if fileExists(legacyDatastore) {
+		t.Fatal("Expected the legacy datastore to be moved")
+	}
+
+	// Only done once, an existing datastore is never replaced
+	if err := os.WriteFile(legacyDatastore, []byte(`{"tweets":null}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := config.MigrateLegacyDatastore(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+	if moved, _ := os.ReadFile(config.Datastore.Filepath); string(moved) != string(data) {
+		t.Fatalf("Expected the datastore to be kept. Result: %s", moved)
+	}
+}
+
+func TestMigrateLegacySecrets(t *testing.T) {
+	chdirTemp(t)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	config := NewConfig()
+	if from, err := config.MigrateLegacySecrets(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+
+	data := []byte(`{"version":1}`)
+	if err := os.WriteFile(legacySecretsFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Not migrated when the secrets file is configured
+	configured := config
+	configured.Secrets.File = "./secrets.json"
+	if from, err := configured.MigrateLegacySecrets(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+
+	from, err := config.MigrateLegacySecrets()
+	if err != nil || from != legacySecretsFile {
+		t.Fatalf("Expected the secrets file to be migrated. Result: %q, %v", from, err)
+	}
+	if moved, err := os.ReadFile(config.Secrets.File); err != nil || string(moved) != string(data) {
+		t.Fatalf("Unexpected secrets file: %s, error: %v", moved, err)
+	}
+	if info, err := os.Stat(config.Secrets.File); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the secrets file to stay private. Result: %v, error: %v", info.Mode(), err)
+	}
+
+	// An existing secrets file is never replaced
+	if err := os.WriteFile(legacySecretsFile, []byte(`{}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if from, err := config.MigrateLegacySecrets(); err != nil || from != "" {
+		t.Fatalf("Expected nothing to be migrated. Result: %q, %v", from, err)
+	}
+	if warnings := config.PathWarnings(); len(warnings) != 1 || !strings.Contains(warnings[0], legacySecretsFile) {
+		t.Fatalf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestPathWarnings(t *testing.T) {
+	dir := chdirTemp(t)
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	config := NewConfig()
+	if warnings := config.PathWarnings(); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings. Result: %v", warnings)
+	}
+
+	for _, filePath := range []string{legacyDatastore, legacyLockfile} {
+		if err := os.WriteFile(filePath, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	warnings := config.PathWarnings()
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "datastore") || !strings.Contains(warnings[1], "lock file") {
+		t.Fatalf("Unexpected warnings: %v", warnings)
+	}
+
+	// The files in the current directory are used
+	config.Datastore.Filepath = filepath.Join(dir, datastoreFileName)
+	config.Lockfile = legacyLockfile
+	if warnings := config.PathWarnings(); len(warnings) != 0 {
+		t.Fatalf("Expected no warnings. Result: %v", warnings)
+	}
+
+	// A lock file in the runtime directory that was used by some of the previous versions
+	runtimeLockfile := filepath.Join(runtimeDir, "ajtweet", "ajtweet.lock")
+	if err := ensureParentDir(runtimeLockfile); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(runtimeLockfile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	warnings = config.PathWarnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], runtimeLockfile) {
+		t.Fatalf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestCreateMissingDirs(t *testing.T) {
+	dir := t.TempDir()
+
+	var app Application
+	config := NewConfig()
+	config.Datastore.Filepath = filepath.Join(dir, "data", "ajtweet", "tweets.json")
+	config.Lockfile = filepath.Join(dir, "run", "ajtweet", "ajtweet.lock")
+
+	if errs := config.CheckPaths(); len(errs) != 0 {
+		t.Fatalf("Expected the missing directories to be allowed. Result: %v", errs)
+	}
+
+	if err := app.Configure(config); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.AcquireLock(); err != nil {
+		t.Fatal(err)
+	}
+	defer app.ReleaseLock()
+
+	if err := app.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(config.Datastore.Filepath) || !fileExists(config.Lockfile) {
+		t.Fatal("Expected the datastore and lock file to be created")
+	}
+}
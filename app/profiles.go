@@ -0,0 +1,116 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The configuration key of the profiles, e.g. profiles.staging.datastore.filepath
+const profilesKey = "profiles"
+
+// The environment variable used to select the profile when --profile is not specified.
+const EnvProfile = EnvPrefix + "_PROFILE"
+
+var (
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// Return the names of the profiles in the configuration, sorted.
+func ProfileNames(v *viper.Viper) []string {
+	profiles := v.GetStringMap(profilesKey)
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Merge the values of the profile on top of the shared configuration values.
+// The environment variables and flags still take precedence over the values of the profile.
+func ApplyProfile(v *viper.Viper, name string) error {
+	settings, exists := v.GetStringMap(profilesKey)[strings.ToLower(name)]
+	if !exists {
+		available := "none"
+		if names := ProfileNames(v); len(names) > 0 {
+			available = strings.Join(names, ", ")
+		}
+		return fmt.Errorf("%w: %q (available profiles: %s)", ErrUnknownProfile, name, available)
+	}
+
+	if settings == nil {
+		return nil
+	}
+
+	values, ok := toStringMap(settings)
+	if !ok {
+		return fmt.Errorf("%w: profiles.%s: expected the configuration values of the profile", ErrInvalidConfig, name)
+	}
+	return v.MergeConfigMap(values)
+}
+
+// Convert the map decoded from the configuration file (the keys can be of any type) to a map keyed by strings.
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, true
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			result[fmt.Sprint(key)] = item
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// Return the references of the accounts tweets can be sent from, e.g. twitter and mastodon:product
+// The default Twitter account (send.authentication) is listed as twitter.
+func (config Config) AccountRefs() []string {
+	app := Application{config: config}
+	accounts := app.accounts()
+
+	var refs []string
+	if len(accounts) == 0 || config.Send.Authentication != (Authentication{}) {
+		refs = append(refs, ServiceTwitter)
+	}
+	for _, account := range accounts {
+		refs = append(refs, account.Ref())
+	}
+	return refs
+}
+
+// Strip the profiles.name prefix from the configuration key, e.g. profiles.staging.send.max is send.max
+func profileKey(key string) string {
+	parts := strings.SplitN(key, ".", 3)
+	if len(parts) == 3 && parts[0] == profilesKey {
+		return parts[2]
+	}
+	return key
+}
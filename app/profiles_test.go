@@ -0,0 +1,152 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const profilesYAML = `
+datastore:
+  filepath: ./shared.json
+send:
+  max: 10
+  delay: 30
+profiles:
+  staging:
+    datastore:
+      filepath: ./staging.json
+    send:
+      max: 2
+  production:
+    accounts:
+      mastodon:
+        product:
+          instance: https://mastodon.social
+          access_token: token
+  empty:
+`
+
+func readProfilesConfig(t *testing.T) *viper.Viper {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(profilesYAML)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestProfileNames(t *testing.T) {
+	v := readProfilesConfig(t)
+	expected := []string{"empty", "production", "staging"}
+	if names := ProfileNames(v); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected %v. Result: %v", expected, names)
+	}
+
+	if names := ProfileNames(viper.New()); len(names) != 0 {
+		t.Fatalf("Expected no profiles. Result: %v", names)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	v := readProfilesConfig(t)
+	if err := ApplyProfile(v, "Staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewConfig()
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	// Values of the profile override the shared values, the others are kept
+	if config.Datastore.Filepath != "./staging.json" || config.Send.Max != 2 || config.Send.Delay != 30 {
+		t.Fatalf("Unexpected configuration: datastore %q, max %d, delay %d", config.Datastore.Filepath, config.Send.Max, config.Send.Delay)
+	}
+
+	// The environment still takes precedence over the profile
+	t.Setenv("AJTWEET_SEND_MAX", "7")
+	v = readProfilesConfig(t)
+	if err := BindEnv(v); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyProfile(v, "staging"); err != nil {
+		t.Fatal(err)
+	}
+	if max := v.GetInt("send.max"); max != 7 {
+		t.Fatalf("Expected send.max == 7. Result: %d", max)
+	}
+
+	if err := ApplyProfile(readProfilesConfig(t), "empty"); err != nil {
+		t.Fatalf("Expected an empty profile to be allowed. Result: %v", err)
+	}
+}
+
+func TestApplyUnknownProfile(t *testing.T) {
+	err := ApplyProfile(readProfilesConfig(t), "qa")
+	if !errors.Is(err, ErrUnknownProfile) || !strings.Contains(err.Error(), "empty, production, staging") {
+		t.Fatalf("Expected error: %q. Result: %v", ErrUnknownProfile, err)
+	}
+}
+
+func TestAccountRefs(t *testing.T) {
+	v := readProfilesConfig(t)
+	if err := ApplyProfile(v, "production"); err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewConfig()
+	if err := v.Unmarshal(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	if refs := config.AccountRefs(); !reflect.DeepEqual(refs, []string{"mastodon:product"}) {
+		t.Fatalf("Unexpected accounts: %v", refs)
+	}
+
+	config.Send.Authentication.APIKey = "key"
+	if refs := config.AccountRefs(); !reflect.DeepEqual(refs, []string{"twitter", "mastodon:product"}) {
+		t.Fatalf("Unexpected accounts: %v", refs)
+	}
+}
+
+func TestIsKnownProfileKey(t *testing.T) {
+	tests := map[string]bool{
+		"profiles.staging.send.max":                              true,
+		"profiles.staging.datastore.filepath":                    true,
+		"profiles.production.accounts.mastodon.product.instance": true,
+		"profiles.staging.send.maximum":                          false,
+		"profiles.staging.profiles.qa.send.max":                  false,
+	}
+
+	for key, expected := range tests {
+		if known := IsKnownKey(key); known != expected {
+			t.Fatalf("%s: Expected %v. Result: %v", key, expected, known)
+		}
+	}
+}
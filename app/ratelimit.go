@@ -0,0 +1,117 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit is the state of the API rate limit reported by a service.
+type RateLimit struct {
+	Limit     int       `json:"limit"`     // The number of requests allowed in the current window.
+	Remaining int       `json:"remaining"` // The number of requests remaining in the current window.
+	Reset     time.Time `json:"reset"`     // The time at which the window will be reset.
+}
+
+// The header prefixes used by the services to report the rate limit.
+var rateLimitHeaderPrefixes = []string{
+	"X-Rate-Limit-", // Twitter
+	"X-RateLimit-",  // Mastodon
+	"RateLimit-",    // Bluesky
+}
+
+// Parse the rate limit from the response headers. Return nil when the headers do not contain a rate limit.
+func parseRateLimit(header http.Header) *RateLimit {
+	for _, prefix := range rateLimitHeaderPrefixes {
+		limit, err := strconv.Atoi(header.Get(prefix + "Limit"))
+		if err != nil {
+			continue
+		}
+
+		rateLimit := &RateLimit{Limit: limit}
+		rateLimit.Remaining, _ = strconv.Atoi(header.Get(prefix + "Remaining"))
+
+		// The reset time is either the number of seconds since the epoch or a timestamp (Mastodon)
+		reset := header.Get(prefix + "Reset")
+		if seconds, err := strconv.ParseInt(reset, 10, 64); err == nil {
+			rateLimit.Reset = time.Unix(seconds, 0).UTC()
+		} else if t, err := time.Parse(time.RFC3339, reset); err == nil {
+			rateLimit.Reset = t.UTC()
+		}
+		return rateLimit
+	}
+	return nil
+}
+
+// http.RoundTripper that records the rate limit reported by the last response.
+type rateLimitRecorder struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	last *RateLimit
+}
+
+// Return a copy of the http.Client that records the rate limits using the returned recorder.
+func recordRateLimits(httpClient *http.Client) (*http.Client, *rateLimitRecorder) {
+	client := &http.Client{}
+	if httpClient != nil {
+		*client = *httpClient
+	}
+
+	recorder := &rateLimitRecorder{next: client.Transport}
+	if recorder.next == nil {
+		recorder.next = http.DefaultTransport
+	}
+	client.Transport = recorder
+	return client, recorder
+}
+
+func (r *rateLimitRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := r.next.RoundTrip(req)
+	if err == nil {
+		if rateLimit := parseRateLimit(res.Header); rateLimit != nil {
+			r.mu.Lock()
+			r.last = rateLimit
+			r.mu.Unlock()
+		}
+	}
+	return res, err
+}
+
+// Return the last rate limit recorded or nil when none has been recorded.
+func (r *rateLimitRecorder) RateLimit() *RateLimit {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return nil
+	}
+	rateLimit := *r.last
+	return &rateLimit
+}
@@ -0,0 +1,257 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownReport = errors.New("unknown report format")
+)
+
+// ReportFormat is the format of the report written after sending.
+type ReportFormat string
+
+const (
+	ReportJSON ReportFormat = "json"
+)
+
+// Parse the report format from a string, e.g. json.
+func ParseReportFormat(value string) (ReportFormat, error) {
+	switch format := ReportFormat(strings.ToLower(value)); format {
+	case ReportJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownReport, value)
+}
+
+// SendOutcome describes what happened to a tweet (or one of its destinations) during a send.
+type SendOutcome string
+
+const (
+	OutcomeSent    SendOutcome = "sent"    // The tweet was sent.
+	OutcomeSkipped SendOutcome = "skipped" // The tweet was not attempted, e.g. sending stopped after an earlier failure.
+	OutcomeFailed  SendOutcome = "failed"  // Sending the tweet failed.
+	OutcomeDryRun  SendOutcome = "dry-run" // The tweet would have been sent.
+	OutcomeExpired SendOutcome = "expired" // The tweet expired instead of being sent.
+)
+
+// SendResult summarises the outcome of a send as a whole.
+type SendResult string
+
+const (
+	ResultNothingToSend  SendResult = "nothing-to-send" // No tweets were due to be sent.
+	ResultAllSent        SendResult = "all-sent"        // All the tweets that were due have been sent.
+	ResultPartialFailure SendResult = "partial-failure" // One or more tweets failed or were not sent, e.g. vetoed by a hook.
+	ResultFatal          SendResult = "fatal"           // Sending could not be done at all, e.g. invalid credentials.
+)
+
+// Return the exit code used by the send command for the result.
+func (result SendResult) ExitCode() int {
+	switch result {
+	case ResultAllSent:
+		return 0
+	case ResultNothingToSend:
+		return 3
+	case ResultPartialFailure:
+		return 4
+	}
+	return 1
+}
+
+// SendReport is the machine readable summary of a send.
+type SendReport struct {
+	Started    time.Time     `json:"started"`
+	Finished   time.Time     `json:"finished"`
+	DurationMs int64         `json:"durationMs"`
+	DryRun     bool          `json:"dryRun"`
+	Result     SendResult    `json:"result"`
+	Error      string        `json:"error,omitempty"`
+	Totals     SendTotals    `json:"totals"`
+	Tweets     []TweetReport `json:"tweets"`
+}
+
+// SendTotals counts the tweets by outcome.
+type SendTotals struct {
+	Considered int `json:"considered"`
+	Sent       int `json:"sent"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+	DryRun     int `json:"dryRun"`
+	Expired    int `json:"expired"`
+}
+
+// TweetReport describes what happened to a single tweet.
+type TweetReport struct {
+	Id           uuid.UUID           `json:"id"`
+	Message      string              `json:"message"`
+	Outcome      SendOutcome         `json:"outcome"`
+	Attempt      int                 `json:"attempt,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	Started      *time.Time          `json:"started,omitempty"`
+	DurationMs   int64               `json:"durationMs"`
+	Destinations []DestinationReport `json:"destinations,omitempty"`
+}
+
+// DestinationReport describes what happened when the tweet was sent to one of its accounts.
+type DestinationReport struct {
+	Account    string      `json:"account"`
+	Service    string      `json:"service"`
+	Outcome    SendOutcome `json:"outcome"`
+	RemoteId   string      `json:"remoteId,omitempty"` // The identifier assigned by the service, e.g. the Twitter ID.
+	URL        string      `json:"url,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"durationMs"`
+	RateLimit  *RateLimit  `json:"rateLimit,omitempty"` // The rate limit reported by the service after the request.
+}
+
+func newSendReport(dryRun bool) *SendReport {
+	return &SendReport{Started: time.Now().UTC(), DryRun: dryRun, Tweets: []TweetReport{}}
+}
+
+// The methods used to build the report are safe to call on a nil *SendReport.
+
+// Record that the tweet expired instead of being sent.
+func (r *SendReport) expired(tw tweet.Tweet) {
+	if r == nil {
+		return
+	}
+	r.Tweets = append(r.Tweets, TweetReport{Id: tw.Id, Message: tw.Message, Outcome: OutcomeExpired})
+}
+
+// Record that sending the tweet has started.
+func (r *SendReport) begin(tw tweet.Tweet) {
+	if r == nil {
+		return
+	}
+	started := time.Now().UTC()
+	r.Tweets = append(r.Tweets, TweetReport{Id: tw.Id, Message: tw.Message, Attempt: tw.Attempts + 1, Started: &started})
+}
+
+// Record what happened when the current tweet was sent to one of its destinations.
+func (r *SendReport) destination(destination DestinationReport) {
+	if r == nil || len(r.Tweets) == 0 {
+		return
+	}
+	current := &r.Tweets[len(r.Tweets)-1]
+	current.Destinations = append(current.Destinations, destination)
+}
+
+// Record the outcome of the current tweet.
+func (r *SendReport) end(outcome SendOutcome, err error) {
+	if r == nil || len(r.Tweets) == 0 {
+		return
+	}
+	current := &r.Tweets[len(r.Tweets)-1]
+	current.Outcome = outcome
+	if err != nil {
+		current.Error = err.Error()
+	}
+	if current.Started != nil {
+		current.DurationMs = time.Since(*current.Started).Milliseconds()
+	}
+}
+
+// Record the tweets that were due to be sent but have not been reported as skipped.
+func (r *SendReport) skipRemaining(tweets []tweet.Tweet) {
+	if r == nil {
+		return
+	}
+
+	reported := make(map[uuid.UUID]bool, len(r.Tweets))
+	for _, tw := range r.Tweets {
+		reported[tw.Id] = true
+	}
+
+	for _, tw := range tweets {
+		if !reported[tw.Id] {
+			r.Tweets = append(r.Tweets, TweetReport{Id: tw.Id, Message: tw.Message, Outcome: OutcomeSkipped})
+		}
+	}
+}
+
+// Count the totals and determine the result from the error returned by the send.
+func (r *SendReport) finish(err error) {
+	if r == nil {
+		return
+	}
+
+	r.Finished = time.Now().UTC()
+	r.DurationMs = r.Finished.Sub(r.Started).Milliseconds()
+
+	r.Totals = SendTotals{}
+	for _, tw := range r.Tweets {
+		switch tw.Outcome {
+		case OutcomeSent:
+			r.Totals.Sent++
+		case OutcomeSkipped:
+			r.Totals.Skipped++
+		case OutcomeFailed:
+			r.Totals.Failed++
+		case OutcomeDryRun:
+			r.Totals.DryRun++
+		case OutcomeExpired:
+			r.Totals.Expired++
+			continue
+		}
+		r.Totals.Considered++
+	}
+
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	stopped := errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+	switch {
+	case err == nil && r.Totals.Considered == 0:
+		r.Result = ResultNothingToSend
+	case err == nil && r.Totals.Skipped == 0:
+		r.Result = ResultAllSent
+	case r.Totals.Failed > 0 || r.Totals.Skipped > 0 || stopped:
+		// The tweets vetoed by a before_send hook are skipped without an error
+		r.Result = ResultPartialFailure
+	default:
+		r.Result = ResultFatal
+	}
+}
+
+// Write the report in the specified format.
+func (r *SendReport) Write(out io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownReport, format)
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/twittertest"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	reset := time.Date(2022, 6, 1, 12, 15, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		header http.Header
+	}{
+		{"Twitter", http.Header{"X-Rate-Limit-Limit": {"200"}, "X-Rate-Limit-Remaining": {"199"}, "X-Rate-Limit-Reset": {"1654085700"}}},
+		{"Mastodon", http.Header{"X-Ratelimit-Limit": {"200"}, "X-Ratelimit-Remaining": {"199"}, "X-Ratelimit-Reset": {"2022-06-01T12:15:00.000Z"}}},
+		{"Bluesky", http.Header{"Ratelimit-Limit": {"200"}, "Ratelimit-Remaining": {"199"}, "Ratelimit-Reset": {"1654085700"}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rateLimit := parseRateLimit(tc.header)
+			if rateLimit == nil || rateLimit.Limit != 200 || rateLimit.Remaining != 199 || !rateLimit.Reset.Equal(reset) {
+				t.Fatalf("Unexpected rate limit: %v", rateLimit)
+			}
+		})
+	}
+
+	if rateLimit := parseRateLimit(http.Header{}); rateLimit != nil {
+		t.Fatalf("Expected no rate limit. Result: %v", rateLimit)
+	}
+}
+
+func newReportApp(t *testing.T, apiBaseURL string) *Application {
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(tempFile) })
+
+	app := &Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Send.APIBaseURL = apiBaseURL
+	app.config.Send.Authentication = testTwitterAuth
+	return app
+}
+
+func TestSendReport(t *testing.T) {
+	// Every second tweet fails
+	mock := twittertest.NewServer(twittertest.Options{FailEvery: 2})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	app := newReportApp(t, server.URL)
+
+	now := time.Now()
+	for i, message := range []string{"First", "Second", "Third"} {
+		scheduledTime := now.Add(time.Duration(i-3) * time.Minute).Format(time.RFC3339)
+		if err := app.Add(message, scheduledTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first tweet is sent, the second fails and the third is skipped
+	report, err := app.SendWith(context.Background(), io.Discard, SendOptions{})
+	if err == nil {
+		t.Fatal("Expected the second tweet to fail")
+	}
+
+	if report.Result != ResultPartialFailure || report.Result.ExitCode() != 4 {
+		t.Fatalf("Expected a partial failure. Result: %s", report.Result)
+	}
+
+	expected := SendTotals{Considered: 3, Sent: 1, Failed: 1, Skipped: 1}
+	if report.Totals != expected {
+		t.Fatalf("Expected totals: %v. Result: %v", expected, report.Totals)
+	}
+
+	outcomes := []SendOutcome{OutcomeSent, OutcomeFailed, OutcomeSkipped}
+	for i, outcome := range outcomes {
+		if report.Tweets[i].Outcome != outcome {
+			t.Fatalf("Expected tweet %d to be %s. Result: %v", i+1, outcome, report.Tweets[i])
+		}
+	}
+
+	sent := report.Tweets[0]
+	if len(sent.Destinations) != 1 || sent.Destinations[0].RemoteId != mock.Posts()[0].Id ||
+		sent.Destinations[0].Service != ServiceTwitter || sent.Attempt != 1 || sent.Started == nil {
+		t.Fatalf("Unexpected report for the sent tweet: %v", sent)
+	}
+
+	rateLimit := sent.Destinations[0].RateLimit
+	if rateLimit == nil || rateLimit.Limit != 200 || rateLimit.Remaining != 199 {
+		t.Fatalf("Expected the rate limit to be reported. Result: %v", rateLimit)
+	}
+
+	if failed := report.Tweets[1]; failed.Error == "" || failed.Destinations[0].Outcome != OutcomeFailed {
+		t.Fatalf("Expected the error to be reported. Result: %v", failed)
+	}
+
+	var buffer bytes.Buffer
+	if err := report.Write(&buffer, ReportJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded SendReport
+	if err := json.Unmarshal(buffer.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Result != ResultPartialFailure || len(decoded.Tweets) != 3 {
+		t.Fatalf("Unexpected JSON report: %s", buffer.String())
+	}
+}
+
+func TestSendReportResults(t *testing.T) {
+	_, server := newMockTwitter(t)
+
+	// Nothing to send
+	app := newReportApp(t, server.URL)
+	report, err := app.SendWith(context.Background(), io.Discard, SendOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Result != ResultNothingToSend || report.Result.ExitCode() != 3 {
+		t.Fatalf("Expected nothing to send. Result: %s", report.Result)
+	}
+
+	// All sent (dry run)
+	if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = app.SendWith(context.Background(), io.Discard, SendOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Result != ResultAllSent || report.Totals.DryRun != 1 || report.Tweets[0].Outcome != OutcomeDryRun {
+		t.Fatalf("Expected the dry run to be reported. Result: %v", report)
+	}
+
+	// Fatal
+	app = newReportApp(t, server.URL)
+	app.config.Send.Proxy = "not a proxy"
+	if err := app.Add("Hello", time.Now().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = app.SendWith(context.Background(), io.Discard, SendOptions{})
+	if err == nil {
+		t.Fatal("Expected the invalid proxy to fail")
+	}
+	if report.Result != ResultFatal || report.Result.ExitCode() != 1 || report.Error == "" {
+		t.Fatalf("Expected a fatal result. Result: %v", report)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/secrets"
+)
+
+// Return the passphrase used to encrypt the secrets file, read from AJTWEET_SECRETS_PASSPHRASE or the key file.
+func (config Secrets) Passphrase() ([]byte, error) {
+	if value, present := os.LookupEnv(envSecretsPassphrase); present && value != "" {
+		return []byte(value), nil
+	}
+
+	if config.KeyFile != "" {
+		return secrets.ReadKeyFile(config.KeyFile)
+	}
+
+	return nil, fmt.Errorf("%w: set %s or secrets.key_file", secrets.ErrNoPassphrase, envSecretsPassphrase)
+}
+
+// Open the encrypted secrets file.
+func OpenSecrets(config Secrets) (*secrets.Store, error) {
+	passphrase, err := config.Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Open(config.File, passphrase)
+}
+
+// Replace the credentials that reference a secret (e.g. secret://twitter-api-key) with the value from the
+// secrets file. The secrets file is only opened when a reference is found.
+func (config *Config) ResolveSecrets() error {
+	var store *secrets.Store
+
+	return config.forEachCredential(func(path string, value *string) error {
+		name, isRef := secrets.ParseRef(*value)
+		if !isRef {
+			return nil
+		}
+
+		if store == nil {
+			var err error
+			if store, err = OpenSecrets(config.Secrets); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+
+		secret, err := store.Get(name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		*value = secret
+		return nil
+	})
+}
+
+// Call fn with the configuration path and value of each credential, e.g. send.authentication.api_key
+// Changes made to the value by fn are stored in the configuration.
+func (config *Config) forEachCredential(fn func(path string, value *string) error) error {
+	if err := config.Send.Authentication.forEachCredential("send.authentication", fn); err != nil {
+		return err
+	}
+
+	for _, name := range sortedKeys(config.Accounts.Twitter) {
+		auth := config.Accounts.Twitter[name]
+		if err := auth.forEachCredential("accounts.twitter."+name, fn); err != nil {
+			return err
+		}
+		config.Accounts.Twitter[name] = auth
+	}
+
+	for _, name := range sortedKeys(config.Accounts.Mastodon) {
+		account := config.Accounts.Mastodon[name]
+		if err := fn("accounts.mastodon."+name+".access_token", &account.AccessToken); err != nil {
+			return err
+		}
+		config.Accounts.Mastodon[name] = account
+	}
+
+	for _, name := range sortedKeys(config.Accounts.Bluesky) {
+		account := config.Accounts.Bluesky[name]
+		if err := fn("accounts.bluesky."+name+".app_password", &account.AppPassword); err != nil {
+			return err
+		}
+		config.Accounts.Bluesky[name] = account
+	}
+
+	for i := range config.Hooks {
+		if err := fn(fmt.Sprintf("hooks[%d].secret", i), &config.Hooks[i].Secret); err != nil {
+			return err
+		}
+	}
+
+	return fn("serve.token", &config.Serve.Token)
+}
+
+func (auth *Authentication) forEachCredential(prefix string, fn func(path string, value *string) error) error {
+	if err := fn(prefix+".api_key", &auth.APIKey); err != nil {
+		return err
+	}
+	if err := fn(prefix+".api_secret", &auth.APISecret); err != nil {
+		return err
+	}
+	if err := fn(prefix+".oauth1.token", &auth.OAuth1.Token); err != nil {
+		return err
+	}
+	return fn(prefix+".oauth1.secret", &auth.OAuth1.Secret)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/secrets"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	t.Setenv(envSecretsPassphrase, "passphrase")
+
+	config := NewConfig()
+	config.Secrets.File = filepath.Join(t.TempDir(), "secrets.json")
+
+	store, err := OpenSecrets(config.Secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("api-key", "key-1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("mastodon-token", "token-5678"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Send.Authentication = Authentication{APIKey: "secret://api-key", APISecret: "plain"}
+	config.Accounts.Mastodon = map[string]Mastodon{
+		"product": {Instance: "https://mastodon.social", AccessToken: "secret://mastodon-token"},
+	}
+
+	if err := config.ResolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Send.Authentication.APIKey != "key-1234" || config.Send.Authentication.APISecret != "plain" {
+		t.Fatalf("Unexpected authentication: %v", config.Send.Authentication)
+	}
+
+	if token := config.Accounts.Mastodon["product"].AccessToken; token != "token-5678" {
+		t.Fatalf("Unexpected access token: %q", token)
+	}
+
+	config.Serve.Token = "secret://missing"
+	err = config.ResolveSecrets()
+	if !errors.Is(err, secrets.ErrNotFound) || !strings.Contains(err.Error(), "serve.token") {
+		t.Fatalf("Expected the missing secret to be reported. Result: %v", err)
+	}
+}
+
+func TestResolveSecretsWithoutReferences(t *testing.T) {
+	t.Setenv(envSecretsPassphrase, "")
+
+	config := NewConfig()
+	config.Secrets.File = filepath.Join(t.TempDir(), "secrets.json")
+	config.Send.Authentication = testTwitterAuth
+
+	if err := config.ResolveSecrets(); err != nil {
+		t.Fatalf("Expected the secrets file not to be needed. Error: %v", err)
+	}
+
+	config.Hooks = []Hook{{Event: "after_send", URL: "https://example.com", Secret: "secret://hook"}}
+	err := config.ResolveSecrets()
+	if !errors.Is(err, secrets.ErrNoPassphrase) || !strings.Contains(err.Error(), "hooks[0].secret") {
+		t.Fatalf("Expected the missing passphrase to be reported. Result: %v", err)
+	}
+}
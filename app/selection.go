@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNothingSelected = errors.New("no identifiers or filters were specified")
+	ErrInvalidShift    = errors.New("invalid time shift")
+)
+
+// Selection specifies the tweets to operate on by their identifiers and/or a filter.
+// When both are specified, only the identified tweets that also match the filter are selected.
+type Selection struct {
+	Ids    []string // Identifiers or unique prefixes of identifiers.
+	Filter Filter
+}
+
+// Return true if neither identifiers nor filter criteria have been specified.
+func (selection Selection) IsEmpty() bool {
+	return len(selection.Ids) == 0 && selection.Filter.IsEmpty()
+}
+
+// Return the selected tweets ordered by which tweets need to be sent first.
+// An empty selection is an error instead of selecting all the tweets.
+func (app *Application) Select(selection Selection) ([]tweet.Tweet, error) {
+	if selection.IsEmpty() {
+		return nil, ErrNothingSelected
+	}
+
+	predicates, err := selection.Filter.predicates(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(selection.Ids) > 0 {
+		ids := make(map[uuid.UUID]bool, len(selection.Ids))
+		for _, idString := range selection.Ids {
+			id, err := app.tweets.Resolve(idString)
+			if err != nil {
+				return nil, err
+			}
+			ids[id] = true
+		}
+
+		predicates = append(predicates, func(tw tweet.Tweet) bool {
+			return ids[tw.Id]
+		})
+	}
+
+	return app.tweets.Query(selection.Filter.Limit, predicates...), nil
+}
+
+// Write the tweets in the same human readable form as the list command to the specified io.Writer.
+func (app *Application) Preview(out io.Writer, tweets []tweet.Tweet) error {
+	return writeTweets(out, tweets, app.idFormatter(false))
+}
+
+// Move the scheduled time of the tweet matching the specified identifier by the shift.
+func (app *Application) Reschedule(id uuid.UUID, shift time.Duration) error {
+	return app.tweets.Update(id, func(tw *tweet.Tweet) error {
+		tw.ScheduledTime = tw.ScheduledTime.Add(shift)
+		return nil
+	})
+}
+
+var shiftRegexp = regexp.MustCompile(`^([+-])?(?:(\d+)w)?(?:(\d+)d)?(.*)$`)
+
+// Parse a time shift such as +1d, -2h30m or 1w2d.
+// In addition to the units supported by time.ParseDuration, w (weeks) and d (days) are supported as
+// long as they are specified first.
+func ParseShift(value string) (time.Duration, error) {
+	matches := shiftRegexp.FindStringSubmatch(value)
+	if matches == nil || value == "" || value == "+" || value == "-" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidShift, value)
+	}
+
+	var shift time.Duration
+	if matches[2] != "" {
+		weeks, _ := strconv.Atoi(matches[2])
+		shift += time.Duration(weeks) * 7 * 24 * time.Hour
+	}
+	if matches[3] != "" {
+		days, _ := strconv.Atoi(matches[3])
+		shift += time.Duration(days) * 24 * time.Hour
+	}
+	if matches[4] != "" {
+		rest, err := time.ParseDuration(matches[4])
+		if err != nil || rest < 0 {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidShift, value)
+		}
+		shift += rest
+	}
+
+	if matches[1] == "-" {
+		shift = -shift
+	}
+	return shift, nil
+}
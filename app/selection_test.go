@@ -0,0 +1,126 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSelect(t *testing.T) {
+	app := Application{}
+	app.Add("Campaign launch", "2023-12-16T19:42:00Z")
+	app.Add("Campaign follow up", "2024-01-16T19:42:00Z")
+	app.Add("Something else", "2023-12-17T19:42:00Z")
+	app.tweets.Tweets[0].Tags = []string{"campaign-x"}
+	app.tweets.Tweets[1].Tags = []string{"campaign-x"}
+	tweets := app.tweets.Tweets
+
+	testCases := []struct {
+		name      string
+		selection Selection
+		expected  []string
+	}{
+		{"Ids", Selection{Ids: []string{tweets[1].Id.String(), tweets[2].Id.String()[:8]}},
+			[]string{"Something else", "Campaign follow up"}},
+		{"Match and before", Selection{Filter: Filter{Match: "^Campaign", Before: "2024-01-01"}},
+			[]string{"Campaign launch"}},
+		{"Tag", Selection{Filter: Filter{Tags: []string{"campaign-x"}}},
+			[]string{"Campaign launch", "Campaign follow up"}},
+		{"Ids and filter", Selection{Ids: []string{tweets[0].Id.String(), tweets[2].Id.String()}, Filter: Filter{Tags: []string{"campaign-x"}}},
+			[]string{"Campaign launch"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := app.Select(tc.selection)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(result) != len(tc.expected) {
+				t.Fatalf("Expected %d tweets. Result: %d", len(tc.expected), len(result))
+			}
+
+			for i, tw := range result {
+				if tw.Message != tc.expected[i] {
+					t.Errorf("Expected %q. Result %q", tc.expected[i], tw.Message)
+				}
+			}
+		})
+	}
+
+	if _, err := app.Select(Selection{Filter: Filter{Limit: 1}}); !errors.Is(err, ErrNothingSelected) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNothingSelected, err)
+	}
+
+	if _, err := app.Select(Selection{Ids: []string{"ffffffff"}}); err == nil {
+		t.Fatal("Expected an error for an unknown identifier")
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	app := Application{}
+	app.Add("Tweet 1", "2024-01-16T19:42:00Z")
+
+	if err := app.Reschedule(app.tweets.Tweets[0].Id, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := parseTime("2024-01-17T19:42:00Z")
+	if result := app.tweets.Tweets[0].ScheduledTime; !result.Equal(expected) {
+		t.Fatalf("Expected: %s. Result: %s", expected, result)
+	}
+}
+
+func TestParseShift(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"+1d", 24 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"-1d", -24 * time.Hour},
+		{"+1w2d", 9 * 24 * time.Hour},
+		{"2h30m", 150 * time.Minute},
+		{"-1d12h", -36 * time.Hour},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			result, err := ParseShift(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != tc.expected {
+				t.Fatalf("Expected: %s. Result: %s", tc.expected, result)
+			}
+		})
+	}
+
+	for _, value := range []string{"", "+", "1x", "1d1w", "+1d-2h", "tomorrow"} {
+		if _, err := ParseShift(value); !errors.Is(err, ErrInvalidShift) {
+			t.Errorf("Expected %q to be invalid. Result: %v", value, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/rivo/uniseg"
+)
+
+// Sender posts messages to a social network on behalf of an account.
+type Sender interface {
+	// Check the account settings and prepare the sender to be used. No network requests are made.
+	Configure() error
+
+	// Post a new message along with the media previously uploaded using UploadMedia.
+	Post(ctx context.Context, text string, mediaIds []string) (PostResult, error)
+
+	// Post a message as a reply to the post with the specified identifier.
+	PostReply(ctx context.Context, inReplyToId string, text string, mediaIds []string) (PostResult, error)
+
+	// Upload the media file (e.g. an image) and return the identifier to be used when posting.
+	UploadMedia(ctx context.Context, filePath string) (string, error)
+
+	// Return the rate limit reported by the last API response or nil when it is not known.
+	RateLimit() *RateLimit
+}
+
+// PostResult describes a message that was posted.
+type PostResult struct {
+	Id  string // The identifier assigned by the service.
+	URL string // The URL at which the message can be viewed.
+}
+
+// The services supported by the senders.
+const (
+	ServiceTwitter  = "twitter"
+	ServiceMastodon = "mastodon"
+	ServiceBluesky  = "bluesky"
+)
+
+var (
+	ErrUnknownAccount   = errors.New("unknown account")
+	ErrAmbiguousAccount = errors.New("ambiguous account")
+	ErrUnknownService   = errors.New("unknown service")
+	ErrMessageTooLong   = errors.New("message is too long")
+)
+
+// Create a new (unconfigured) Sender for the account's service that uses the http.Client to make the API requests.
+func (app *Application) newSender(account Account, httpClient *http.Client) (Sender, error) {
+	httpClient, rateLimits := recordRateLimits(httpClient)
+
+	switch strings.ToLower(account.Service) {
+	case "", ServiceTwitter:
+		sender := newTwitterSender(account.Authentication)
+		sender.apiBaseURL = app.config.Send.APIBaseURL
+		sender.httpClient = httpClient
+		sender.rateLimits = rateLimits
+		return sender, nil
+	case ServiceMastodon:
+		sender := newMastodonSender(account.Mastodon)
+		sender.httpClient = httpClient
+		sender.rateLimits = rateLimits
+		return sender, nil
+	case ServiceBluesky:
+		sender := newBlueskySender(account.Bluesky)
+		sender.httpClient = httpClient
+		sender.rateLimits = rateLimits
+		return sender, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownService, account.Service)
+}
+
+// Return the display name of the account's service, e.g. Twitter.
+func serviceName(account Account) string {
+	switch strings.ToLower(account.Service) {
+	case "", ServiceTwitter:
+		return "Twitter"
+	case ServiceMastodon:
+		return "Mastodon"
+	case ServiceBluesky:
+		return "Bluesky"
+	}
+	return account.Service
+}
+
+// Check that the message can be posted from the account, e.g. that it is not too long.
+func validateMessage(account Account, message string) error {
+	switch strings.ToLower(account.Service) {
+	case ServiceBluesky:
+		if count := uniseg.GraphemeClusterCount(message); count > blueskyMaxGraphemes {
+			return fmt.Errorf("%w: %d characters exceeds the Bluesky limit of %d", ErrMessageTooLong, count, blueskyMaxGraphemes)
+		}
+	}
+	return nil
+}
+
+// Check that the tweet's message can be posted to each of the configured accounts it will be sent to.
+// Accounts that have not been configured are reported when the tweet is sent.
+func (app *Application) validateTargets(tw tweet.Tweet) error {
+	for _, destination := range tw.Targets() {
+		if account, err := app.account(destination.Account); err == nil {
+			if err := validateMessage(account, tw.Message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// senderPool creates the Sender for an account the first time a tweet is sent from it, so that only the
+// credentials (using the cmd:, file: or env: prefixes) of the accounts that are actually sent from are resolved.
+// The Sender, or the reason it could not be created, is kept for the rest of the send.
+type senderPool struct {
+	app        *Application
+	httpClient *http.Client
+	senders    map[string]Sender
+	errs       map[string]error
+}
+
+// Create the pool of senders used to send the tweets.
+func (app *Application) newSenderPool() (*senderPool, error) {
+	httpClient, err := newHTTPClient(app.config.Send)
+	if err != nil {
+		return nil, err
+	}
+
+	return &senderPool{
+		app:        app,
+		httpClient: httpClient,
+		senders:    make(map[string]Sender),
+		errs:       make(map[string]error),
+	}, nil
+}
+
+// Return the configured Sender for the account, resolving the account's credentials when it is first needed.
+func (pool *senderPool) sender(ctx context.Context, account Account) (Sender, error) {
+	ref := account.Ref()
+	if sender, exists := pool.senders[ref]; exists {
+		return sender, nil
+	}
+	if err, exists := pool.errs[ref]; exists {
+		return nil, err
+	}
+
+	sender, err := pool.create(ctx, account)
+	if err != nil {
+		// Sending was stopped while resolving the credentials, they will be resolved again the next time
+		if ctx.Err() == nil {
+			pool.errs[ref] = err
+		}
+		return nil, err
+	}
+	pool.senders[ref] = sender
+	return sender, nil
+}
+
+// Check that the account has been configured without resolving its credentials, e.g. for a dry run.
+func (pool *senderPool) check(account Account) error {
+	if err := pool.app.checkAccount(account); err != nil {
+		return err
+	}
+
+	sender, err := pool.app.newSender(account, pool.httpClient)
+	if err != nil {
+		return accountError(account, err)
+	}
+	return accountError(account, sender.Configure())
+}
+
+func (pool *senderPool) create(ctx context.Context, account Account) (Sender, error) {
+	if err := pool.app.checkAccount(account); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if account.Ref() == "" {
+		account.Authentication, err = resolveAuthentication(ctx, &pool.app.credentials, "send.authentication",
+			account.Authentication)
+	} else {
+		account, err = resolveAccount(ctx, &pool.app.credentials, account)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := pool.app.newSender(account, pool.httpClient)
+	if err != nil {
+		return nil, accountError(account, err)
+	}
+	if err := sender.Configure(); err != nil {
+		return nil, accountError(account, err)
+	}
+	return sender, nil
+}
+
+// Check that tweets can be sent from the account.
+// The default account (empty reference) is the Twitter account specified by send.authentication and
+// can only be left out when other accounts have been configured, in which case it can not be sent from.
+func (app *Application) checkAccount(account Account) error {
+	if account.Ref() == "" && app.config.Send.Authentication == (Authentication{}) && len(app.accounts()) > 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownAccount, "")
+	}
+	return nil
+}
+
+// Return the error prefixed by the account it is about. The errors of the default account are returned as is.
+func accountError(account Account, err error) error {
+	if err == nil || account.Ref() == "" {
+		return err
+	}
+	return fmt.Errorf("account %q: %w", account.Ref(), err)
+}
+
+// Account resolved from the configuration.
+type Account struct {
+	Service string // The service the account belongs to, e.g. mastodon.
+	Name    string // The name of the account in the configuration. Empty for the default Twitter account.
+
+	Authentication // Twitter settings.
+	Mastodon       // Mastodon settings.
+	Bluesky        // Bluesky settings.
+}
+
+// Return the reference to the account in the form of service:name, e.g. mastodon:product.
+// The empty string is returned for the default Twitter account.
+func (account Account) Ref() string {
+	if account.Name == "" {
+		return ""
+	}
+	return account.Service + ":" + account.Name
+}
+
+// Return all the configured accounts ordered by their references.
+func (app *Application) accounts() []Account {
+	var accounts []Account
+	for name, auth := range app.config.Accounts.Twitter {
+		accounts = append(accounts, Account{Service: ServiceTwitter, Name: name, Authentication: auth})
+	}
+	for name, config := range app.config.Accounts.Mastodon {
+		accounts = append(accounts, Account{Service: ServiceMastodon, Name: name, Mastodon: config})
+	}
+	for name, config := range app.config.Accounts.Bluesky {
+		accounts = append(accounts, Account{Service: ServiceBluesky, Name: name, Bluesky: config})
+	}
+
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Ref() < accounts[j].Ref()
+	})
+	return accounts
+}
+
+// Return the account matching the reference, either service:name or only the name when it is unique.
+// The empty reference is the default Twitter account.
+func (app *Application) account(ref string) (Account, error) {
+	if ref == "" {
+		return Account{Service: ServiceTwitter, Authentication: app.config.Send.Authentication}, nil
+	}
+
+	service, name, hasService := strings.Cut(ref, ":")
+	if !hasService {
+		service, name = "", ref
+	}
+
+	service = strings.ToLower(strings.TrimSpace(service))
+	switch service {
+	case "", ServiceTwitter, ServiceMastodon, ServiceBluesky:
+	default:
+		return Account{}, fmt.Errorf("%w: %q", ErrUnknownService, service)
+	}
+
+	var matches []Account
+	for _, account := range app.accounts() {
+		if (service == "" || account.Service == service) && strings.EqualFold(account.Name, strings.TrimSpace(name)) {
+			matches = append(matches, account)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Account{}, fmt.Errorf("%w: %q", ErrUnknownAccount, ref)
+	case 1:
+		return matches[0], nil
+	}
+
+	refs := make([]string, len(matches))
+	for i, account := range matches {
+		refs[i] = account.Ref()
+	}
+	return Account{}, fmt.Errorf("%w: %q could be any of %s", ErrAmbiguousAccount, ref, strings.Join(refs, ", "))
+}
+
+// The context key of the idempotency key of a post.
+type idempotencyKeyContextKey struct{}
+
+// Return a context carrying the key that identifies the post of a tweet to a destination. A sender that supports it,
+// e.g. Mastodon's Idempotency-Key header, uses it so that retrying a post that timed out does not post it twice.
+func withIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// Return the idempotency key of the post, or an empty string when there is none.
+func idempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// Upload the tweet's media and then post the tweet to the destination account using the sender.
+func post(ctx context.Context, sender Sender, tw tweet.Tweet, account string) (PostResult, error) {
+	mediaIds := make([]string, 0, len(tw.Media))
+	for _, filePath := range tw.Media {
+		id, err := sender.UploadMedia(ctx, filePath)
+		if err != nil {
+			return PostResult{}, fmt.Errorf("failed to upload %q: %w", filePath, err)
+		}
+		mediaIds = append(mediaIds, id)
+	}
+
+	// The same key is used by every attempt, the destination is included since a tweet is posted once per account
+	ctx = withIdempotencyKey(ctx, tw.Id.String()+"/"+account)
+	if tw.InReplyTo != "" {
+		return sender.PostReply(ctx, tw.InReplyTo, tw.Message, mediaIds)
+	}
+	return sender.Post(ctx, tw.Message, mediaIds)
+}
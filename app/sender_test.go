@@ -0,0 +1,286 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+func TestSenderPool(t *testing.T) {
+	app := Application{}
+	ctx := context.Background()
+
+	pool, err := app.newSenderPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The default Twitter account is required when no accounts are configured
+	if _, err := pool.sender(ctx, Account{Service: ServiceTwitter}); !errors.Is(err, ErrMissingAuth) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMissingAuth, err)
+	}
+
+	app.config.Accounts.Mastodon = map[string]Mastodon{
+		"product": {Instance: "https://mastodon.social", AccessToken: "token"},
+	}
+	app.config.Accounts.Bluesky = map[string]Bluesky{"news": {}}
+
+	account, err := app.account("product")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := pool.sender(ctx, account)
+	if _, ok := sender.(*mastodonSender); !ok || err != nil {
+		t.Fatalf("Expected the Mastodon sender. Result: %v, %v", sender, err)
+	}
+	if again, _ := pool.sender(ctx, account); again != sender {
+		t.Fatal("Expected the sender to be reused")
+	}
+
+	// The default account can not be sent from when only other accounts are configured
+	if err := pool.check(Account{Service: ServiceTwitter}); !errors.Is(err, ErrUnknownAccount) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownAccount, err)
+	}
+
+	account, err = app.account("news")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.sender(ctx, account); !errors.Is(err, ErrMissingAuth) || !strings.HasPrefix(err.Error(), `account "bluesky:news"`) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMissingAuth, err)
+	}
+}
+
+func TestAccountReferences(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Twitter = map[string]Authentication{"product": {}}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}, "support": {}}
+
+	tests := []struct {
+		ref      string
+		expected string
+		err      error
+	}{
+		{"", "", nil},
+		{"twitter:product", "twitter:product", nil},
+		{"Mastodon:Product", "mastodon:product", nil},
+		{"support", "mastodon:support", nil},
+		{"product", "", ErrAmbiguousAccount},
+		{"bluesky:product", "", ErrUnknownAccount},
+		{"unknown", "", ErrUnknownAccount},
+		{"myspace:product", "", ErrUnknownService},
+	}
+
+	for _, test := range tests {
+		account, err := app.account(test.ref)
+		if !errors.Is(err, test.err) {
+			t.Fatalf("%q: Expected error: %v. Result: %v", test.ref, test.err, err)
+		}
+		if account.Ref() != test.expected {
+			t.Fatalf("%q: Expected: %q. Result: %q", test.ref, test.expected, account.Ref())
+		}
+	}
+}
+
+func TestAddWithAccountAndMedia(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+
+	now := time.Now().Format(time.RFC3339)
+	if err := app.AddWith("Hello", now, AddOptions{Account: "unknown"}); !errors.Is(err, ErrUnknownAccount) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownAccount, err)
+	}
+
+	if err := app.AddWith("Hello", now, AddOptions{Media: []string{"missing.png"}}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected error: %q. Result: %q", os.ErrNotExist, err)
+	}
+
+	filePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(filePath, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.AddWith("Hello", now, AddOptions{Account: "product", Media: []string{filePath}, InReplyTo: "42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if tw.Account != "mastodon:product" || len(tw.Media) != 1 || tw.Media[0] != filePath || tw.InReplyTo != "42" {
+		t.Fatalf("Unexpected tweet: %v", tw)
+	}
+}
+
+func TestSendToMastodon(t *testing.T) {
+	fake := newFakeMastodon(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Accounts.Mastodon = map[string]Mastodon{
+		"product": {Instance: fake.server.URL, AccessToken: fakeMastodonToken},
+	}
+
+	filePath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(filePath, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.AddWith("Hello Mastodon", time.Now().Format(time.RFC3339),
+		AddOptions{Account: "product", Media: []string{filePath}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buffer bytes.Buffer
+	if err := app.Send(context.Background(), &buffer, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.statuses) != 1 || fake.statuses[0].Status != "Hello Mastodon" ||
+		len(fake.statuses[0].MediaIds) != 1 || len(fake.uploads) != 1 {
+		t.Fatalf("Unexpected statuses: %v, uploads: %v", fake.statuses, fake.uploads)
+	}
+
+	if !strings.Contains(buffer.String(), "Mastodon identifier: 101") {
+		t.Fatalf("Expected the identifier to be reported. Result: %q", buffer.String())
+	}
+
+	if count := len(app.tweets.Tweets); count != 0 {
+		t.Fatalf("Expected the tweet to be removed. Result: %d", count)
+	}
+}
+
+func TestAddWithDestinations(t *testing.T) {
+	app := Application{}
+	app.config.Accounts.Twitter = map[string]Authentication{"product": {}}
+	app.config.Accounts.Mastodon = map[string]Mastodon{"product": {}}
+	now := time.Now().Format(time.RFC3339)
+
+	if err := app.AddWith("Hello", now, AddOptions{Account: "twitter:product", Destinations: []string{"mastodon:product"}}); !errors.Is(err, ErrInvalidDestinations) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidDestinations, err)
+	}
+
+	if err := app.AddWith("Hello", now, AddOptions{Destinations: []string{"mastodon:product", "Mastodon:Product"}}); !errors.Is(err, ErrInvalidDestinations) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidDestinations, err)
+	}
+
+	if err := app.AddWith("Hello", now, AddOptions{Destinations: []string{"product"}}); !errors.Is(err, ErrAmbiguousAccount) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrAmbiguousAccount, err)
+	}
+
+	if err := app.AddWith("Hello", now, AddOptions{Destinations: []string{"twitter:product", "mastodon:product"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if tw.Account != "" || len(tw.Destinations) != 2 || tw.Destinations[1].Account != "mastodon:product" {
+		t.Fatalf("Unexpected tweet: %v", tw)
+	}
+}
+
+func TestSendCrossPostRetriesFailedDestinations(t *testing.T) {
+	mastodon := newFakeMastodon(t)
+	pds := newFakePDS(t)
+
+	tempFile, err := getTempFilepath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tempFile)
+
+	app := Application{}
+	app.config.Datastore.Filepath = tempFile
+	app.config.Send.Max = 10
+	app.config.Send.MaxAttempts = 3
+	app.config.Accounts.Mastodon = map[string]Mastodon{
+		"product": {Instance: mastodon.server.URL, AccessToken: fakeMastodonToken},
+	}
+	app.config.Accounts.Bluesky = map[string]Bluesky{
+		"product": {Handle: fakePDSHandle, AppPassword: "wrong", PDS: pds.server.URL},
+	}
+
+	if err := app.AddWith("Hello everyone", time.Now().Format(time.RFC3339),
+		AddOptions{Destinations: []string{"mastodon:product", "bluesky:product"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bluesky fails
+	if err := app.Send(context.Background(), io.Discard, false); !errors.Is(err, ErrDeliveryFailed) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrDeliveryFailed, err)
+	}
+
+	tw := app.tweets.Tweets[0]
+	if tw.Status != tweet.StatusScheduled || tw.Attempts != 1 ||
+		tw.Destinations[0].Status != tweet.DestinationSent || tw.Destinations[0].RemoteId != "101" ||
+		tw.Destinations[1].Status != tweet.DestinationFailed || tw.Destinations[1].Error == "" {
+		t.Fatalf("Unexpected delivery status: %v", tw.Destinations)
+	}
+
+	// The delivery status was saved
+	loaded := tweet.TweetList{}
+	if err := loaded.Load(tempFile); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Tweets[0].Destinations, tw.Destinations) {
+		t.Fatalf("Expected: %v. Result: %v", tw.Destinations, loaded.Tweets[0].Destinations)
+	}
+
+	var buffer bytes.Buffer
+	if err := app.List(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buffer.String(), "to: mastodon:product (sent: "+mastodon.server.URL+"/@product/101)\n") ||
+		!strings.Contains(buffer.String(), "to: bluesky:product (failed: ") {
+		t.Fatalf("Expected the status of each destination. Result: %q", buffer.String())
+	}
+
+	// Only Bluesky is retried
+	app.config.Accounts.Bluesky["product"] = Bluesky{Handle: fakePDSHandle, AppPassword: fakePDSPassword, PDS: pds.server.URL}
+	if err := app.Send(context.Background(), io.Discard, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mastodon.statuses) != 1 || len(pds.records) != 1 {
+		t.Fatalf("Expected a single post to each destination. Mastodon: %d, Bluesky: %d", len(mastodon.statuses), len(pds.records))
+	}
+
+	if count := len(app.tweets.Tweets); count != 0 {
+		t.Fatalf("Expected the tweet to be removed once sent to all the destinations. Result: %d", count)
+	}
+}
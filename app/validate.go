@@ -0,0 +1,327 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/logging"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+	ErrUnknownKey    = errors.New("unknown configuration key")
+)
+
+// Check the configuration values, e.g. that send.max is not negative. All the problems found are returned.
+func (config Config) Validate() []error {
+	var errs []error
+	invalid := func(key string, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, key, fmt.Sprintf(format, args...)))
+	}
+
+	counts := []struct {
+		key   string
+		value int
+	}{
+		{"send.max", config.Send.Max},
+		{"send.delay", config.Send.Delay},
+		{"send.max_attempts", config.Send.MaxAttempts},
+		{"send.expire_after", config.Send.ExpireAfter},
+		{"send.timeout", config.Send.Timeout},
+		{"send.deadline", config.Send.Deadline},
+		{"log.max_size", config.Log.MaxSize},
+		{"log.max_backups", config.Log.MaxBackups},
+	}
+	for _, count := range counts {
+		if count.value < 0 {
+			invalid(count.key, "must not be negative, got %d", count.value)
+		}
+	}
+
+	if config.Datastore.Filepath == "" {
+		invalid("datastore.filepath", "must not be empty")
+	}
+	if config.Lockfile == "" {
+		invalid("lockfile", "must not be empty")
+	}
+
+	if config.Log.Level != "" {
+		if _, err := logging.ParseLevel(config.Log.Level); err != nil {
+			invalid("log.level", "%s", err)
+		}
+	}
+	if _, err := logging.ParseFormat(config.Log.Format); err != nil {
+		invalid("log.format", "%s", err)
+	}
+
+	for key, value := range map[string]string{"send.proxy": config.Send.Proxy, "send.api_base_url": config.Send.APIBaseURL} {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			invalid(key, "expected a URL, e.g. http://localhost:8080, got %q", value)
+		}
+	}
+
+	if config.Serve.Listen != "" {
+		if _, _, err := net.SplitHostPort(config.Serve.Listen); err != nil {
+			invalid("serve.listen", "expected an address, e.g. 127.0.0.1:8080, got %q", config.Serve.Listen)
+		}
+	}
+
+	for i, hook := range config.Hooks {
+		if _, err := hook.hook(); err != nil {
+			invalid(fmt.Sprintf("hooks[%d]", i), "%s", err)
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Error() < errs[j].Error()
+	})
+	return errs
+}
+
+// Check that the files the app writes to (e.g. the datastore and lock file) can be written and that the files
+// it reads (e.g. the CA bundle) can be read. Files are created and removed in the directories to check this.
+func (config Config) CheckPaths() []error {
+	var errs []error
+
+	writable := []struct {
+		key  string
+		path string
+	}{
+		{"datastore.filepath", config.Datastore.Filepath},
+		{"lockfile", config.Lockfile},
+		{"log.file", config.Log.File},
+	}
+	for _, file := range writable {
+		if file.path == "" {
+			continue
+		}
+		if err := checkWritable(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %q is not writable: %s", ErrInvalidConfig, file.key, file.path, err))
+		}
+	}
+
+	readable := []struct {
+		key  string
+		path string
+	}{
+		{"send.ca_bundle", config.Send.CABundle},
+		{"secrets.key_file", config.Secrets.KeyFile},
+	}
+	for _, file := range readable {
+		if file.path == "" {
+			continue
+		}
+		if f, err := os.Open(file.path); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: %s", ErrInvalidConfig, file.key, err))
+		} else {
+			f.Close()
+		}
+	}
+
+	return errs
+}
+
+// Check that the file can be written without changing it, or created when it does not exist yet.
+func checkWritable(filePath string) error {
+	file, err := os.OpenFile(filePath, os.O_WRONLY, 0)
+	if err == nil {
+		return file.Close()
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	// The missing directories are created when the file is written, e.g. $XDG_DATA_HOME/ajtweet
+	dir := filepath.Dir(filePath)
+	for !fileExists(dir) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+
+	temp, err := os.CreateTemp(dir, ".ajtweet-check-*")
+	if err != nil {
+		return err
+	}
+	temp.Close()
+	return os.Remove(temp.Name())
+}
+
+// Check if the key is a configuration value, e.g. send.max or accounts.mastodon.product.access_token
+func IsKnownKey(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasPrefix(key, profilesKey+".") {
+		if key = profileKey(key); strings.HasPrefix(key, profilesKey+".") {
+			// Profiles can't be nested
+			return false
+		}
+	}
+
+	for _, configKey := range ConfigKeys() {
+		if configKey.Key == key {
+			return true
+		}
+	}
+
+	if key == "hooks" {
+		return true
+	}
+
+	// accounts.service.name.field
+	parts := strings.SplitN(key, ".", 4)
+	if len(parts) != 4 || parts[0] != "accounts" || parts[2] == "" {
+		return false
+	}
+
+	var fields []ConfigKey
+	switch parts[1] {
+	case ServiceTwitter:
+		fields = configKeys(reflect.ValueOf(Authentication{}), "")
+	case ServiceMastodon:
+		fields = configKeys(reflect.ValueOf(Mastodon{}), "")
+	case ServiceBluesky:
+		fields = configKeys(reflect.ValueOf(Bluesky{}), "")
+	}
+	for _, field := range fields {
+		if field.Key == parts[3] {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the keys in the configuration that are not used by the app (e.g. misspelled keys), sorted.
+func UnknownKeys(v *viper.Viper) []string {
+	var unknown []string
+	for _, key := range v.AllKeys() {
+		if !IsKnownKey(key) {
+			unknown = append(unknown, key)
+		}
+	}
+
+	hookFields := configKeys(reflect.ValueOf(Hook{}), "")
+	if list, ok := v.Get("hooks").([]interface{}); ok {
+		for i, item := range list {
+			var fields []string
+			switch entry := item.(type) {
+			case map[string]interface{}:
+				for field := range entry {
+					fields = append(fields, field)
+				}
+			case map[interface{}]interface{}:
+				for field := range entry {
+					fields = append(fields, fmt.Sprint(field))
+				}
+			}
+
+			for _, field := range fields {
+				if !containsKey(hookFields, strings.ToLower(field)) {
+					unknown = append(unknown, fmt.Sprintf("hooks[%d].%s", i, field))
+				}
+			}
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+func containsKey(keys []ConfigKey, key string) bool {
+	for _, configKey := range keys {
+		if configKey.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Check that the configuration values have the expected types, e.g. that send.max is a number.
+func CheckTypes(v *viper.Viper) []error {
+	var errs []error
+	for _, key := range ConfigKeys() {
+		if !v.IsSet(key.Key) {
+			continue
+		}
+		value := v.Get(key.Key)
+		if err := checkType(key.Type, value); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s: expected a value of type %s, got %q", ErrInvalidConfig, key.Key, key.Type,
+				MaskCredential(key.Key, fmt.Sprint(value))))
+		}
+	}
+	return errs
+}
+
+func checkType(typ string, value interface{}) error {
+	switch typ {
+	case typeInt:
+		switch value := value.(type) {
+		case int, int32, int64, uint, uint32, uint64:
+			return nil
+		case float64:
+			if value == math.Trunc(value) {
+				return nil
+			}
+		case string:
+			_, err := strconv.Atoi(value)
+			return err
+		}
+		return ErrInvalidConfig
+
+	case typeBool:
+		switch value := value.(type) {
+		case bool:
+			return nil
+		case string:
+			_, err := strconv.ParseBool(value)
+			return err
+		}
+		return ErrInvalidConfig
+
+	case typeList:
+		switch value.(type) {
+		case []interface{}, []string, string:
+			return nil
+		}
+		return ErrInvalidConfig
+
+	case typeString:
+		switch value.(type) {
+		case []interface{}, map[string]interface{}:
+			return ErrInvalidConfig
+		}
+	}
+	return nil
+}
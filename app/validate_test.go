@@ -0,0 +1,156 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestValidate(t *testing.T) {
+	if errs := NewConfig().Validate(); len(errs) != 0 {
+		t.Fatalf("Expected the default configuration to be valid. Result: %v", errs)
+	}
+
+	tests := []struct {
+		name   string
+		change func(config *Config)
+		key    string
+	}{
+		{"negative max", func(c *Config) { c.Send.Max = -1 }, "send.max"},
+		{"negative delay", func(c *Config) { c.Send.Delay = -5 }, "send.delay"},
+		{"negative timeout", func(c *Config) { c.Send.Timeout = -1 }, "send.timeout"},
+		{"empty datastore", func(c *Config) { c.Datastore.Filepath = "" }, "datastore.filepath"},
+		{"empty lockfile", func(c *Config) { c.Lockfile = "" }, "lockfile"},
+		{"log level", func(c *Config) { c.Log.Level = "loud" }, "log.level"},
+		{"log format", func(c *Config) { c.Log.Format = "xml" }, "log.format"},
+		{"proxy", func(c *Config) { c.Send.Proxy = "proxy:3128" }, "send.proxy"},
+		{"listen", func(c *Config) { c.Serve.Listen = "8080" }, "serve.listen"},
+		{"hook", func(c *Config) { c.Hooks = []Hook{{Event: "after_tweet", URL: "https://example.com"}} }, "hooks[0]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := NewConfig()
+			test.change(&config)
+
+			errs := config.Validate()
+			if len(errs) != 1 || !errors.Is(errs[0], ErrInvalidConfig) || !strings.Contains(errs[0].Error(), test.key+":") {
+				t.Fatalf("Expected the problem with %s to be reported. Result: %v", test.key, errs)
+			}
+		})
+	}
+}
+
+func TestCheckPaths(t *testing.T) {
+	dir := t.TempDir()
+
+	config := NewConfig()
+	config.Datastore.Filepath = filepath.Join(dir, "tweets.json")
+	config.Lockfile = filepath.Join(dir, "ajtweet.lock")
+	if errs := config.CheckPaths(); len(errs) != 0 {
+		t.Fatalf("Expected the paths to be valid. Result: %v", errs)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("Expected no files to be left behind. Result: %v", entries)
+	}
+
+	// The missing directories are created when needed, but not inside a file
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.WriteFile(blocked, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	config.Datastore.Filepath = filepath.Join(blocked, "tweets.json")
+	config.Send.CABundle = filepath.Join(dir, "ca.pem")
+	errs := config.CheckPaths()
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "datastore.filepath") || !strings.Contains(errs[1].Error(), "send.ca_bundle") {
+		t.Fatalf("Expected the datastore and CA bundle to be reported. Result: %v", errs)
+	}
+}
+
+func TestIsKnownKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		known bool
+	}{
+		{"send.max", true},
+		{"Send.Max", true},
+		{"lockfile", true},
+		{"hooks", true},
+		{"accounts.twitter.product.oauth1.token", true},
+		{"accounts.mastodon.product.access_token", true},
+		{"accounts.bluesky.news.pds", true},
+		{"sned.max", false},
+		{"send", false},
+		{"accounts.mastodon.product.app_password", false},
+		{"accounts.myspace.product.token", false},
+	}
+
+	for _, test := range tests {
+		if known := IsKnownKey(test.key); known != test.known {
+			t.Fatalf("Expected %v for %q. Result: %v", test.known, test.key, known)
+		}
+	}
+}
+
+func TestUnknownKeysAndTypes(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	data := `
+send:
+  max: ten
+  delay: 2
+  mx_attempts: 3
+approval:
+  required: maybe
+accounts:
+  mastodon:
+    product:
+      access_token: token
+      instanse: https://mastodon.social
+hooks:
+  - event: after_send
+    url: https://example.com
+    secrte: secret
+`
+	if err := v.ReadConfig(strings.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"accounts.mastodon.product.instanse", "hooks[0].secrte", "send.mx_attempts"}
+	if unknown := UnknownKeys(v); !reflect.DeepEqual(unknown, expected) {
+		t.Fatalf("Expected %v. Result: %v", expected, unknown)
+	}
+
+	errs := CheckTypes(v)
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "send.max") || !strings.Contains(errs[1].Error(), "approval.required") {
+		t.Fatalf("Expected send.max and approval.required to be reported. Result: %v", errs)
+	}
+}
@@ -27,18 +27,26 @@ import (
 	"os"
 	"time"
 
+	"github.com/andrejacobs/ajtweet-cli/app"
 	"github.com/spf13/cobra"
 )
 
 var (
 	scheduledAtFlag string
+	addDraftFlag    bool
+	addApprovalFlag bool
+	addAccountFlag  string
+	addToFlag       []string
+	addMediaFlag    []string
+	addReplyToFlag  string
 )
 
 // addCmd represents the add command
 var addCmd = &cobra.Command{
 	Use:   "add",
-	Short: "Add a new tweet to be sent to Twitter",
-	Long: `Add a new tweet to be sent to Twitter at a preferred scheduled time.
+	Short: "Add a new tweet to be sent to Twitter, Mastodon or Bluesky",
+	Long: `Add a new tweet to be sent to Twitter, Mastodon or Bluesky at a preferred
+scheduled time.
 
 -t, --scheduledAt specifies the preferred time in the RFC3339 format at which
 you would like the tweet to be sent at. If no time is specified then the
@@ -47,6 +55,32 @@ NOTE: The actual time the tweet will be sent depends entirely on when the send
 command is run. Hence why this is the preferred time and not "guaranteed time".
 
 Example RFC3339 format: YYYY-MM-DDTHH:mm:ssZ, e.g. 2022-05-16T19:39Z
+
+-d, --draft adds the tweet as a draft. Drafts will not be sent until they are
+resumed using the resume command.
+
+--account specifies the account, as configured in the accounts section of
+the configuration, from which the tweet will be sent. Accounts are referenced
+as service:name (e.g. mastodon:product) or only by name when the name is
+unique. The Twitter account configured in send.authentication is used when no
+account is specified.
+
+--to cross-posts the tweet to each of the specified accounts. Can be repeated.
+The delivery to each account is tracked separately and when sending to some of
+the accounts fails, only those accounts will be retried.
+
+--media attaches a media file (e.g. an image) to the tweet. Can be repeated.
+The file is only uploaded when the tweet is sent and thus needs to exist until
+then.
+
+--reply-to specifies the identifier of the post, on the account's service,
+that the tweet is a reply to, e.g. the at:// URI of a Bluesky post.
+
+Bluesky posts are limited to 300 characters (grapheme clusters).
+
+--needs-approval adds the tweet pending approval. The tweet will not be sent
+until it is approved using the approve command. All new tweets need approval
+when approval.required is set in the configuration.
 	
 Tweets are stored as per the application's configuration. Please see the 
 main help section for more details (ajtweet help)
@@ -58,6 +92,18 @@ Examples:
 
  ajtweet add --scheduledAt "2032-05-16T19:42:00Z" "Send this tweet a year from now"
     Add a tweet to be sent at the preferred scheduled time.
+
+ ajtweet add --draft "Still working on this one"
+    Add a tweet that will not be sent until it is resumed.
+
+ ajtweet add --needs-approval "Our new product launches today!"
+    Add a tweet that will not be sent until it is approved.
+
+ ajtweet add --account mastodon:product --media ./launch.png "Our new product launches today!"
+    Add a tweet with an image to be sent from the Mastodon account named product.
+
+ ajtweet add --to twitter:product --to mastodon:product "Launch day!"
+    Add a tweet to be cross-posted to Twitter and Mastodon.
 	`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -66,7 +112,14 @@ Examples:
 			scheduledAtFlag = time.Now().Format(time.RFC3339)
 		}
 
-		if err := application.Add(args[0], scheduledAtFlag); err != nil {
+		if err := application.AddWith(args[0], scheduledAtFlag, app.AddOptions{
+			Draft:         addDraftFlag,
+			NeedsApproval: addApprovalFlag,
+			Account:       addAccountFlag,
+			Destinations:  addToFlag,
+			Media:         addMediaFlag,
+			InReplyTo:     addReplyToFlag,
+		}); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to add tweet. Error: %s\n", err)
 			cleanupAndExit(1)
 		}
@@ -82,4 +135,10 @@ func init() {
 	rootCmd.AddCommand(addCmd)
 
 	addCmd.Flags().StringVarP(&scheduledAtFlag, "scheduledAt", "t", "", "Scheduled date time according to RFC3339 standard")
+	addCmd.Flags().BoolVarP(&addDraftFlag, "draft", "d", false, "Add the tweet as a draft")
+	addCmd.Flags().BoolVar(&addApprovalFlag, "needs-approval", false, "The tweet needs to be approved before it will be sent")
+	addCmd.Flags().StringVar(&addAccountFlag, "account", "", "Account to send the tweet from, e.g. mastodon:product")
+	addCmd.Flags().StringArrayVar(&addToFlag, "to", nil, "Account to cross-post the tweet to (can be repeated)")
+	addCmd.Flags().StringArrayVar(&addMediaFlag, "media", nil, "Media file to attach (can be repeated)")
+	addCmd.Flags().StringVar(&addReplyToFlag, "reply-to", "", "Identifier of the post to reply to")
 }
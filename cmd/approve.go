@@ -0,0 +1,107 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	approveAsFlag     string
+	approveDryRunFlag bool
+	approveYesFlag    bool
+	approveFilter     app.Filter
+)
+
+// approveCmd represents the approve command
+var approveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve tweets to be sent",
+	Long: `Approve tweets that are pending approval so that they will be sent.
+
+--as specifies the name of the person approving the tweets. The name and the
+time of the approval are recorded with each tweet. When approval.approvers is
+set in the configuration then only the listed people may approve tweets.
+
+Tweets are selected in the same way as the delete command, either by
+identifiers (or unique prefixes) passed as arguments and/or by using the
+same filters as the list command. When filters are used, the matching
+tweets are displayed and you will be prompted to confirm. Use -y, --yes to
+skip the prompt.
+
+You may also simulate the process by running the command in the dry run
+mode (-n, --dry-run).
+
+Examples:
+
+ ajtweet list --pending
+    Display the tweets waiting to be approved.
+
+ ajtweet approve 28cf75a --as alice
+    Approve the tweet of which the identifier starts with 28cf75a.
+
+ ajtweet approve --status pending --tag campaign-x --as alice
+    Approve all the pending tweets of the campaign.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if approveAsFlag == "" {
+			return errors.New("--as is required")
+		}
+		if len(args) < 1 && approveFilter.IsEmpty() {
+			return errors.New("expected identifiers to be passed as arguments or filters to be specified")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		tweets := selectOrExit(app.Selection{Ids: args, Filter: approveFilter}, approveYesFlag)
+
+		for _, tw := range tweets {
+			if err := application.Approve(tw.Id, approveAsFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to approve the tweet with identifier: %q. Error: %s\n", tw.Id, err)
+				cleanupAndExit(1)
+			}
+
+			fmt.Fprintf(os.Stdout, "Approving tweet with identifier: %q\n", tw.Id.String())
+		}
+
+		if !approveDryRunFlag {
+			if err := application.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the changes. Error: %s\n", err)
+				cleanupAndExit(2)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(approveCmd)
+
+	approveCmd.Flags().StringVar(&approveAsFlag, "as", "", "Name of the person approving the tweets")
+	approveCmd.Flags().BoolVarP(&approveDryRunFlag, "dry-run", "n", false, "Tweets will not be approved")
+	approveCmd.Flags().BoolVarP(&approveYesFlag, "yes", "y", false, "Do not prompt for confirmation")
+	addFilterFlags(approveCmd, &approveFilter)
+}
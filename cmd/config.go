@@ -0,0 +1,406 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	configInitOutputFlag      string
+	configInitForceFlag       bool
+	configInitInteractiveFlag bool
+	configInitOptions         = app.DefaultInitOptions()
+	configShowEffectiveFlag   bool
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, change, check and display the configuration",
+	Long: `Create, change, check and display the configuration.
+
+The configuration is checked each time ajtweet is run and the command is
+stopped when a value is invalid, e.g. a negative send.max. Unknown keys,
+e.g. a misspelled key, are reported as warnings. The config commands work
+with an invalid configuration so that it can be fixed.
+
+Examples:
+
+ ajtweet config init
+ ajtweet config init --interactive
+ ajtweet config get send.max
+ ajtweet config set send.max 20
+ ajtweet config validate
+ ajtweet config show --effective
+ ajtweet config env
+`,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true", annotationSkipValidation: "true"},
+	// Only the configuration is used which does not need the lock
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a new configuration file with comments describing the values",
+	Long: `Create a new YAML configuration file with comments describing the values.
+
+The file is written to --output, the --config file or ./.ajtweet.yaml (in
+that order). An existing file is only replaced when --force is specified.
+
+The values can be specified using the flags or by answering the questions
+when --interactive is specified. The Twitter credentials are left out (to be
+read from the environment) unless --secrets is specified, in which case they
+are referenced from the encrypted secrets file (see ajtweet secrets).
+
+Examples:
+
+ ajtweet config init
+ ajtweet config init --output /etc/ajtweet/.ajtweet.yaml --datastore /var/lib/ajtweet/tweets.json
+ ajtweet config init --interactive
+`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationConfigOptional: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath := configInitOutputFlag
+		if filePath == "" {
+			filePath = cfgFile
+		}
+		if filePath == "" {
+			filePath = ".ajtweet.yaml"
+		}
+
+		if _, err := os.Stat(filePath); err == nil && !configInitForceFlag {
+			fmt.Fprintf(os.Stderr, "Failed to create the configuration file. Error: %q already exists, use --force to replace it\n", filePath)
+			cleanupAndExit(1)
+		}
+
+		options := configInitOptions
+		if configInitInteractiveFlag {
+			options = promptInitOptions(options)
+		}
+
+		data, err := app.InitialConfig(options)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create the configuration file. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if err := os.WriteFile(filePath, data, 0600); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create the configuration file. Error: %s\n", err)
+			cleanupAndExit(2)
+		}
+
+		fmt.Fprintf(os.Stdout, "Created the configuration file: %s\n", filePath)
+		if options.Secrets {
+			fmt.Fprintln(os.Stdout, `Store the Twitter credentials using "ajtweet secrets set twitter-api-key" etc.`)
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get key",
+	Short: "Display the effective value of a configuration key",
+	Long: `Display the effective value of a configuration key, taking the flags,
+environment variables, env files, configuration file and default values into
+account. Use "ajtweet doctor" to display where each value came from.
+
+Credentials are displayed as they are specified, e.g. secret://name.
+
+Examples:
+
+ ajtweet config get send.max
+ ajtweet config get accounts.mastodon.product.instance
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		key := strings.ToLower(args[0])
+		if !app.IsKnownKey(key) {
+			fmt.Fprintf(os.Stderr, "Failed to get the value. Error: %s: %q\n", app.ErrUnknownKey, key)
+			cleanupAndExit(1)
+		}
+
+		value, _ := configValueSource(key, findConfigKey(key))
+		fmt.Fprintln(os.Stdout, formatConfigValue(value))
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set key value",
+	Short: "Change the value of a configuration key in the configuration file",
+	Long: `Change the value of a configuration key in the YAML configuration file.
+The comments and the order of the other values in the file are kept.
+
+The value is checked against the type of the key, e.g. send.max must be a
+number. Lists are comma separated.
+
+Credentials are written as they are specified, please consider storing them
+in the encrypted secrets file and setting a reference instead, e.g.
+secret://twitter-api-key.
+
+Examples:
+
+ ajtweet config set send.max 20
+ ajtweet config set approval.approvers alice,bob
+ ajtweet config set send.authentication.api_key secret://twitter-api-key
+`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath := viper.ConfigFileUsed()
+		key := strings.ToLower(args[0])
+
+		if err := app.SetConfigValue(filePath, key, args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set the value. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		fmt.Fprintf(os.Stdout, "Set %s = %s in %s\n", key, app.MaskCredential(key, args[1]), filePath)
+
+		if _, source := configValueSource(key, findConfigKey(key)); !strings.HasPrefix(source, "config file") && source != "default" {
+			fmt.Fprintf(os.Stdout, "Note: the value is overridden by the %s\n", source)
+		}
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the configuration",
+	Long: `Check the configuration for:
+  * Unknown keys, e.g. misspelled keys.
+  * Values of the wrong type, e.g. send.max: ten
+  * Invalid values, e.g. a negative send.max or an unknown log.level.
+  * Files that can't be written (datastore.filepath, lockfile, log.file) or
+    read (send.ca_bundle, secrets.key_file).
+  * Environment variables of the wrong type, e.g. AJTWEET_SEND_MAX=ten
+
+The exit code is 1 when any problems were found.
+
+Examples:
+
+ ajtweet config validate
+ ajtweet --config /etc/ajtweet/.ajtweet.yaml config validate
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var problems []error
+		if err := app.ValidateEnv(); err != nil {
+			problems = append(problems, err)
+		}
+		problems = append(problems, app.CheckTypes(viper.GetViper())...)
+		for _, key := range app.UnknownKeys(viper.GetViper()) {
+			problems = append(problems, fmt.Errorf("%w: %q", app.ErrUnknownKey, key))
+		}
+
+		config := application.Config()
+		problems = append(problems, config.Validate()...)
+		problems = append(problems, config.CheckPaths()...)
+
+		if len(problems) == 0 {
+			fmt.Fprintf(os.Stdout, "The configuration is valid: %s\n", viper.ConfigFileUsed())
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "Found %d problem(s) in the configuration: %s\n", len(problems), viper.ConfigFileUsed())
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+		}
+		cleanupAndExit(1)
+	},
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Display the configuration with the credentials masked",
+	Long: `Display the YAML configuration file with the credentials masked.
+
+--effective displays the configuration used by ajtweet instead, i.e. the
+configuration file combined with the default values, environment variables,
+env files and flags.
+
+Credentials are masked unless they are references, e.g. secret://name or
+cmd:pass show twitter/api-key.
+
+Examples:
+
+ ajtweet config show
+ ajtweet config show --effective
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var data []byte
+		var err error
+
+		if configShowEffectiveFlag {
+			data, err = application.Config().Masked().EncodeYAML()
+		} else {
+			filePath := viper.ConfigFileUsed()
+			switch strings.ToLower(filepath.Ext(filePath)) {
+			case ".yaml", ".yml", "":
+			default:
+				err = fmt.Errorf("%w: %q, only YAML files can be displayed, use --effective instead", app.ErrUnsupportedConfig, filePath)
+			}
+
+			if err == nil {
+				if data, err = os.ReadFile(filePath); err == nil {
+					data, err = app.MaskConfigFile(data)
+				}
+			}
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to display the configuration. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+		os.Stdout.Write(data)
+	},
+}
+
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the environment variables that override the configuration values",
+	Long: `List the environment variables that override the configuration values
+along with the type and default value of each.
+
+The name of the variable is the configuration path in upper case with the
+AJTWEET_ prefix and the dots replaced by underscores, e.g. AJTWEET_SEND_MAX
+for send.max. Lists are comma separated, e.g.
+
+    AJTWEET_APPROVAL_APPROVERS=alice,bob
+
+Values that can only be specified in the configuration file (e.g. the
+credentials of the accounts) can also be overridden using the same naming
+convention once they are present in the file, e.g.
+AJTWEET_ACCOUNTS_MASTODON_PRODUCT_ACCESS_TOKEN.
+
+Examples:
+
+ ajtweet config env
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ENVIRONMENT\tKEY\tTYPE\tDEFAULT\tALIASES")
+		for _, key := range app.ConfigKeys() {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.Env, key.Key, key.Type, key.Default, strings.Join(key.Aliases, ", "))
+		}
+		w.Flush()
+	},
+}
+
+// Return the description of the configuration key or the zero value when it is only found in the config file.
+func findConfigKey(key string) app.ConfigKey {
+	for _, configKey := range app.ConfigKeys() {
+		if configKey.Key == key {
+			return configKey
+		}
+	}
+	return app.ConfigKey{}
+}
+
+// Format the configuration value for display, lists are comma separated.
+func formatConfigValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(value, ",")
+	case []interface{}:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Ask for each of the values, keeping the current value when nothing is entered.
+func promptInitOptions(options app.InitOptions) app.InitOptions {
+	reader := bufio.NewReader(os.Stdin)
+	prompt := func(question string, current string) string {
+		fmt.Fprintf(os.Stdout, "%s [%s]: ", question, current)
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Fprintf(os.Stderr, "Failed to read the answer. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+		return current
+	}
+	promptInt := func(question string, current int) int {
+		for {
+			value, err := strconv.Atoi(prompt(question, strconv.Itoa(current)))
+			if err == nil && value >= 0 {
+				return value
+			}
+			fmt.Fprintln(os.Stdout, "Please enter a number that is not negative.")
+		}
+	}
+
+	options.Datastore = prompt("File path of where the tweets are stored", options.Datastore)
+	options.Lockfile = prompt("File path of the lock file", options.Lockfile)
+	options.Max = promptInt("The maximum number of tweets to send each time", options.Max)
+	options.Delay = promptInt("The number of seconds to wait after each tweet", options.Delay)
+
+	secrets := "n"
+	if options.Secrets {
+		secrets = "y"
+	}
+	answer := strings.ToLower(prompt("Store the Twitter credentials in the encrypted secrets file? (y/n)", secrets))
+	options.Secrets = answer == "y" || answer == "yes"
+	return options
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configEnvCmd)
+
+	configInitCmd.Flags().StringVarP(&configInitOutputFlag, "output", "o", "", "The path of the configuration file to create (default is the --config file or ./.ajtweet.yaml)")
+	configInitCmd.Flags().BoolVarP(&configInitForceFlag, "force", "f", false, "Replace the configuration file when it already exists")
+	configInitCmd.Flags().BoolVarP(&configInitInteractiveFlag, "interactive", "i", false, "Ask for each of the values")
+	configInitCmd.Flags().StringVar(&configInitOptions.Datastore, "datastore", configInitOptions.Datastore, "File path of where the tweets are stored")
+	configInitCmd.Flags().StringVar(&configInitOptions.Lockfile, "lockfile", configInitOptions.Lockfile, "File path of the lock file")
+	configInitCmd.Flags().IntVar(&configInitOptions.Max, "max", configInitOptions.Max, "The maximum number of tweets to send each time")
+	configInitCmd.Flags().IntVar(&configInitOptions.Delay, "delay", configInitOptions.Delay, "The number of seconds to wait after each tweet")
+	configInitCmd.Flags().BoolVar(&configInitOptions.Secrets, "secrets", false, "Reference the Twitter credentials in the encrypted secrets file")
+
+	configShowCmd.Flags().BoolVar(&configShowEffectiveFlag, "effective", false, "Display the configuration used by ajtweet (including the defaults, environment and flags)")
+}
@@ -0,0 +1,182 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	daemonIntervalFlag time.Duration
+	daemonListenFlag   string
+	daemonMetricsFlag  string
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep sending the scheduled tweets and serve the metrics",
+	Long: `Keep sending the scheduled tweets and serve the metrics.
+
+Instead of running "ajtweet send" from cron, the daemon checks for the tweets
+that are due every --interval (default 1m) and sends them exactly like the
+send command does (see ajtweet send --help).
+
+The lock is only held while sending, so the other commands (e.g. add and
+list) can be used while the daemon is running. The tweets are reloaded from
+the datastore before each send to pick up their changes. When the lock is
+held by another instance the send is skipped until the next interval.
+
+Metrics:
+ The metrics are served in the Prometheus text format on /metrics when
+ --listen address is specified and can also be written to a file for the
+ node_exporter textfile collector after each send using --metrics-textfile.
+
+   ajtweet_queue_tweets{status}                 Tweets in the datastore by status.
+   ajtweet_due_tweets                           Tweets that are due but not sent yet.
+   ajtweet_oldest_overdue_seconds               Age of the oldest due tweet.
+   ajtweet_sends_total{outcome,service}         Sends by outcome (sent, failed,
+                                                skipped, dry-run or expired).
+   ajtweet_api_request_duration_seconds{service}
+                                                Histogram of the API latency.
+   ajtweet_rate_limit_remaining{account,service}
+                                                Requests remaining in the rate limit
+                                                window reported by the service.
+   ajtweet_last_send_timestamp_seconds          Time the last send finished.
+
+Pressing Ctrl+C (SIGINT) or SIGTERM stops the daemon gracefully, a send in
+progress is stopped the same way as the send command. A second signal exits
+immediately.
+
+Examples:
+ ajtweet daemon
+ ajtweet daemon --interval 5m --listen localhost:9090
+ ajtweet daemon --metrics-textfile /var/lib/node_exporter/textfile/ajtweet.prom
+`,
+	Args: cobra.NoArgs,
+	// The lock is acquired for each send instead of for as long as the daemon is running
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		handleSignals(true)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if daemonIntervalFlag <= 0 {
+			fmt.Fprintf(os.Stderr, "Failed to start the daemon. Error: the interval must be greater than 0\n")
+			cleanupAndExit(1)
+		}
+
+		metrics := app.NewMetrics()
+		application.SetMetrics(metrics)
+
+		var server *http.Server
+		if daemonListenFlag != "" {
+			listener, err := net.Listen("tcp", daemonListenFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to start the metrics server. Error: %s\n", err)
+				cleanupAndExit(1)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Registry().Handler())
+			server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+			fmt.Fprintf(os.Stdout, "Serving the metrics on http://%s/metrics\n", listener.Addr())
+			go func() {
+				if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					fmt.Fprintf(os.Stderr, "Failed to serve the metrics. Error: %s\n", err)
+					cancelAppContext()
+				}
+			}()
+		}
+
+		for {
+			daemonSend(metrics)
+
+			timer := time.NewTimer(daemonIntervalFlag)
+			select {
+			case <-appContext.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+
+			if appContext.Err() != nil {
+				break
+			}
+		}
+
+		if server != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(ctx)
+		}
+	},
+}
+
+// Send the tweets that are due while holding the lock.
+// Errors are reported and the daemon keeps running so that the tweets will be retried the next time.
+func daemonSend(metrics *app.Metrics) {
+	if appContext.Err() != nil {
+		return
+	}
+
+	if err := application.AcquireLock(); err != nil {
+		fmt.Fprintf(os.Stderr, "Skipping the send. Error: %s\n", err)
+		return
+	}
+	hasLock = true
+
+	defer func() {
+		if err := application.ReleaseLock(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to release the lock. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+		hasLock = false
+	}()
+
+	if err := application.Reload(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load the tweets. Error: %s\n", err)
+		return
+	}
+
+	if err := application.Send(appContext, os.Stdout, false); err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Failed to send. Error: %s\n", err)
+	}
+
+	writeMetricsTextfile(metrics, daemonMetricsFlag)
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().DurationVar(&daemonIntervalFlag, "interval", time.Minute, "How often to check for the tweets that are due")
+	daemonCmd.Flags().StringVar(&daemonListenFlag, "listen", "", "Serve the metrics on /metrics at this address, e.g. localhost:9090")
+	daemonCmd.Flags().StringVar(&daemonMetricsFlag, "metrics-textfile", "", "Write the metrics in the Prometheus text format to this file after each send")
+}
@@ -28,12 +28,16 @@ import (
 	"os"
 	"time"
 
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
 	"github.com/spf13/cobra"
 )
 
 var (
 	deleteDryRunFlag bool
 	deleteAllFlag    bool
+	deleteYesFlag    bool
+	deleteFilter     app.Filter
 )
 
 // deleteCmd represents the delete command
@@ -42,7 +46,15 @@ var deleteCmd = &cobra.Command{
 	Short: "Delete scheduled tweets",
 	Long: `Delete scheduled tweets
 
-Each argument must match a tweet identifier in the scheduled list.
+Each argument must match a tweet identifier in the scheduled list, or a
+unique prefix of one (at least 4 characters), e.g. as displayed by the list
+command.
+
+Tweets can also be selected using the same filters as the list command, e.g.
+--match, --before, --after, --tag and --account. When filters are used, the
+matching tweets are displayed and you will be prompted to confirm the
+deletion by repeating a random string. Use -y, --yes to skip the prompt,
+e.g. when running from a script.
 
 You may also simulate the deletion process by running the command
 in the dry run mode (-n, --dry-run).
@@ -52,51 +64,56 @@ Examples:
  ajtweet delete "28cf75a1-e7b3-4401-a878-4362bdc4befe" "a2fdb340-0b61-4a89-b52e-82deae2e3aa8"
     Delete two tweets with the specified identifiers.
 
+ ajtweet delete 28cf75a
+    Delete the tweet of which the identifier starts with 28cf75a.
+
  ajtweet delete --dry-run "28cf75a1-e7b3-4401-a878-4362bdc4befe"
     Simulate a delete by running in dry run mode.
 
+ ajtweet delete --match "^Launch" --before 2024-01-01 --tag campaign-x
+    Delete the tweets matching all of the filters.
+
  ajtweet delete --all
     Delete all the scheduled tweets.
 `,
 	Args: func(cmd *cobra.Command, args []string) error {
 		argCount := len(args)
 		if deleteAllFlag {
-			if argCount != 0 {
-				return errors.New("--all Does not expect arguments to be passed")
+			if argCount != 0 || !deleteFilter.IsEmpty() {
+				return errors.New("--all Does not expect arguments or filters to be passed")
 			}
-		} else if argCount < 1 {
-			return errors.New("expected identifiers to be passed as arguments")
+		} else if argCount < 1 && deleteFilter.IsEmpty() {
+			return errors.New("expected identifiers to be passed as arguments or filters to be specified")
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if deleteAllFlag {
-			// Confirm with the user
-			expectedConfirm := randomString(5 + rand.Intn(5))
-			fmt.Fprintf(os.Stdout, "Please confirm by entering: %s\n> ", expectedConfirm)
-
-			var confirm string
-			if _, err := fmt.Scanln(&confirm); err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to receive user confirmation. Error: %s\n", err)
-				cleanupAndExit(1)
-			}
-
-			if confirm != expectedConfirm {
-				fmt.Fprintf(os.Stderr, "Confirmation failed\n")
-				cleanupAndExit(1)
-			}
+			confirmOrExit(deleteYesFlag)
 
 			if err := application.DeleteAll(); err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to delete all the tweets. Error: %s\n", err)
 				cleanupAndExit(1)
 			}
-		} else {
+		} else if deleteFilter.IsEmpty() {
 			for _, idString := range args {
 				if err := application.Delete(idString); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to delete the tweet with identifier: %q. Error: %s\n", idString, err)
 					cleanupAndExit(1)
 				}
 
+				fmt.Fprintf(os.Stdout, "Deleting tweet with identifier: %q\n", idString)
+			}
+		} else {
+			tweets := selectAndConfirmOrExit(app.Selection{Ids: args, Filter: deleteFilter}, deleteYesFlag)
+
+			for _, tw := range tweets {
+				idString := tw.Id.String()
+				if err := application.Delete(idString); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to delete the tweet with identifier: %q. Error: %s\n", idString, err)
+					cleanupAndExit(1)
+				}
+
 				fmt.Fprintf(os.Stdout, "Deleting tweet with identifier: %q\n", idString)
 			}
 		}
@@ -115,10 +132,73 @@ func init() {
 
 	deleteCmd.Flags().BoolVarP(&deleteDryRunFlag, "dry-run", "n", false, "Tweets will not be deleted")
 	deleteCmd.Flags().BoolVarP(&deleteAllFlag, "all", "a", false, "Delete all the scheduled tweets")
+	deleteCmd.Flags().BoolVarP(&deleteYesFlag, "yes", "y", false, "Do not prompt for confirmation")
+	addFilterFlags(deleteCmd, &deleteFilter)
 
 	rand.Seed(time.Now().UnixNano())
 }
 
+// Select the tweets by identifiers only, or by filters in which case the user will be
+// asked for confirmation. Exits the app when the selection fails.
+func selectOrExit(selection app.Selection, yes bool) []tweet.Tweet {
+	if !selection.Filter.IsEmpty() {
+		return selectAndConfirmOrExit(selection, yes)
+	}
+
+	tweets, err := application.Select(selection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to select the tweets. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+	return tweets
+}
+
+// Select the tweets, display them and then ask the user for confirmation.
+// Exits the app when nothing is selected or the confirmation fails.
+func selectAndConfirmOrExit(selection app.Selection, yes bool) []tweet.Tweet {
+	tweets, err := application.Select(selection)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to select the tweets. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+
+	if len(tweets) == 0 {
+		fmt.Fprintln(os.Stdout, "No tweets matched")
+		cleanupAndExit(0)
+	}
+
+	fmt.Fprintf(os.Stdout, "%d tweet(s) matched\n\n", len(tweets))
+	if err := application.Preview(os.Stdout, tweets); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		cleanupAndExit(1)
+	}
+
+	confirmOrExit(yes)
+	return tweets
+}
+
+// Ask the user to confirm by repeating a random string, unless yes is true.
+// Exits the app when the confirmation fails.
+func confirmOrExit(yes bool) {
+	if yes {
+		return
+	}
+
+	expectedConfirm := randomString(5 + rand.Intn(5))
+	fmt.Fprintf(os.Stdout, "Please confirm by entering: %s\n> ", expectedConfirm)
+
+	var confirm string
+	if _, err := fmt.Scanln(&confirm); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to receive user confirmation. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+
+	if confirm != expectedConfirm {
+		fmt.Fprintf(os.Stderr, "Confirmation failed\n")
+		cleanupAndExit(1)
+	}
+}
+
 var letterRunes = []rune("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
 func randomString(count int) string {
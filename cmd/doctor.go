@@ -0,0 +1,183 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Display the configuration and where each value came from",
+	Long: `Display the configuration file and environment files that were used
+along with each configuration value and where it came from.
+
+The source of a value is one of (in order of precedence):
+  flag          Specified on the command line, e.g. --log-level
+  environment   An environment variable, e.g. AJTWEET_API_KEY
+  env file      An environment variable loaded from --env-file or .ajtweet.env
+  config file   The configuration file, or the profile selected using --profile
+  default       The default value
+
+Warnings are shown when more than one configuration file is found, or when
+the datastore or lock file created in the current working directory by the
+previous versions is not used.
+
+Credentials are masked unless they reference a secret (secret://name) or
+are read when needed (cmd:, file: or env:).
+
+Examples:
+
+ ajtweet doctor
+ ajtweet doctor --env-file ./production.env
+`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true", annotationSkipValidation: "true"},
+	// Only the configuration is displayed which does not need the lock
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Fprintf(os.Stdout, "Configuration file: %s\n", viper.ConfigFileUsed())
+		if activeProfile != "" {
+			fmt.Fprintf(os.Stdout, "Profile: %s\n", activeProfile)
+		}
+
+		fmt.Fprintln(os.Stdout, "Environment files:")
+		if len(envFilesLoaded) == 0 {
+			fmt.Fprintln(os.Stdout, "  (none)")
+		}
+		for _, filePath := range envFilesLoaded {
+			fmt.Fprintf(os.Stdout, "  %s\n", filePath)
+		}
+		fmt.Fprintln(os.Stdout)
+
+		warnings := application.Config().PathWarnings()
+		if len(configFilesFound) > 1 {
+			warnings = append([]string{fmt.Sprintf("found %d configuration files, ignoring: %s",
+				len(configFilesFound), strings.Join(configFilesFound[1:], ", "))}, warnings...)
+		}
+		if len(warnings) > 0 {
+			fmt.Fprintln(os.Stdout, "Warnings:")
+			for _, warning := range warnings {
+				fmt.Fprintf(os.Stdout, "  %s\n", warning)
+			}
+			fmt.Fprintln(os.Stdout)
+		}
+
+		configKeys := make(map[string]app.ConfigKey)
+		for _, key := range app.ConfigKeys() {
+			configKeys[key.Key] = key
+		}
+
+		keys := viper.AllKeys()
+		for key := range configKeys {
+			if !viper.InConfig(key) {
+				keys = append(keys, key)
+			}
+		}
+		keys = uniqueSorted(keys)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, key := range keys {
+			value, source := configValueSource(key, configKeys[key])
+			fmt.Fprintf(w, "%s\t%s\t%s\n", key, displayConfigValue(key, value), source)
+		}
+		w.Flush()
+	},
+}
+
+// Return the effective value of the configuration key and where it came from.
+func configValueSource(key string, configKey app.ConfigKey) (interface{}, string) {
+	if flag, bound := flagBindings[key]; bound && flag.Changed {
+		return flag.Value.String(), "flag --" + flag.Name
+	}
+
+	// Values only found in the config file (e.g. the accounts) follow the same naming convention
+	envName := configKey.Env
+	if envName == "" {
+		envName = app.EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	}
+	envNames := append(append([]string{}, configKey.Aliases...), envName)
+
+	for _, envName := range envNames {
+		if value, present := os.LookupEnv(envName); present {
+			if filePath, loaded := envFileSources[envName]; loaded {
+				return value, fmt.Sprintf("env file %s (%s)", filePath, envName)
+			}
+			return value, fmt.Sprintf("environment (%s)", envName)
+		}
+	}
+
+	if viper.InConfig(key) {
+		if activeProfile != "" && viper.InConfig("profiles."+activeProfile+"."+key) {
+			return viper.Get(key), "config file (profile " + activeProfile + ")"
+		}
+		return viper.Get(key), "config file"
+	}
+
+	if flag, bound := flagBindings[key]; bound {
+		return flag.DefValue, "default"
+	}
+	if configKey.Key != "" {
+		return configKey.Default, "default"
+	}
+	return viper.Get(key), "default"
+}
+
+// Return the sorted strings without duplicates.
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	unique := values[:0]
+	for i, value := range values {
+		if i == 0 || value != values[i-1] {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// Format the value for display, masking the credentials.
+func displayConfigValue(key string, value interface{}) string {
+	text := ""
+	if value != nil {
+		text = fmt.Sprintf("%v", value)
+	}
+
+	// The hooks contain the webhook secrets
+	if key == "hooks" && text != "" {
+		return "********"
+	}
+	return app.MaskCredential(key, text)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
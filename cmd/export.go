@@ -0,0 +1,79 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormatFlag string
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the scheduled tweets to CSV, YAML, JSON Lines or iCalendar",
+	Long: `Export the scheduled tweets to CSV, YAML, JSON Lines or iCalendar.
+
+The scheduled tweets are written to stdout in the format specified by
+-f, --format (csv, yaml, jsonl or ics). The default format is jsonl.
+
+The CSV, YAML and JSON Lines formats can be imported again using the
+import command.
+
+The iCalendar (ics) format contains one event per scheduled tweet. Each
+event uses the tweet's identifier as a unique identifier so that calendar
+apps will update the existing events instead of creating duplicates.
+
+Examples:
+
+ ajtweet export --format csv > queue.csv
+    Export the tweets to a CSV file.
+
+ ajtweet export --format ics > queue.ics
+    Export the tweets as a calendar that can be opened in a calendar app.
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		format, err := app.ParseFormat(exportFormatFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to determine the file format. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if err := application.Export(os.Stdout, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export the tweets. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportFormatFlag, "format", "f", string(app.FormatJSONL), "Format of the output (csv, yaml, jsonl or ics)")
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+// Add the command line flags used to select tweets to the command.
+func addFilterFlags(cmd *cobra.Command, filter *app.Filter) {
+	cmd.Flags().StringVar(&filter.Before, "before", "", "Only tweets scheduled before this time (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().StringVar(&filter.After, "after", "", "Only tweets scheduled after this time (RFC3339 or YYYY-MM-DD)")
+	cmd.Flags().BoolVar(&filter.Due, "due", false, "Only tweets that need to be sent now")
+	cmd.Flags().StringVar(&filter.Contains, "contains", "", "Only tweets containing the text, or matching /regex/")
+	cmd.Flags().StringVar(&filter.Match, "match", "", "Only tweets matching the regular expression")
+	cmd.Flags().StringSliceVar(&filter.Tags, "tag", nil, "Only tweets with the tag (can be repeated)")
+	cmd.Flags().StringSliceVar(&filter.Accounts, "account", nil, "Only tweets sent from the account (can be repeated)")
+	cmd.Flags().StringSliceVar(&filter.Statuses, "status", nil, "Only tweets with the status, e.g. paused (can be repeated)")
+	cmd.Flags().IntVar(&filter.Limit, "limit", 0, "Maximum number of tweets (0 means no limit)")
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	importFormatFlag string
+	importMapFlag    []string
+	importDryRunFlag bool
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import scheduled tweets from a CSV, JSON Lines or YAML file",
+	Long: `Import scheduled tweets from a CSV, JSON Lines or YAML file.
+
+The whole file is validated before any tweets are added. If any of the rows
+are invalid then the errors are reported along with the line numbers and
+none of the tweets will be added.
+
+-f, --format specifies the format of the file (csv, jsonl or yaml). By
+default the format is determined from the file extension. Use - as the file
+name to read from stdin, in which case the format must be specified.
+
+The following fields are read from each row:
+  message         The message to be tweeted (required).
+  scheduledTime   The preferred time in the RFC3339 format. If no time is
+                  specified then the current time will be used.
+  account         The account the tweet will be sent from, or the accounts
+                  separated by commas to cross-post to. Rows with an
+                  unknown or ambiguous account are rejected.
+  tags            A list of tags, or a single value separated by commas.
+  id              Optional. The imported tweets are assigned new ids, but
+                  rows with the id of a tweet that is already in the queue
+                  are rejected, e.g. when importing an export of the queue.
+
+CSV files must contain a header row naming the columns. Use -m, --map
+field=column to read a field from a differently named column or key.
+
+You may also preview the import by running the command in the dry run mode
+(-n, --dry-run).
+
+Examples:
+
+ ajtweet import campaign.csv
+    Import the tweets from a CSV file.
+
+ ajtweet import --map message=Text --map scheduledTime=When campaign.csv
+    Import the tweets using the columns "Text" and "When".
+
+ ajtweet import --dry-run campaign.yaml
+    Preview the tweets that would be imported.
+
+ cat campaign.jsonl | ajtweet import --format jsonl -
+    Import the tweets in JSON Lines format from stdin.
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		filePath := args[0]
+
+		var format app.Format
+		var err error
+		if importFormatFlag != "" {
+			format, err = app.ParseFormat(importFormatFlag)
+		} else {
+			format, err = app.FormatFromPath(filePath)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to determine the file format. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		mapping := app.NewImportMapping()
+		if err := mapping.Parse(importMapFlag); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse the column mapping. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		var in io.Reader = os.Stdin
+		if filePath != "-" {
+			file, err := os.Open(filePath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to open the file. Error: %s\n", err)
+				cleanupAndExit(1)
+			}
+			defer file.Close()
+			in = file
+		}
+
+		if err := application.Import(os.Stdout, in, format, mapping); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import tweets. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if !importDryRunFlag {
+			if err := application.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the changes. Error: %s\n", err)
+				cleanupAndExit(2)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importFormatFlag, "format", "f", "", "Format of the file (csv, jsonl or yaml)")
+	importCmd.Flags().StringSliceVarP(&importMapFlag, "map", "m", nil, "Map a field to a column, e.g. message=text")
+	importCmd.Flags().BoolVarP(&importDryRunFlag, "dry-run", "n", false, "Tweets will not be imported")
+}
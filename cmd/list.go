@@ -23,18 +23,21 @@ package cmd
 
 import (
 	"fmt"
-	"io"
 	"os"
 
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
 	"github.com/spf13/cobra"
 )
 
 var (
-	jsonFlag bool
+	jsonFlag        bool
+	listOutputFlag  string
+	listFullIdsFlag bool
+	listPendingFlag bool
+	listFilter      app.Filter
 )
 
-type listFunc func(io.Writer) error
-
 // listCmd represents the list command
 var listCmd = &cobra.Command{
 	Use:   "list",
@@ -47,8 +50,41 @@ colour output whereas piping to another command will not use colours.
 Colour output can also be disabled by setting the environment variable
 NO_COLOR.
 
+Tweets are identified by the shortest unique prefix of their identifiers,
+similar to git's short hashes. These prefixes can be used by any of the
+commands that expect an identifier. Use --full-ids to display the complete
+identifiers instead.
+
 -j, --json Can be used to output the list into a JSON format.
 
+-o, --output Specifies the output format:
+  table             Aligned columns with the messages truncated.
+  wide              Aligned columns with more details.
+  json              Same as --json.
+  template=TEMPLATE Go text/template executed for each tweet, e.g.
+                    template='{{.Id}} {{.Message}}'
+                    Use {{short .Id}} to display the short identifier.
+
+Filters:
+  --pending
+    Only tweets waiting to be approved, i.e. the review queue.
+  --before TIME, --after TIME
+    Only tweets scheduled before or after the time (RFC3339 or YYYY-MM-DD).
+  --due
+    Only tweets that need to be sent now, i.e. the scheduled (and approved)
+    tweets of which the scheduled time has passed. These are the tweets that
+    will be sent by the next send.
+  --contains TEXT
+    Only tweets containing the text (case insensitive). Wrap the value in
+    slashes to match a regular expression instead, e.g. --contains '/^Hello/'.
+  --tag TAG, --account ACCOUNT
+    Only tweets with one of the tags or sent from one of the accounts.
+  --status STATUS
+    Only tweets with one of the statuses (draft, pending, scheduled, paused,
+    sending, sent, failed or expired).
+  --limit N
+    Display at most N tweets.
+
 Examples:
 
  ajtweet list
@@ -57,6 +93,18 @@ Examples:
  ajtweet list --json
     List all the tweets in JSON output.
 
+ ajtweet list --due --output table
+    List the tweets that need to be sent now as a table.
+
+ ajtweet list --pending
+    List the tweets waiting to be approved.
+
+ ajtweet list --tag campaign-x --after 2022-06-01 --limit 5
+    List the next 5 tweets tagged campaign-x scheduled after the 1st of June.
+
+ ajtweet list --output template='{{.Id}} {{.Message}}'
+    List the identifier and message of each tweet.
+
  NO_COLOR=1 ajtweet list
     Disable colour output while displaying the list.
 
@@ -65,13 +113,27 @@ Examples:
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 
-		var handler listFunc = application.List
-
 		if jsonFlag {
-			handler = application.ListJSON
+			listOutputFlag = "json"
+		}
+
+		if listPendingFlag {
+			listFilter.Statuses = append(listFilter.Statuses, string(tweet.StatusPending))
+		}
+
+		output, err := app.ParseOutput(listOutputFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse the output format. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		options := app.ListOptions{
+			Filter:  listFilter,
+			Output:  output,
+			FullIds: listFullIdsFlag,
 		}
 
-		if err := handler(os.Stdout); err != nil {
+		if err := application.ListWith(os.Stdout, options); err != nil {
 			fmt.Fprint(os.Stderr, err)
 			cleanupAndExit(1)
 		}
@@ -84,4 +146,8 @@ func init() {
 	rootCmd.AddCommand(listCmd)
 
 	listCmd.Flags().BoolVarP(&jsonFlag, "json", "j", false, "Output the list into JSON format")
+	listCmd.Flags().BoolVar(&listFullIdsFlag, "full-ids", false, "Display the full identifiers")
+	listCmd.Flags().BoolVar(&listPendingFlag, "pending", false, "Only tweets waiting to be approved")
+	listCmd.Flags().StringVarP(&listOutputFlag, "output", "o", "", "Output format (table, wide, json or template=TEMPLATE)")
+	addFilterFlags(listCmd, &listFilter)
 }
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/twittertest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mockServerAddrFlag            string
+	mockServerRateLimitFlag       int
+	mockServerRateLimitWindowFlag time.Duration
+	mockServerFailEveryFlag       int
+)
+
+// mockServerCmd represents the mock-server command
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a local mock of the Twitter API",
+	Long: `Run a local mock of the Twitter API that can be used for testing and demos
+without posting real tweets.
+
+The mock server supports creating tweets (POST /2/tweets) including replies
+and media, uploading media (POST /1.1/media/upload.json), the rate limit
+headers (x-rate-limit-limit, x-rate-limit-remaining and x-rate-limit-reset)
+and the error payloads returned by the Twitter API, e.g. for duplicate
+tweets and when the rate limit has been exceeded. The requests received are
+logged to stdout.
+
+Point ajtweet at the mock server by setting send.api_base_url in the
+configuration file used by the send command:
+
+    send:
+        api_base_url: http://localhost:8080
+
+Any non-empty credentials will be accepted by the mock server.
+
+The mock server does not lock the datastore and keeps running until it is
+interrupted (Ctrl+C).
+
+Examples:
+
+ ajtweet mock-server
+    Listen on localhost:8080.
+
+ ajtweet mock-server --addr :9000 --rate-limit 5 --rate-limit-window 1m
+    Listen on port 9000 and only allow 5 tweets per minute.
+
+ ajtweet mock-server --fail-every 3
+    Fail every third tweet with a 503 Service Unavailable error.
+`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true"},
+	// The mock server does not need the lock and would otherwise block the other commands while running
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		server := twittertest.NewServer(twittertest.Options{
+			RateLimit:       mockServerRateLimitFlag,
+			RateLimitWindow: mockServerRateLimitWindowFlag,
+			FailEvery:       mockServerFailEveryFlag,
+			Log:             os.Stdout,
+		})
+
+		listener, err := net.Listen("tcp", mockServerAddrFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start the mock server. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		fmt.Fprintf(os.Stdout, "Mock Twitter API listening on http://%s\n", listener.Addr())
+
+		if err := http.Serve(listener, server); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to run the mock server. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockServerCmd)
+
+	mockServerCmd.Flags().StringVar(&mockServerAddrFlag, "addr", "localhost:8080", "The address to listen on")
+	mockServerCmd.Flags().IntVar(&mockServerRateLimitFlag, "rate-limit", twittertest.DefaultRateLimit, "The number of tweets allowed per rate limit window")
+	mockServerCmd.Flags().DurationVar(&mockServerRateLimitWindowFlag, "rate-limit-window", twittertest.DefaultRateLimitWindow, "The duration of the rate limit window")
+	mockServerCmd.Flags().IntVar(&mockServerFailEveryFlag, "fail-every", 0, "Fail every nth tweet with a 503 Service Unavailable error (0 = never)")
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pauseDryRunFlag bool
+	pauseYesFlag    bool
+	pauseFilter     app.Filter
+)
+
+// pauseCmd represents the pause command
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause scheduled tweets",
+	Long: `Pause scheduled tweets so that they will not be sent until they are resumed.
+
+Only tweets with the scheduled status can be paused. When filters are used,
+the selected tweets that can not be paused (e.g. drafts or tweets that are
+pending approval) are skipped and reported, otherwise pausing fails.
+
+Tweets are selected in the same way as the delete command, either by
+identifiers (or unique prefixes) passed as arguments and/or by using the
+same filters as the list command. When filters are used, the matching
+tweets are displayed and you will be prompted to confirm. Use -y, --yes to
+skip the prompt.
+
+You may also simulate the process by running the command in the dry run
+mode (-n, --dry-run).
+
+Examples:
+
+ ajtweet pause 28cf75a
+    Pause the tweet of which the identifier starts with 28cf75a.
+
+ ajtweet pause --tag campaign-x
+    Pause all the tweets of the campaign.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 && pauseFilter.IsEmpty() {
+			return errors.New("expected identifiers to be passed as arguments or filters to be specified")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		tweets := selectOrExit(app.Selection{Ids: args, Filter: pauseFilter}, pauseYesFlag)
+
+		for _, tw := range tweets {
+			if err := application.Pause(tw.Id); err != nil {
+				if !pauseFilter.IsEmpty() && errors.Is(err, tweet.ErrInvalidTransition) {
+					fmt.Fprintf(os.Stdout, "Skipping tweet with identifier: %q, only scheduled tweets can be paused (status: %s)\n",
+						tw.Id.String(), tw.Status)
+					continue
+				}
+				fmt.Fprintf(os.Stderr, "Failed to pause the tweet with identifier: %q. Error: %s\n", tw.Id, err)
+				cleanupAndExit(1)
+			}
+
+			fmt.Fprintf(os.Stdout, "Pausing tweet with identifier: %q\n", tw.Id.String())
+		}
+
+		if !pauseDryRunFlag {
+			if err := application.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the changes. Error: %s\n", err)
+				cleanupAndExit(2)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+
+	pauseCmd.Flags().BoolVarP(&pauseDryRunFlag, "dry-run", "n", false, "Tweets will not be paused")
+	pauseCmd.Flags().BoolVarP(&pauseYesFlag, "yes", "y", false, "Do not prompt for confirmation")
+	addFilterFlags(pauseCmd, &pauseFilter)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// profilesCmd represents the profiles command
+var profilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "Manage the profiles in the configuration file",
+	Long: `Manage the profiles in the configuration file.
+
+Profiles are used to configure separate queues (e.g. staging and production)
+with different datastores, lock files and credentials in the same
+configuration file. Select a profile using --profile name or AJTWEET_PROFILE.
+See the Profiles section of "ajtweet --help" for more details.
+
+Examples:
+
+ ajtweet profiles list
+`,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true", annotationSkipValidation: "true"},
+	// Only the configuration is used which does not need the lock
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var profilesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles along with the datastore and accounts of each",
+	Long: `List the profiles in the configuration file along with the datastore, lock
+file and accounts each of them resolves to, taking the shared values and the
+environment variables into account. The selected profile is marked with *.
+
+Examples:
+
+ ajtweet profiles list
+`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := app.ProfileNames(viper.GetViper())
+		if len(names) == 0 {
+			fmt.Fprintf(os.Stdout, "No profiles in the configuration file: %s\n", viper.ConfigFileUsed())
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "\tPROFILE\tDATASTORE\tLOCKFILE\tACCOUNTS")
+		for _, name := range names {
+			config, err := profileConfig(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to resolve the profile %q. Error: %s\n", name, err)
+				cleanupAndExit(1)
+			}
+
+			selected := ""
+			if name == activeProfile {
+				selected = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", selected, name, config.Datastore.Filepath, config.Lockfile,
+				strings.Join(config.AccountRefs(), ", "))
+		}
+		w.Flush()
+	},
+}
+
+// Return the configuration of the profile by reading the config file again and applying the profile.
+func profileConfig(name string) (app.Config, error) {
+	v := viper.New()
+	v.SetConfigFile(viper.ConfigFileUsed())
+	if err := v.ReadInConfig(); err != nil {
+		return app.Config{}, err
+	}
+
+	if err := app.BindEnv(v); err != nil {
+		return app.Config{}, err
+	}
+
+	if err := app.ApplyProfile(v, name); err != nil {
+		return app.Config{}, err
+	}
+
+	config := app.NewConfig()
+	if err := v.Unmarshal(&config); err != nil {
+		return app.Config{}, err
+	}
+	config.PopulateFromEnv()
+	return config, nil
+}
+
+func init() {
+	rootCmd.AddCommand(profilesCmd)
+	profilesCmd.AddCommand(profilesListCmd)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	rescheduleShiftFlag  string
+	rescheduleDryRunFlag bool
+	rescheduleYesFlag    bool
+	rescheduleFilter     app.Filter
+)
+
+// rescheduleCmd represents the reschedule command
+var rescheduleCmd = &cobra.Command{
+	Use:   "reschedule",
+	Short: "Move the scheduled time of tweets",
+	Long: `Move the scheduled time of tweets by a time shift.
+
+-s, --shift specifies how far the scheduled time will be moved, e.g. +1d
+moves the tweets a day later and -2h30m moves them two and a half hours
+earlier. The units w (weeks) and d (days) can be used in addition to h, m
+and s.
+
+Tweets are selected in the same way as the delete command, either by
+identifiers (or unique prefixes) passed as arguments and/or by using the
+same filters as the list command. When filters are used, the matching
+tweets are displayed and you will be prompted to confirm. Use -y, --yes to
+skip the prompt.
+
+You may also simulate the process by running the command in the dry run
+mode (-n, --dry-run).
+
+Examples:
+
+ ajtweet reschedule --shift +1d 28cf75a
+    Send the tweet a day later.
+
+ ajtweet reschedule --shift +1w --tag campaign-x
+    Postpone all the tweets of the campaign by a week.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if rescheduleShiftFlag == "" {
+			return errors.New("--shift is required")
+		}
+		if len(args) < 1 && rescheduleFilter.IsEmpty() {
+			return errors.New("expected identifiers to be passed as arguments or filters to be specified")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		shift, err := app.ParseShift(rescheduleShiftFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to parse the time shift. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		tweets := selectOrExit(app.Selection{Ids: args, Filter: rescheduleFilter}, rescheduleYesFlag)
+
+		for _, tw := range tweets {
+			if err := application.Reschedule(tw.Id, shift); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to reschedule the tweet with identifier: %q. Error: %s\n", tw.Id, err)
+				cleanupAndExit(1)
+			}
+
+			fmt.Fprintf(os.Stdout, "Rescheduling tweet with identifier: %q to %s\n", tw.Id.String(),
+				tw.ScheduledTime.Add(shift).Format(time.RFC3339))
+		}
+
+		if !rescheduleDryRunFlag {
+			if err := application.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the changes. Error: %s\n", err)
+				cleanupAndExit(2)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rescheduleCmd)
+
+	rescheduleCmd.Flags().StringVarP(&rescheduleShiftFlag, "shift", "s", "", "Time shift, e.g. +1d or -2h30m")
+	rescheduleCmd.Flags().BoolVarP(&rescheduleDryRunFlag, "dry-run", "n", false, "Tweets will not be rescheduled")
+	rescheduleCmd.Flags().BoolVarP(&rescheduleYesFlag, "yes", "y", false, "Do not prompt for confirmation")
+	addFilterFlags(rescheduleCmd, &rescheduleFilter)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resumeDryRunFlag bool
+	resumeYesFlag    bool
+	resumeFilter     app.Filter
+)
+
+// resumeCmd represents the resume command
+var resumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume paused, draft, failed or expired tweets",
+	Long: `Resume tweets so that they will be sent again at their scheduled time.
+
+Paused, draft, failed and expired tweets can be resumed. Resuming a failed
+tweet also resets the number of attempts made to send it. Tweets left in the
+sending status by a send that was killed can also be resumed, check that
+they were not posted first.
+
+Tweets are selected in the same way as the delete command, either by
+identifiers (or unique prefixes) passed as arguments and/or by using the
+same filters as the list command. When filters are used, the matching
+tweets are displayed and you will be prompted to confirm. Use -y, --yes to
+skip the prompt.
+
+You may also simulate the process by running the command in the dry run
+mode (-n, --dry-run).
+
+Examples:
+
+ ajtweet resume 28cf75a
+    Resume the tweet of which the identifier starts with 28cf75a.
+
+ ajtweet resume --status failed --yes
+    Retry all the tweets that failed to be sent.
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 && resumeFilter.IsEmpty() {
+			return errors.New("expected identifiers to be passed as arguments or filters to be specified")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		tweets := selectOrExit(app.Selection{Ids: args, Filter: resumeFilter}, resumeYesFlag)
+
+		for _, tw := range tweets {
+			if err := application.Resume(tw.Id); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to resume the tweet with identifier: %q. Error: %s\n", tw.Id, err)
+				cleanupAndExit(1)
+			}
+
+			fmt.Fprintf(os.Stdout, "Resuming tweet with identifier: %q\n", tw.Id.String())
+		}
+
+		if !resumeDryRunFlag {
+			if err := application.Save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save the changes. Error: %s\n", err)
+				cleanupAndExit(2)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+
+	resumeCmd.Flags().BoolVarP(&resumeDryRunFlag, "dry-run", "n", false, "Tweets will not be resumed")
+	resumeCmd.Flags().BoolVarP(&resumeYesFlag, "yes", "y", false, "Do not prompt for confirmation")
+	addFilterFlags(resumeCmd, &resumeFilter)
+}
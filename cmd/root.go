@@ -24,20 +24,69 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/andrejacobs/ajtweet-cli/app"
 	"github.com/andrejacobs/ajtweet-cli/internal/buildinfo"
+	"github.com/andrejacobs/ajtweet-cli/internal/dotenv"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var envFile string
+var profileFlag string
+
+// The profile selected using --profile or AJTWEET_PROFILE.
+var activeProfile string
 var application app.Application
 var hasLock bool
+var logCloser io.Closer
+
+// The context is cancelled when the app is interrupted or terminated.
+// Commands that support cancellation (see annotationCancellable) use it to stop what they are doing and to
+// release the lock before exiting, all other commands exit immediately.
+var appContext, cancelAppContext = context.WithCancel(context.Background())
+
+// The name of the dotenv file that is loaded from the same directory as the config file.
+const defaultEnvFile = ".ajtweet.env"
+
+// The dotenv files that were loaded and the file each environment variable was loaded from, keyed by the variable name.
+var envFilesLoaded []string
+var envFileSources = make(map[string]string)
+
+// The flags bound to the configuration keys.
+var flagBindings = make(map[string]*pflag.Flag)
+
+// Annotation used to mark the commands that stop gracefully when appContext is cancelled.
+const annotationCancellable = "cancellable"
+
+// Annotation used to mark the commands that do not use the credentials and must work without the secrets
+// referenced in the configuration, e.g. before the secrets have been set.
+const annotationSkipSecrets = "skip-secrets"
+
+// Annotation used to mark the commands that must work with an invalid configuration, e.g. to fix it.
+const annotationSkipValidation = "skip-validation"
+
+// Annotation used to mark the commands that do not need a configuration file, e.g. to create it.
+const annotationConfigOptional = "config-optional"
+
+// Annotation used to mark the commands that do not use the datastore and must not move the datastore
+// created in the current working directory by the previous versions, e.g. mock-server.
+const annotationSkipMigration = "skip-migration"
+
+// The directories searched for the configuration file and the configuration files found in them.
+var configSearchPaths []string
+var configFilesFound []string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -53,18 +102,77 @@ Configuration:
   ajtweet will look for a configuration file named ".ajtweet" and a 
   supported extension (.yaml, .toml, .ini) in the following directories
   (in this specified order):
-      ./             Current working directory
-      $HOME/         User's home directory
+      ./                        Current working directory
+      $XDG_CONFIG_HOME/ajtweet  Default is $HOME/.config/ajtweet
+      $HOME/                    User's home directory
       /etc/ajtweet
 
   For example: The configuration file $HOME/.ajtweet.ini will be found and use
-  before the file /etc/ajtweet/.ajtweet.yaml
+  before the file /etc/ajtweet/.ajtweet.yaml. A warning is shown when more
+  than one configuration file is found.
+
+  The datastore is stored in $XDG_DATA_HOME/ajtweet/ajtweets-data.json and
+  the encrypted secrets file in $XDG_DATA_HOME/ajtweet/secrets.json (default
+  is $HOME/.local/share/ajtweet) and the lock file is created in
+  $XDG_STATE_HOME/ajtweet/ajtweet.lock (default is $HOME/.local/state/ajtweet)
+  unless datastore.filepath, secrets.file and lockfile are configured. The
+  datastore ./ajtweets-data.json and the secrets file ./ajtweet-secrets.json
+  created in the current working directory by the previous versions are moved
+  to the new location the first time ajtweet is run.
 
   --config path
     Can be used to explicitly specify the configuration file to be used.
 
-  Environment variables can also be used to override some of the configuration
-  values. See the Authentication section for more details.
+  Environment variables can also be used to override the configuration values.
+  The name of the variable is the configuration path in upper case with the
+  AJTWEET_ prefix and the dots replaced by underscores, e.g. AJTWEET_SEND_MAX
+  for send.max and AJTWEET_DATASTORE_FILEPATH for datastore.filepath. Lists
+  are comma separated. Use "ajtweet config env" to list all the variables.
+
+  --env-file path
+    Load the environment variables from this dotenv file (NAME=value per
+    line, the same format as env_example). The .ajtweet.env file next to the
+    configuration file is also loaded when it exists. Variables that are
+    already set in the environment take precedence over the files.
+
+  The precedence is: flag > environment > env file > config file > default.
+  Use "ajtweet doctor" to display where each value came from.
+
+  The configuration is checked each time ajtweet is run, see "ajtweet config"
+  to create, change, check and display the configuration.
+
+Profiles:
+  Separate queues (e.g. staging and production) can be configured in the same
+  configuration file using profiles. The values of the profile selected using
+  --profile name (or AJTWEET_PROFILE) are merged on top of the shared values.
+  Each profile can change any of the configuration values. Use
+  "ajtweet profiles list" to list the profiles.
+
+      send:
+        max: 10
+      profiles:
+        staging:
+          datastore:
+            filepath: ./staging-tweets.json
+          lockfile: ./staging.lock
+          send:
+            api_base_url: http://localhost:8080
+        production:
+          datastore:
+            filepath: /var/lib/ajtweet/tweets.json
+          lockfile: /run/ajtweet/ajtweet.lock
+
+Logging:
+  In addition to the output of the commands, a structured log of what ajtweet
+  is doing (e.g. the tweets sent along with the tweet id, account, twitter_id,
+  attempt and duration) can be written to stderr or a file.
+
+  --log-level debug|info|warn|error|off   (config path: log.level)
+  --log-format text|json                  (config path: log.format)
+  --log-file path                         (config path: log.file)
+
+  The log file is rotated once it reaches log.max_size megabytes (default 10)
+  and log.max_backups rotated files are kept (default 3).
 
 Authentication:
   You will need to have a registered developer account with Twitter to be able
@@ -84,25 +192,102 @@ Authentication:
       config path: send.authentication.oauth1.secret
 	  environment: AJTWEET_ACCESS_SECRET
 
+  The credentials (including those of the Mastodon and Bluesky accounts, the
+  hook secrets and serve.token) can also be read when they are needed by
+  using one of the following prefixes:
+    cmd:command    Run the command using the shell and use its output,
+                   e.g. cmd:pass show twitter/api-key
+    file:path      Read the file, e.g. file:/run/secrets/twitter-api-key
+    env:NAME       Read the environment variable, e.g. env:TWITTER_API_KEY
+  Only the credentials of the accounts that due tweets are sent from are
+  resolved, once for as long as the command runs.
+
+Accounts:
+  Additional Twitter, Mastodon and Bluesky accounts can be configured in the
+  accounts section, keyed by the account name for each service. Accounts are
+  referenced as service:name (e.g. mastodon:product) or only by name when the
+  name is unique.
+
+      accounts:
+        mastodon:
+          product:
+            instance: https://mastodon.social
+            access_token: your_mastodon_access_token
+        bluesky:
+          news:
+            handle: news.bsky.social
+            app_password: your_bluesky_app_password
+
+Secrets:
+  Instead of storing the credentials in plaintext, they can be stored in an
+  encrypted secrets file using "ajtweet secrets set" and referenced from the
+  configuration as secret://name. See "ajtweet secrets --help".
+
+      send:
+        authentication:
+          api_key: secret://twitter-api-key
+
 Examples:
 
+ ajtweet --profile staging list
+ AJTWEET_PROFILE=production ajtweet send
+
+ ajtweet config init
+ ajtweet config set send.max 20
+ ajtweet config validate
+
  ajtweet add "Send this tweet asap"
  ajtweet add --scheduledAt "2022-05-23T21:22:42Z" "Send this later"
+ ajtweet add --account mastodon:product --media ./launch.png "Launch day!"
+ ajtweet add --to twitter:product --to mastodon:product "Launch day!"
 
  date | xargs -0 ajtweet add
     Pass the output from date as the message argument expected by add.
 
+ ajtweet import campaign.csv
+ ajtweet import --dry-run campaign.yaml
+
  ajtweet list
  ajtweet list --json
+ ajtweet list --due --output table
+ ajtweet list --tag campaign-x --before 2022-06-01
  NO_COLOR=1 ajtweet list
 
+ ajtweet export --format csv
+ ajtweet export --format ics > queue.ics
+
  ajtweet delete "a2fdb340-0b61-4a89-b52e-82deae2e3aa8"
  ajtweet delete --dry-run "a2fdb340-0b61-4a89-b52e-82deae2e3aa8"
  ajtweet delete --all
+ ajtweet delete --match "^Launch" --before 2024-01-01 --tag campaign-x
+
+ ajtweet reschedule --shift +1d "a2fdb340"
+ ajtweet reschedule --shift +1w --tag campaign-x --yes
+
+ ajtweet add --draft "Still working on this one"
+ ajtweet pause --tag campaign-x --yes
+ ajtweet resume --status failed --yes
+
+ ajtweet add --needs-approval "Our new product launches today!"
+ ajtweet list --pending
+ ajtweet approve "8b957da" --as alice
 
  ajtweet send
  ajtweet send --dry-run
  NO_COLOR=1 ajtweet send
+
+ ajtweet send --metrics-textfile /var/lib/node_exporter/textfile/ajtweet.prom
+ ajtweet daemon --interval 1m --listen localhost:9090
+    Keep sending the scheduled tweets and serve the metrics on /metrics.
+
+ AJTWEET_SERVE_TOKEN=your_token ajtweet serve --listen 127.0.0.1:8080
+    Serve a REST API for managing the scheduled tweets.
+
+ ajtweet mock-server --addr localhost:8080
+    Run a local mock of the Twitter API, see send.api_base_url.
+
+ ajtweet secrets set twitter-api-key
+    Store a secret in the encrypted secrets file, see secret://name.
 `,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		// Lock the app
@@ -113,12 +298,7 @@ Examples:
 		hasLock = true
 
 		// Check if we are interrupted or terminated in some way, so that we can release the lock
-		signalCh := make(chan os.Signal, 1)
-		signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
-		go func() {
-			<-signalCh
-			cleanupAndExit(42)
-		}()
+		handleSignals(cmd.Annotations[annotationCancellable] != "")
 	},
 	PersistentPostRun: func(cmd *cobra.Command, args []string) {
 		cleanup()
@@ -139,7 +319,16 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	// Persistent flags that are available to every subcommand
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.ajtweet.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .ajtweet.yaml in ./, $XDG_CONFIG_HOME/ajtweet, $HOME or /etc/ajtweet)")
+
+	rootCmd.PersistentFlags().String("log-level", "", "log level: debug, info, warn, error or off (default is info with --log-file, otherwise off)")
+	rootCmd.PersistentFlags().String("log-format", "text", "log format: text or json")
+	rootCmd.PersistentFlags().String("log-file", "", "log to this file instead of stderr, the file is rotated once it reaches log.max_size megabytes")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "use the values of this profile from the config file (default is $AJTWEET_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "load the environment variables from this dotenv file (default is the .ajtweet.env file next to the config file)")
+	bindFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level"))
+	bindFlag("log.format", rootCmd.PersistentFlags().Lookup("log-format"))
+	bindFlag("log.file", rootCmd.PersistentFlags().Lookup("log-file"))
 
 	versionTemplate := `{{printf "%s: %s - %s\n" .Name .Short .Version}}`
 	rootCmd.SetVersionTemplate(versionTemplate)
@@ -157,11 +346,19 @@ func initConfig() {
 
 		// Search for a config file with base name ".ajtweet" (extension will determine the type of format used, e.g. yaml)
 		// starting at the current working directory
-		viper.AddConfigPath(".")
+		configSearchPaths = append(configSearchPaths, ".")
+		// then check $XDG_CONFIG_HOME/ajtweet
+		if dir := app.ConfigDir(); dir != "" {
+			configSearchPaths = append(configSearchPaths, dir)
+		}
 		// then check the $HOME directory
-		viper.AddConfigPath(home)
+		configSearchPaths = append(configSearchPaths, home)
 		// finally check in /etc/ajtweet
-		viper.AddConfigPath(fmt.Sprintf("/etc/%s/", rootCmd.Name()))
+		configSearchPaths = append(configSearchPaths, fmt.Sprintf("/etc/%s/", rootCmd.Name()))
+
+		for _, dir := range configSearchPaths {
+			viper.AddConfigPath(dir)
+		}
 
 		viper.SetConfigType("yaml")
 		viper.SetConfigName(".ajtweet")
@@ -169,37 +366,229 @@ func initConfig() {
 		//NOTE: Viper supports the following formats: JSON, TOML, YAML, HCL (HashiCorp), INI, envfile or Java properties
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// Read in environment variables that match, e.g. AJTWEET_SEND_MAX for send.max
+	if err := app.BindEnv(viper.GetViper()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error binding the environment variables. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err != nil {
+	if err := viper.ReadInConfig(); err != nil && !(commandHasAnnotation(annotationConfigOptional) && configNotFound(err)) {
 		fmt.Fprintf(os.Stderr, "Error reading config file: %s. Error: %s\n", viper.ConfigFileUsed(), err)
 		cleanupAndExit(1)
 	}
 
+	warnMultipleConfigFiles()
+	loadEnvFiles()
+	applyProfile()
 	initApplication()
 }
 
+// Warn when more than one configuration file is found in the search directories, since only the first one is used.
+func warnMultipleConfigFiles() {
+	if cfgFile != "" {
+		return
+	}
+
+	configFilesFound = app.FindConfigFiles(configSearchPaths, ".ajtweet", viper.SupportedExts)
+	if len(configFilesFound) > 1 && !commandHasAnnotation(annotationSkipValidation) {
+		fmt.Fprintf(os.Stderr, "Warning: found %d configuration files, using %s and ignoring: %s\n",
+			len(configFilesFound), configFilesFound[0], strings.Join(configFilesFound[1:], ", "))
+	}
+}
+
+// Merge the values of the profile selected by --profile or AJTWEET_PROFILE on top of the shared values.
+func applyProfile() {
+	activeProfile = profileFlag
+	if activeProfile == "" {
+		activeProfile = os.Getenv(app.EnvProfile)
+	}
+	if activeProfile == "" {
+		return
+	}
+
+	activeProfile = strings.ToLower(activeProfile)
+	if err := app.ApplyProfile(viper.GetViper(), activeProfile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error selecting the profile. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+}
+
+// Check if the error is caused by the config file not being found.
+func configNotFound(err error) bool {
+	var notFound viper.ConfigFileNotFoundError
+	return errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist)
+}
+
+// Load the environment variables from the --env-file and the .ajtweet.env file next to the config file.
+// Variables that are already set in the environment are not changed.
+func loadEnvFiles() {
+	var files []string
+	if envFile != "" {
+		files = append(files, envFile)
+	}
+	if used := viper.ConfigFileUsed(); used != "" {
+		if filePath := filepath.Join(filepath.Dir(used), defaultEnvFile); envFile == "" || filepath.Clean(envFile) != filePath {
+			files = append(files, filePath)
+		}
+	}
+
+	for _, filePath := range files {
+		names, err := dotenv.Load(filePath)
+		if err != nil {
+			// The .ajtweet.env file is optional
+			if filePath != envFile && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Error loading the environment file: %s. Error: %s\n", filePath, err)
+			cleanupAndExit(1)
+		}
+
+		envFilesLoaded = append(envFilesLoaded, filePath)
+		for _, name := range names {
+			envFileSources[name] = filePath
+		}
+	}
+}
+
+// Bind the flag to the configuration key and record the binding so that doctor can report which values
+// were specified by flags.
+func bindFlag(key string, flag *pflag.Flag) {
+	viper.BindPFlag(key, flag)
+	flagBindings[key] = flag
+}
+
 // Initialize the main Application "context" used by the CLI commands.
 func initApplication() {
+	// The commands that help fixing the configuration must work with an invalid configuration
+	validate := !commandHasAnnotation(annotationSkipValidation)
+
+	if validate {
+		if err := app.ValidateEnv(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing the environment: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if errs := app.CheckTypes(viper.GetViper()); len(errs) > 0 {
+			exitInvalidConfig(errs)
+		}
+	}
+
 	appConfig := app.NewConfig()
-	if err := viper.Unmarshal(&appConfig); err != nil {
+	if err := viper.Unmarshal(&appConfig); err != nil && validate {
 		fmt.Fprintf(os.Stderr, "Error parsing the configuration: %s", err)
 		cleanupAndExit(1)
 	}
 
 	appConfig.PopulateFromEnv()
 
-	if appConfig.Datastore.Filepath == "" {
-		appConfig.Datastore.Filepath = "./ajtweets-data.json"
+	if validate {
+		if errs := appConfig.Validate(); len(errs) > 0 {
+			exitInvalidConfig(errs)
+		}
+
+		for _, key := range app.UnknownKeys(viper.GetViper()) {
+			fmt.Fprintf(os.Stderr, "Warning: unknown configuration key %q in %s\n", key, viper.ConfigFileUsed())
+		}
 	}
 
-	if err := application.Configure(appConfig); err != nil {
+	if !commandHasAnnotation(annotationSkipMigration) {
+		migrateLegacyFiles(appConfig)
+	}
+
+	if !commandHasAnnotation(annotationSkipSecrets) {
+		if err := appConfig.ResolveSecrets(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving the secrets: %s\n", err)
+			cleanupAndExit(1)
+		}
+	}
+
+	logger, closer, err := app.NewLogger(appConfig.Log)
+	if err != nil && validate {
+		fmt.Fprintf(os.Stderr, "Error configuring the log: %s\n", err)
+		cleanupAndExit(1)
+	}
+	if err == nil {
+		logCloser = closer
+		application.SetLogger(logger)
+	}
+
+	if err := application.Configure(appConfig); err != nil && validate {
 		fmt.Fprintf(os.Stderr, "Error configuring the application: %s", err)
 		cleanupAndExit(1)
 	}
 }
 
+// Move the datastore and the secrets file created in the current working directory by the previous versions
+// to the default locations and warn about the files that are ambiguous.
+func migrateLegacyFiles(config app.Config) {
+	from, err := config.MigrateLegacyDatastore()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating the datastore. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+	if from != "" {
+		fmt.Fprintf(os.Stderr, "Moved the datastore %s to %s\n", from, config.Datastore.Filepath)
+	}
+
+	migrateLegacySecrets(config)
+
+	for _, warning := range config.PathWarnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+}
+
+// Move the secrets file created in the current working directory by the previous versions to the default location.
+func migrateLegacySecrets(config app.Config) {
+	from, err := config.MigrateLegacySecrets()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating the secrets file. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+	if from != "" {
+		fmt.Fprintf(os.Stderr, "Moved the secrets file %s to %s\n", from, config.Secrets.File)
+	}
+}
+
+// Report the problems found in the configuration and exit.
+func exitInvalidConfig(errs []error) {
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Error in the configuration: %s\n", err)
+	}
+	fmt.Fprintln(os.Stderr, `Use "ajtweet config validate" to check the configuration.`)
+	cleanupAndExit(1)
+}
+
+// Check if the command being run or any of its parents has the annotation, e.g. annotationSkipSecrets.
+func commandHasAnnotation(annotation string) bool {
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		return false
+	}
+
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.Annotations[annotation] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Exit (releasing the lock) when the app is interrupted or terminated.
+// Cancellable commands are given the chance to stop by cancelling appContext, a second signal will exit immediately.
+func handleSignals(cancellable bool) {
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signalCh
+		if cancellable {
+			cancelAppContext()
+			<-signalCh
+		}
+		cleanupAndExit(42)
+	}()
+}
+
 // Sub commmands must call this instead of just os.Exit()
 func cleanupAndExit(code int) {
 	cleanup()
@@ -215,4 +604,9 @@ func cleanup() {
 		}
 		hasLock = false
 	}
+
+	if logCloser != nil {
+		logCloser.Close()
+		logCloser = nil
+	}
 }
@@ -0,0 +1,215 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+// secretsCmd represents the secrets command
+var secretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted secrets referenced by the configuration",
+	Long: `Manage the encrypted secrets referenced by the configuration.
+
+Credentials (e.g. the Twitter API key, Mastodon access tokens, Bluesky app
+passwords, webhook secrets and the REST API token) do not need to be stored
+in plaintext in the configuration file. Instead they can be stored in an
+encrypted secrets file and referenced from the configuration as
+secret://name.
+
+    send:
+        authentication:
+            api_key: secret://twitter-api-key
+            api_secret: secret://twitter-api-secret
+
+The references are resolved when ajtweet starts and the values are only kept
+in memory, they are never written to disk in plaintext.
+
+The secrets file is encrypted using NaCl secretbox (XSalsa20 and Poly1305)
+with a key derived from a passphrase using scrypt. The passphrase is read
+from the AJTWEET_SECRETS_PASSPHRASE environment variable or from the key
+file specified by secrets.key_file. The location of the secrets file is
+specified by secrets.file (default $XDG_DATA_HOME/ajtweet/secrets.json or
+$HOME/.local/share/ajtweet/secrets.json). The ./ajtweet-secrets.json created
+in the current working directory by the previous versions is moved to the new
+location the first time ajtweet is run.
+
+    secrets:
+        file: /etc/ajtweet/secrets.json
+        key_file: /etc/ajtweet/secrets.key
+
+Examples:
+
+ ajtweet secrets set twitter-api-key
+    Prompts for the value of the secret.
+
+ pass show twitter/api-key | ajtweet secrets set twitter-api-key
+    Reads the value of the secret from stdin.
+
+ ajtweet secrets list
+ ajtweet secrets delete twitter-api-key
+`,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true"},
+	// Only the secrets file is changed which does not need the lock
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+}
+
+var secretsSetCmd = &cobra.Command{
+	Use:   "set name",
+	Short: "Store a secret in the encrypted secrets file",
+	Long: `Store a secret in the encrypted secrets file.
+
+The value is read from stdin (only the first line is used) so that it does
+not end up in the shell history. An existing secret with the same name is
+replaced.
+
+Examples:
+
+ ajtweet secrets set twitter-api-key
+ echo "$TOKEN" | ajtweet secrets set mastodon-product-token
+`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := secrets.ValidateName(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set the secret. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		store := openSecretsOrExit()
+
+		if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprintf(os.Stderr, "Value for %s: ", name)
+		}
+
+		value, err := readSecretValue(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read the value of the secret. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if err := store.Set(name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to set the secret. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save the secrets. Error: %s\n", err)
+			cleanupAndExit(2)
+		}
+
+		fmt.Fprintf(os.Stdout, "Secret %q saved, reference it as %s%s\n", name, secrets.Scheme, name)
+	},
+}
+
+var secretsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the names of the secrets",
+	Long: `List the names of the secrets stored in the encrypted secrets file.
+The values are not displayed.
+
+Examples:
+
+ ajtweet secrets list
+`,
+	Args:        cobra.NoArgs,
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openSecretsOrExit()
+		for _, name := range store.Names() {
+			fmt.Fprintln(os.Stdout, name)
+		}
+	},
+}
+
+var secretsDeleteCmd = &cobra.Command{
+	Use:   "delete name",
+	Short: "Delete a secret from the encrypted secrets file",
+	Long: `Delete a secret from the encrypted secrets file.
+
+Examples:
+
+ ajtweet secrets delete twitter-api-key
+`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationSkipSecrets: "true", annotationSkipMigration: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		store := openSecretsOrExit()
+
+		if err := store.Delete(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to delete the secret. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		if err := store.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to save the secrets. Error: %s\n", err)
+			cleanupAndExit(2)
+		}
+
+		fmt.Fprintf(os.Stdout, "Secret %q deleted\n", args[0])
+	},
+}
+
+// Open the encrypted secrets file or exit when it can't be opened (e.g. the passphrase is wrong).
+func openSecretsOrExit() *secrets.Store {
+	// The datastore is not migrated without the lock, but the secrets file has to be found
+	migrateLegacySecrets(application.Config())
+
+	store, err := app.OpenSecrets(application.Config().Secrets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open the secrets file. Error: %s\n", err)
+		cleanupAndExit(1)
+	}
+	return store
+}
+
+// Read the first line from the reader without the line ending.
+func readSecretValue(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	value := strings.TrimRight(line, "\r\n")
+	if value == "" {
+		return "", errors.New("the value is empty")
+	}
+	return value, nil
+}
+
+func init() {
+	rootCmd.AddCommand(secretsCmd)
+	secretsCmd.AddCommand(secretsSetCmd)
+	secretsCmd.AddCommand(secretsListCmd)
+	secretsCmd.AddCommand(secretsDeleteCmd)
+}
@@ -22,21 +22,35 @@ THE SOFTWARE.
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 
+	"github.com/andrejacobs/ajtweet-cli/app"
+
 	"github.com/spf13/cobra"
 )
 
 var (
-	sendDryRunFlag bool
+	sendDryRunFlag     bool
+	sendReportFlag     string
+	sendReportFileFlag string
+	sendMetricsFlag    string
+	sendExitCodesFlag  bool
 )
 
 // sendCmd represents the send command
 var sendCmd = &cobra.Command{
 	Use:   "send",
-	Short: "Send the scheduled tweets to Twitter",
-	Long: `Send the scheduled tweets to Twitter
+	Short: "Send the scheduled tweets to Twitter, Mastodon or Bluesky",
+	Long: `Send the scheduled tweets to Twitter, Mastodon or Bluesky
+
+Each tweet is sent from the account it was added with (ajtweet add --account)
+or the Twitter account configured in send.authentication. Tweets added with
+--to are cross-posted to each of the accounts and only the accounts that
+failed will be retried the next time send is run.
 
 The list of scheduled tweets will be checked against the current time to
 determine which tweets need to be sent as soon as possible. Only the tweets
@@ -60,25 +74,199 @@ Rate limiting:
         max: 100
         delay: 5
 
+Network:
+ The API requests can be sent through an HTTP(S) proxy (send.proxy) and
+ additional CA certificates can be trusted (send.ca_bundle), e.g. when the
+ proxy intercepts TLS. When send.proxy is not specified the HTTP_PROXY,
+ HTTPS_PROXY and NO_PROXY environment variables are used.
+
+ Each API request is cancelled after send.timeout seconds (default 30) and
+ sending is stopped after send.deadline seconds (default 0 = no deadline).
+ Tweets that were not sent will be sent the next time.
+
+ Pressing Ctrl+C (SIGINT) or SIGTERM stops sending gracefully. The current
+ request (or delay) is cancelled, the tweet being sent is returned to the
+ scheduled status without counting as a failed attempt, the changes are
+ saved and a summary of the tweets sent is displayed. A second signal exits
+ immediately.
+
+    send:
+        proxy: http://proxy.example.com:3128
+        ca_bundle: /etc/ssl/certs/corporate-ca.pem
+        timeout: 30
+        deadline: 600
+
+Report:
+ A machine readable report of what happened can be written using --report json
+ (to stdout instead of the normal output) or --report-file path. The report
+ lists each tweet that was due with its outcome (sent, skipped, failed,
+ dry-run or expired), the identifiers assigned by the services (e.g. the
+ Twitter ID), errors, timings and the last rate limit reported by each
+ service, along with the totals.
+
+ When a report is written (or --exit-codes is specified) the exit code
+ describes the result:
+   0  all-sent          All the tweets that were due have been sent.
+   1  fatal             Nothing could be sent, e.g. invalid credentials.
+   3  nothing-to-send   No tweets were due to be sent.
+   4  partial-failure   One or more tweets failed or were not sent, e.g.
+                        vetoed by a before_send hook.
+   42                   Sending was interrupted (Ctrl+C).
+
+ Otherwise the exit code is 0 when all the tweets that were due have been
+ sent or nothing was due, 1 when any of the tweets failed or were not sent
+ and 42 when sending was interrupted, e.g. so that a cron job does not fail
+ when nothing was due.
+
+Hooks:
+ Other systems can be notified of the before_send, after_send, send_failed
+ and queue_empty events. Each hook either posts a JSON payload describing the
+ event (the tweet, account, service, the identifier assigned by the service
+ e.g. the Twitter ID, URL and error) to a webhook, or runs an executable that
+ receives the payload on stdin (and the event in AJTWEET_EVENT).
+
+ Webhooks are signed using HMAC-SHA256 when a secret is specified (header
+ X-Ajtweet-Signature: sha256=<hex>) and retried with an increasing delay when
+ the request fails or the response is a 5xx or 429 status.
+
+ A before_send hook vetoes the send by responding with a non 2xx status or
+ exiting with a non-zero status. A hook that fails or times out also vetoes
+ the send. The vetoed tweet remains scheduled (without counting as a failed
+ attempt) and is skipped until the next time. The hooks are not run for a
+ dry run.
+
+    hooks:
+      - event: after_send
+        url: https://cms.example.com/hooks/tweet-sent
+        secret: your_webhook_secret
+        retries: 3
+      - event: before_send
+        command: /usr/local/bin/check-tweet
+        args: ["--strict"]
+        timeout: 10
+
+Metrics:
+ The state of the queue (tweets by status, the number of due tweets and the
+ age of the oldest overdue tweet), the sends by outcome, the API latency and
+ the rate limit remaining can be written in the Prometheus text format for the
+ node_exporter textfile collector using --metrics-textfile path. The counters
+ only describe the current run, use "ajtweet daemon --listen" to serve the
+ metrics of a long running process on /metrics instead.
+
 Authentication:
- Please see the Authentication section from the root command's
+ Please see the Authentication and Accounts sections from the root command's
  help on how to configure the required authentication needed to use the
- Twitter APIs (i.e. ajtweet --help).
+ Twitter, Mastodon and Bluesky APIs (i.e. ajtweet --help).
 
 Examples:
  ajtweet send
- ajtweet send --dry-run	
+ ajtweet send --dry-run
+ ajtweet send --report json | jq .totals
+ ajtweet send --report-file /var/log/ajtweet/last-send.json
+ ajtweet send --exit-codes
+ ajtweet send --metrics-textfile /var/lib/node_exporter/textfile/ajtweet.prom
 `,
+	Annotations: map[string]string{annotationCancellable: "true"},
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := application.Send(os.Stdout, sendDryRunFlag); err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to send. Error: %s\n", err)
+		var metrics *app.Metrics
+		if sendMetricsFlag != "" {
+			metrics = app.NewMetrics()
+			application.SetMetrics(metrics)
+		}
+
+		format := app.ReportJSON
+		if sendReportFlag != "" {
+			var err error
+			if format, err = app.ParseReportFormat(sendReportFlag); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to parse the report format. Error: %s\n", err)
+				cleanupAndExit(1)
+			}
+		}
+
+		// The report is written to stdout instead of the human readable output unless a file is specified
+		var out io.Writer = os.Stdout
+		var reportOut io.Writer
+		if sendReportFileFlag != "" {
+			file, err := os.Create(sendReportFileFlag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to create the report file. Error: %s\n", err)
+				cleanupAndExit(1)
+			}
+			defer file.Close()
+			reportOut = file
+		} else if sendReportFlag != "" {
+			out = io.Discard
+			reportOut = os.Stdout
+		}
+
+		report, sendErr := application.SendWith(appContext, out, app.SendOptions{DryRun: sendDryRunFlag})
+		metricsErr := writeMetricsTextfile(metrics, sendMetricsFlag)
+
+		if reportOut != nil {
+			if err := report.Write(reportOut, format); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write the report. Error: %s\n", err)
+				cleanupAndExit(1)
+			}
+		}
+
+		code := sendExitCode(report.Result, sendExitCodesFlag || reportOut != nil)
+		if sendErr != nil {
+			exitSendFailed(sendErr, code)
+		}
+
+		if metricsErr != nil {
 			cleanupAndExit(1)
 		}
+
+		if code != 0 {
+			cleanupAndExit(code)
+		}
 	},
 }
 
+// Return the exit code for the result of the send. Unless the distinct exit codes are requested only success (0)
+// and failure (1) are reported, so that e.g. a cron job does not fail when nothing was due.
+func sendExitCode(result app.SendResult, distinct bool) int {
+	if distinct {
+		return result.ExitCode()
+	}
+
+	switch result {
+	case app.ResultAllSent, app.ResultNothingToSend:
+		return 0
+	}
+	return 1
+}
+
+// Report the error and exit with the code, or 42 when sending was interrupted.
+func exitSendFailed(err error, code int) {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintf(os.Stderr, "Interrupted. Error: %s\n", err)
+		cleanupAndExit(42)
+	}
+	fmt.Fprintf(os.Stderr, "Failed to send. Error: %s\n", err)
+	cleanupAndExit(code)
+}
+
+// Write the metrics to the node_exporter textfile (when requested) and report any error.
+func writeMetricsTextfile(metrics *app.Metrics, filePath string) error {
+	if metrics == nil || filePath == "" {
+		return nil
+	}
+
+	if err := metrics.Registry().WriteTextfile(filePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write the metrics. Error: %s\n", err)
+		return err
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(sendCmd)
 
 	sendCmd.Flags().BoolVarP(&sendDryRunFlag, "dry-run", "n", false, "Tweets will not be sent to Twitter and also not be deleted")
+	sendCmd.Flags().StringVar(&sendReportFlag, "report", "", "Write a machine readable report of the send to stdout (instead of the normal output) in this format: json")
+	sendCmd.Flags().StringVar(&sendReportFileFlag, "report-file", "", "Write the report to this file instead of stdout (default format is json)")
+	sendCmd.Flags().BoolVar(&sendExitCodesFlag, "exit-codes", false, "Exit with a distinct code for each result (e.g. 3 when nothing was due), also without a report")
+	sendCmd.Flags().StringVar(&sendMetricsFlag, "metrics-textfile", "", "Write the metrics in the Prometheus text format to this file (for the node_exporter textfile collector)")
 }
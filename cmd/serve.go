@@ -0,0 +1,144 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/internal/server"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a REST API for managing the scheduled tweets",
+	Long: `Serve a REST API for managing the scheduled tweets, e.g. for a dashboard.
+
+Endpoints:
+  GET    /tweets                List the tweets. Supports the same filters as
+                                the list command as query parameters: status,
+                                tag, account, before, after, due, contains
+                                and limit.
+  POST   /tweets                Add a tweet. Media can not be attached, since
+                                the files would be read from the server.
+  GET    /tweets/{id}           Get a tweet by its identifier or unique prefix.
+  PATCH  /tweets/{id}           Change the message, scheduledTime, tags or
+                                status (paused or scheduled) of a tweet.
+                                Changing the message of a tweet that needs
+                                approval requires it to be approved again.
+  DELETE /tweets/{id}           Delete a tweet.
+  POST   /send?dry_run=true     Send the tweets that are due and return the
+                                send report (see ajtweet send --help).
+  GET    /openapi.json          The OpenAPI document describing the API.
+
+Authentication:
+  Every request (except for /openapi.json) needs the token configured in
+  serve.token (or the AJTWEET_SERVE_TOKEN environment variable) as the bearer
+  token, e.g. "Authorization: Bearer your_token". The API will not be
+  served without a token.
+
+  Errors are returned as JSON, e.g.
+  {"error": {"status": 404, "message": "Tweet does not exist in the list"}}
+
+Locking:
+  The lock used by the other commands is only held while a request is
+  handled, so the CLI can be used while the API is served. When another
+  command holds the lock the request fails with 423 Locked and should be
+  retried.
+
+The API is served on --listen (config path: serve.listen), which defaults to
+127.0.0.1:8080. Pressing Ctrl+C (SIGINT) or SIGTERM stops the server once the
+current requests have been handled.
+
+Examples:
+ AJTWEET_SERVE_TOKEN=your_token ajtweet serve
+ ajtweet serve --listen 127.0.0.1:9000
+
+ curl -H "Authorization: Bearer your_token" http://127.0.0.1:8080/tweets?due=true
+ curl -H "Authorization: Bearer your_token" -X POST \
+      -d '{"message": "Hello", "scheduledTime": "2022-06-01T12:00:00Z"}' \
+      http://127.0.0.1:8080/tweets
+`,
+	Args: cobra.NoArgs,
+	// The lock is acquired for each request instead of for as long as the server is running
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		handleSignals(true)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := application.Config().ServeToken(appContext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve the API token. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		handler, err := server.New(&application, token)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start the API server. Error: %s (set serve.token or AJTWEET_SERVE_TOKEN)\n", err)
+			cleanupAndExit(1)
+		}
+
+		listener, err := net.Listen("tcp", viper.GetString("serve.listen"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start the API server. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+
+		httpServer := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return appContext },
+		}
+
+		// Wait for the current requests to finish (and release the lock) before exiting
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			<-appContext.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			httpServer.Shutdown(ctx)
+		}()
+
+		fmt.Fprintf(os.Stdout, "Serving the API on http://%s\n", listener.Addr())
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Failed to serve the API. Error: %s\n", err)
+			cleanupAndExit(1)
+		}
+		<-stopped
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("listen", "127.0.0.1:8080", "The address to listen on")
+	bindFlag("serve.listen", serveCmd.Flags().Lookup("listen"))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package dotenv
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := `#!/bin/bash
+# usage: source .env
+export AJTWEET_API_KEY=your_consumer_api_key
+AJTWEET_API_SECRET = spaced   # comment
+
+EMPTY=
+SINGLE='literal # not a comment \n'
+DOUBLE="line\nbreak \"quoted\" \$HOME" # comment
+HASH=abc#def
+`
+	variables, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Variable{
+		{"AJTWEET_API_KEY", "your_consumer_api_key", 3},
+		{"AJTWEET_API_SECRET", "spaced", 4},
+		{"EMPTY", "", 6},
+		{"SINGLE", `literal # not a comment \n`, 7},
+		{"DOUBLE", "line\nbreak \"quoted\" $HOME", 8},
+		{"HASH", "abc#def", 9},
+	}
+
+	if !reflect.DeepEqual(variables, expected) {
+		t.Fatalf("Expected %v. Result: %v", expected, variables)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"NO_EQUALS",
+		"1NAME=value",
+		"BAD-NAME=value",
+		`OPEN="value`,
+		"OPEN='value",
+		`TRAILING="value" extra`,
+	}
+
+	for _, data := range tests {
+		if _, err := Parse(strings.NewReader(data)); !errors.Is(err, ErrSyntax) {
+			t.Fatalf("Expected error: %q for %q. Result: %v", ErrSyntax, data, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), ".ajtweet.env")
+	data := "AJTWEET_DOTENV_TEST_A=from-file\nAJTWEET_DOTENV_TEST_B=from-file\n"
+	if err := os.WriteFile(filePath, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("AJTWEET_DOTENV_TEST_A", "from-env")
+	os.Unsetenv("AJTWEET_DOTENV_TEST_B")
+	t.Cleanup(func() { os.Unsetenv("AJTWEET_DOTENV_TEST_B") })
+
+	names, err := Load(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"AJTWEET_DOTENV_TEST_B"}) {
+		t.Fatalf("Unexpected variables set: %v", names)
+	}
+
+	if value := os.Getenv("AJTWEET_DOTENV_TEST_A"); value != "from-env" {
+		t.Fatalf("Expected the environment to take precedence. Result: %q", value)
+	}
+
+	if value := os.Getenv("AJTWEET_DOTENV_TEST_B"); value != "from-file" {
+		t.Fatalf("Expected the variable to be set. Result: %q", value)
+	}
+
+	if _, err := Load(filePath + ".missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected error: %q. Result: %v", os.ErrNotExist, err)
+	}
+}
@@ -0,0 +1,333 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// hooks is an internal package that notifies other systems of events (e.g. a tweet was sent) by either
+// posting a JSON payload to a webhook or by running a local executable that receives the payload on stdin.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Event that hooks can be run for.
+type Event string
+
+const (
+	EventBeforeSend Event = "before_send" // A tweet is about to be sent. The hook can veto the send.
+	EventAfterSend  Event = "after_send"  // A tweet was sent to one of its destinations.
+	EventSendFailed Event = "send_failed" // Sending a tweet to one of its destinations failed.
+	EventQueueEmpty Event = "queue_empty" // The last scheduled tweet was sent.
+)
+
+// All the supported events.
+var Events = []Event{EventBeforeSend, EventAfterSend, EventSendFailed, EventQueueEmpty}
+
+var (
+	ErrUnknownEvent = errors.New("unknown hook event")
+	ErrInvalidHook  = errors.New("invalid hook")
+	ErrVetoed       = errors.New("vetoed by hook")
+)
+
+// Parse the event from the string, e.g. after_send.
+func ParseEvent(event string) (Event, error) {
+	for _, e := range Events {
+		if strings.EqualFold(strings.TrimSpace(event), string(e)) {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q (expected one of before_send, after_send, send_failed or queue_empty)", ErrUnknownEvent, event)
+}
+
+// The header containing the HMAC-SHA256 signature of the webhook body, e.g. sha256=5d41402abc4b2a76b9719d911017c592
+const SignatureHeader = "X-Ajtweet-Signature"
+
+// The header (and environment variable for commands) containing the event.
+const EventHeader = "X-Ajtweet-Event"
+const eventEnv = "AJTWEET_EVENT"
+
+// The default time a hook is allowed to run.
+const DefaultTimeout = 10 * time.Second
+
+// The delay before the first retry of a webhook, doubled for every following retry.
+var retryDelay = time.Second
+
+// Hook is either a webhook (URL) or a local executable (Command) that is run for an event.
+type Hook struct {
+	Event   Event
+	URL     string        // The URL the payload is posted to.
+	Secret  string        // The secret used to sign the webhook payload (see SignatureHeader). Optional.
+	Retries int           // The number of times a failed webhook is retried.
+	Command string        // The executable that receives the payload on stdin.
+	Args    []string      // The arguments passed to the executable.
+	Timeout time.Duration // The time the hook is allowed to run (including the retries). Default is DefaultTimeout.
+
+	// Return the secret used to sign the webhook payload, used instead of Secret when set. It is only called when
+	// the webhook is called, e.g. to only read the secret from a password manager when it is needed. Optional.
+	SecretFunc func(ctx context.Context) (string, error)
+}
+
+// Return the name used to describe the hook in errors.
+func (hook Hook) String() string {
+	if hook.URL != "" {
+		return fmt.Sprintf("%s webhook %s", hook.Event, hook.URL)
+	}
+	return fmt.Sprintf("%s command %s", hook.Event, hook.Command)
+}
+
+// Check that the hook is either a webhook or a command.
+func (hook Hook) Validate() error {
+	if _, err := ParseEvent(string(hook.Event)); err != nil {
+		return err
+	}
+
+	switch {
+	case hook.URL == "" && hook.Command == "":
+		return fmt.Errorf("%w: %s hook needs either a url or a command", ErrInvalidHook, hook.Event)
+	case hook.URL != "" && hook.Command != "":
+		return fmt.Errorf("%w: %s hook can not have both a url and a command", ErrInvalidHook, hook.Event)
+	case hook.URL != "" && !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://"):
+		return fmt.Errorf("%w: %s hook url %q must be an http or https URL", ErrInvalidHook, hook.Event, hook.URL)
+	case hook.Retries < 0:
+		return fmt.Errorf("%w: %s hook retries can not be negative", ErrInvalidHook, hook.Event)
+	}
+	return nil
+}
+
+// Tweet described by a Payload.
+type Tweet struct {
+	Id            string    `json:"id"`
+	Message       string    `json:"message"`
+	ScheduledTime time.Time `json:"scheduledTime"`
+	Account       string    `json:"account,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	Attempt       int       `json:"attempt,omitempty"`
+}
+
+// Payload is the JSON document describing the event that is sent to the hooks.
+type Payload struct {
+	Event    Event     `json:"event"`
+	Time     time.Time `json:"time"`
+	Tweet    *Tweet    `json:"tweet,omitempty"`
+	Account  string    `json:"account,omitempty"`  // The account the tweet was (or will be) sent from, e.g. mastodon:product.
+	Service  string    `json:"service,omitempty"`  // The service of the account, e.g. twitter.
+	RemoteId string    `json:"remoteId,omitempty"` // The identifier assigned by the service, e.g. the Twitter ID.
+	URL      string    `json:"url,omitempty"`      // The URL at which the tweet can be viewed.
+	Error    string    `json:"error,omitempty"`
+}
+
+// Runner runs the hooks configured for the events.
+type Runner struct {
+	hooks  []Hook
+	client *http.Client
+}
+
+// Create a Runner for the hooks that uses the http.Client to call the webhooks.
+// The hooks must be valid (see Hook.Validate).
+func NewRunner(hooks []Hook, client *http.Client) *Runner {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Runner{hooks: hooks, client: client}
+}
+
+// Return true when there are hooks for the event.
+func (r *Runner) Has(event Event) bool {
+	if r == nil {
+		return false
+	}
+	for _, hook := range r.hooks {
+		if hook.Event == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Run all the hooks for the payload's event in the order they were configured.
+// Every hook is run even when an earlier hook failed, the errors of the failed hooks are returned.
+// For before_send an error means the send was vetoed (see ErrVetoed), either by the hook or because the hook failed.
+func (r *Runner) Run(ctx context.Context, payload Payload) []error {
+	if r == nil {
+		return nil
+	}
+
+	if payload.Time.IsZero() {
+		payload.Time = time.Now().UTC()
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, hook := range r.hooks {
+		if hook.Event != payload.Event {
+			continue
+		}
+
+		if err := r.run(ctx, hook, body); err != nil {
+			if hook.Event == EventBeforeSend {
+				err = fmt.Errorf("%w: %s: %s", ErrVetoed, hook, err)
+			} else {
+				err = fmt.Errorf("%s: %w", hook, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (r *Runner) run(ctx context.Context, hook Hook, body []byte) error {
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if hook.URL != "" {
+		return r.post(ctx, hook, body)
+	}
+	return execute(ctx, hook, body)
+}
+
+// Post the body to the webhook, retrying when the request failed or the server responded with a 5xx or 429 status.
+func (r *Runner) post(ctx context.Context, hook Hook, body []byte) error {
+	if hook.SecretFunc != nil {
+		secret, err := hook.SecretFunc(ctx)
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+
+	delay := retryDelay
+	var err error
+	for attempt := 0; attempt <= hook.Retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w (after %d attempt(s): %s)", ctx.Err(), attempt, err)
+			case <-timer.C:
+			}
+			delay *= 2
+		}
+
+		var retry bool
+		if retry, err = r.postOnce(ctx, hook, body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *Runner) postOnce(ctx context.Context, hook Hook, body []byte) (retry bool, err error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "ajtweet")
+	request.Header.Set(EventHeader, string(hook.Event))
+	if hook.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(hook.Secret, body))
+	}
+
+	response, err := r.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		io.Copy(io.Discard, response.Body)
+		return false, nil
+	}
+
+	reason, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	err = fmt.Errorf("%s", response.Status)
+	if message := firstLine(reason); message != "" {
+		err = fmt.Errorf("%s: %s", response.Status, message)
+	}
+	retry = response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+	return retry, err
+}
+
+// Return the signature of the body, i.e. sha256= followed by the hex encoded HMAC-SHA256 using the secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run the command with the body on stdin. The command fails when it exits with a non-zero status.
+func execute(ctx context.Context, hook Hook, body []byte) error {
+	cmd := exec.CommandContext(ctx, hook.Command, hook.Args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(), eventEnv+"="+string(hook.Event))
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if message := firstLine(output.Bytes()); message != "" {
+			return fmt.Errorf("%w: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
+
+// Return the first non-empty line of the output (e.g. the reason for a veto), shortened to 200 characters.
+func firstLine(output []byte) string {
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if runes := []rune(line); len(runes) > 200 {
+			line = string(runes[:200]) + "..."
+		}
+		return line
+	}
+	return ""
+}
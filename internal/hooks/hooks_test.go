@@ -0,0 +1,221 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	retryDelay = time.Millisecond
+}
+
+func TestParseEvent(t *testing.T) {
+	if event, err := ParseEvent("After_Send"); err != nil || event != EventAfterSend {
+		t.Fatalf("Unexpected event: %q, error: %v", event, err)
+	}
+
+	if _, err := ParseEvent("after_tweet"); !errors.Is(err, ErrUnknownEvent) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrUnknownEvent, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		hook Hook
+		err  error
+	}{
+		{Hook{Event: EventAfterSend, URL: "https://example.com/hook"}, nil},
+		{Hook{Event: EventBeforeSend, Command: "/bin/true"}, nil},
+		{Hook{Event: "sent", URL: "https://example.com/hook"}, ErrUnknownEvent},
+		{Hook{Event: EventAfterSend}, ErrInvalidHook},
+		{Hook{Event: EventAfterSend, URL: "https://example.com/hook", Command: "/bin/true"}, ErrInvalidHook},
+		{Hook{Event: EventAfterSend, URL: "example.com/hook"}, ErrInvalidHook},
+		{Hook{Event: EventAfterSend, URL: "https://example.com/hook", Retries: -1}, ErrInvalidHook},
+	}
+
+	for _, test := range tests {
+		if err := test.hook.Validate(); !errors.Is(err, test.err) {
+			t.Fatalf("%v: Expected error: %v. Result: %v", test.hook, test.err, err)
+		}
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var received Payload
+	var signature, event string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		event = r.Header.Get(EventHeader)
+		if signature != Sign("secret", body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	runner := NewRunner([]Hook{
+		{Event: EventAfterSend, URL: server.URL, Secret: "secret"},
+		{Event: EventSendFailed, URL: server.URL + "/not-called"},
+	}, server.Client())
+
+	payload := Payload{
+		Event:    EventAfterSend,
+		Tweet:    &Tweet{Id: "a2fdb340", Message: "Hello"},
+		Service:  "twitter",
+		RemoteId: "1531989321873223680",
+		URL:      "https://twitter.com/i/web/status/1531989321873223680",
+	}
+	if errs := runner.Run(context.Background(), payload); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	if event != "after_send" || !strings.HasPrefix(signature, "sha256=") {
+		t.Fatalf("Unexpected headers. Event: %q, signature: %q", event, signature)
+	}
+
+	if received.Event != EventAfterSend || received.Tweet == nil || received.Tweet.Message != "Hello" ||
+		received.RemoteId != payload.RemoteId || received.URL != payload.URL || received.Time.IsZero() {
+		t.Fatalf("Unexpected payload: %+v", received)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	runner := NewRunner([]Hook{{Event: EventAfterSend, URL: server.URL, Retries: 2}}, server.Client())
+	if errs := runner.Run(context.Background(), Payload{Event: EventAfterSend}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	if requests != 3 {
+		t.Fatalf("Expected 3 requests. Result: %d", requests)
+	}
+
+	// Client errors are not retried
+	requests = 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad request", http.StatusBadRequest)
+	})
+
+	errs := runner.Run(context.Background(), Payload{Event: EventAfterSend})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "400 Bad Request: bad request") {
+		t.Fatalf("Unexpected errors: %v", errs)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected 1 request. Result: %d", requests)
+	}
+}
+
+func TestBeforeSendVeto(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "the CMS is not ready", http.StatusConflict)
+	}))
+	defer server.Close()
+
+	runner := NewRunner([]Hook{{Event: EventBeforeSend, URL: server.URL}}, server.Client())
+	errs := runner.Run(context.Background(), Payload{Event: EventBeforeSend})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrVetoed) || !strings.Contains(errs[0].Error(), "the CMS is not ready") {
+		t.Fatalf("Expected a veto. Result: %v", errs)
+	}
+
+	// A hook that can not be reached also vetoes the send
+	server.Close()
+	errs = runner.Run(context.Background(), Payload{Event: EventBeforeSend})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrVetoed) {
+		t.Fatalf("Expected a veto. Result: %v", errs)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "payload.json")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat > \"$1\"\nif [ \"$AJTWEET_EVENT\" = before_send ]; then\n  echo \"not today\"\n  exit 1\nfi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := NewRunner([]Hook{
+		{Event: EventAfterSend, Command: script, Args: []string{output}},
+		{Event: EventBeforeSend, Command: script, Args: []string{output}},
+	}, nil)
+
+	if errs := runner.Run(context.Background(), Payload{Event: EventAfterSend, RemoteId: "42"}); len(errs) != 0 {
+		t.Fatal(errs)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received Payload
+	if err := json.Unmarshal(data, &received); err != nil || received.RemoteId != "42" {
+		t.Fatalf("Unexpected payload: %s, error: %v", data, err)
+	}
+
+	errs := runner.Run(context.Background(), Payload{Event: EventBeforeSend})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrVetoed) || !strings.Contains(errs[0].Error(), "not today") {
+		t.Fatalf("Expected a veto. Result: %v", errs)
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	runner := NewRunner([]Hook{{Event: EventAfterSend, Command: "sleep", Args: []string{"5"}, Timeout: 50 * time.Millisecond}}, nil)
+
+	start := time.Now()
+	errs := runner.Run(context.Background(), Payload{Event: EventAfterSend})
+	if len(errs) != 1 || !errors.Is(errs[0], context.DeadlineExceeded) {
+		t.Fatalf("Expected error: %q. Result: %v", context.DeadlineExceeded, errs)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Fatal("Expected the command to be stopped")
+	}
+}
@@ -0,0 +1,256 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// logging is an internal package that provides structured logging with levels in either a text (logfmt)
+// or JSON format.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelOff // Nothing is logged.
+)
+
+func (level Level) String() string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelOff:
+		return "off"
+	}
+	return "level(" + strconv.Itoa(int(level)) + ")"
+}
+
+// Format of the log entries.
+type Format string
+
+const (
+	FormatText Format = "text" // One line of key=value pairs (logfmt) per entry.
+	FormatJSON Format = "json" // One JSON object per entry.
+)
+
+var (
+	ErrUnknownLevel  = errors.New("unknown log level")
+	ErrUnknownFormat = errors.New("unknown log format")
+)
+
+// Parse the level from a string, e.g. info.
+func ParseLevel(value string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	case "off", "none":
+		return LevelOff, nil
+	}
+	return LevelOff, fmt.Errorf("%w: %q (expected debug, info, warn, error or off)", ErrUnknownLevel, value)
+}
+
+// Parse the format from a string, e.g. json.
+func ParseFormat(value string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(value))); format {
+	case "":
+		return FormatText, nil
+	case FormatText, FormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q (expected text or json)", ErrUnknownFormat, value)
+}
+
+// Field is a key and value pair added to a log entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Create a new Field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger writes structured log entries at or above its level.
+// A nil *Logger is valid and discards all entries.
+type Logger struct {
+	out    *lockedWriter
+	level  Level
+	format Format
+	fields []Field
+	now    func() time.Time
+}
+
+// The writer is shared by a Logger and all the loggers derived from it using With.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Create a new Logger that writes the entries at or above the level to the writer.
+func New(out io.Writer, level Level, format Format) *Logger {
+	return &Logger{
+		out:    &lockedWriter{w: out},
+		level:  level,
+		format: format,
+		now:    time.Now,
+	}
+}
+
+// Return a new Logger that adds the fields to every entry.
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+
+	derived := *l
+	derived.fields = append(append([]Field(nil), l.fields...), fields...)
+	return &derived
+}
+
+// Return true when entries at the level will be written.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level != LevelOff && level >= l.level
+}
+
+func (l *Logger) Debug(message string, fields ...Field) {
+	l.log(LevelDebug, message, fields)
+}
+
+func (l *Logger) Info(message string, fields ...Field) {
+	l.log(LevelInfo, message, fields)
+}
+
+func (l *Logger) Warn(message string, fields ...Field) {
+	l.log(LevelWarn, message, fields)
+}
+
+func (l *Logger) Error(message string, fields ...Field) {
+	l.log(LevelError, message, fields)
+}
+
+func (l *Logger) log(level Level, message string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	all := make([]Field, 0, 3+len(l.fields)+len(fields))
+	all = append(all, F("time", l.now().UTC().Format(time.RFC3339Nano)), F("level", level.String()), F("msg", message))
+	all = append(all, l.fields...)
+	all = append(all, fields...)
+
+	var entry bytes.Buffer
+	if l.format == FormatJSON {
+		writeJSON(&entry, all)
+	} else {
+		writeText(&entry, all)
+	}
+	entry.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(entry.Bytes())
+}
+
+func writeText(b *bytes.Buffer, fields []Field) {
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(field.Key)
+		b.WriteByte('=')
+
+		value := formatValue(field.Value)
+		if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+}
+
+func writeJSON(b *bytes.Buffer, fields []Field) {
+	b.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.Key)
+		b.Write(key)
+		b.WriteByte(':')
+
+		var value interface{} = field.Value
+		switch v := field.Value.(type) {
+		case error, time.Duration, fmt.Stringer:
+			value = formatValue(v)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			data, _ = json.Marshal(formatValue(field.Value))
+		}
+		b.Write(data)
+	}
+	b.WriteByte('}')
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
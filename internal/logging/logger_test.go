@@ -0,0 +1,113 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLogger(out *bytes.Buffer, level Level, format Format) *Logger {
+	logger := New(out, level, format)
+	logger.now = func() time.Time {
+		return time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+	return logger
+}
+
+func TestText(t *testing.T) {
+	var out bytes.Buffer
+	logger := newTestLogger(&out, LevelInfo, FormatText).With(F("tweet_id", "8b57969"))
+
+	logger.Info("Tweet sent", F("account", "mastodon:product"), F("duration", 1500*time.Millisecond))
+	logger.Warn("Failed to send tweet", F("error", errors.New("service unavailable")), F("attempt", 2))
+
+	expected := `time=2022-06-01T12:00:00Z level=info msg="Tweet sent" tweet_id=8b57969 account=mastodon:product duration=1.5s
+time=2022-06-01T12:00:00Z level=warn msg="Failed to send tweet" tweet_id=8b57969 error="service unavailable" attempt=2
+`
+	if out.String() != expected {
+		t.Fatalf("Expected:\n%s\nResult:\n%s", expected, out.String())
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var out bytes.Buffer
+	logger := newTestLogger(&out, LevelDebug, FormatJSON)
+
+	logger.Debug("Tweet sent", F("twitter_id", "1234"), F("attempt", 1), F("duration", time.Second), F("account", ""))
+
+	expected := `{"time":"2022-06-01T12:00:00Z","level":"debug","msg":"Tweet sent","twitter_id":"1234","attempt":1,"duration":"1s","account":""}` + "\n"
+	if out.String() != expected {
+		t.Fatalf("Expected: %s\nResult: %s", expected, out.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	var out bytes.Buffer
+	logger := newTestLogger(&out, LevelWarn, FormatText)
+
+	logger.Debug("debug")
+	logger.Info("info")
+	if out.Len() != 0 {
+		t.Fatalf("Expected nothing to be logged. Result: %q", out.String())
+	}
+
+	logger.Error("error")
+	if out.Len() == 0 {
+		t.Fatal("Expected the error to be logged")
+	}
+
+	var nilLogger *Logger
+	nilLogger.With(F("key", "value")).Error("discarded")
+
+	off := newTestLogger(&out, LevelOff, FormatText)
+	if off.Enabled(LevelError) {
+		t.Fatal("Expected nothing to be enabled")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if level, err := ParseLevel("WARNING"); err != nil || level != LevelWarn {
+		t.Fatalf("Expected warn. Result: %s, %v", level, err)
+	}
+
+	if _, err := ParseLevel("verbose"); !errors.Is(err, ErrUnknownLevel) {
+		t.Fatalf("Expected ErrUnknownLevel. Result: %v", err)
+	}
+
+	if format, err := ParseFormat("JSON"); err != nil || format != FormatJSON {
+		t.Fatalf("Expected json. Result: %s, %v", format, err)
+	}
+
+	if _, err := ParseFormat("xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Expected ErrUnknownFormat. Result: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// metrics is an internal package that provides counters, gauges and histograms that can be exposed in the
+// Prometheus text format, either over HTTP or as a file for the node_exporter textfile collector.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds the metrics that will be exposed.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*metric
+}
+
+// Create a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type metricKind string
+
+const (
+	kindCounter   metricKind = "counter"
+	kindGauge     metricKind = "gauge"
+	kindHistogram metricKind = "histogram"
+)
+
+// A metric (family) and all of its series keyed by the label values.
+type metric struct {
+	name       string
+	help       string
+	kind       metricKind
+	labelNames []string
+	buckets    []float64 // Upper bounds of the histogram buckets.
+	series     map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64  // The value of a counter or gauge.
+	counts      []uint64 // The cumulative count per bucket of a histogram.
+	count       uint64
+	sum         float64
+}
+
+func (r *Registry) register(m *metric) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m.series = make(map[string]*series)
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// Return the series for the label values, creating it when needed. The registry must be locked.
+func (m *metric) get(labelValues []string) *series {
+	if len(labelValues) != len(m.labelNames) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", m.name, len(m.labelNames), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, exists := m.series[key]
+	if !exists {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if m.kind == kindHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// Counter is a value that only increases, e.g. the number of tweets sent.
+type Counter struct {
+	registry *Registry
+	metric   *metric
+}
+
+// Register a new Counter with the name, help text and the names of the labels.
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	return &Counter{registry: r, metric: r.register(&metric{name: name, help: help, kind: kindCounter, labelNames: labelNames})}
+}
+
+// Add one to the series with the label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add the (non-negative) value to the series with the label values.
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		panic("metrics: a counter can not be decreased")
+	}
+
+	c.registry.mu.Lock()
+	defer c.registry.mu.Unlock()
+	c.metric.get(labelValues).value += value
+}
+
+// Gauge is a value that can go up and down, e.g. the number of tweets in the queue.
+type Gauge struct {
+	registry *Registry
+	metric   *metric
+}
+
+// Register a new Gauge with the name, help text and the names of the labels.
+func (r *Registry) NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return &Gauge{registry: r, metric: r.register(&metric{name: name, help: help, kind: kindGauge, labelNames: labelNames})}
+}
+
+// Set the value of the series with the label values.
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.registry.mu.Lock()
+	defer g.registry.mu.Unlock()
+	g.metric.get(labelValues).value = value
+}
+
+// Histogram counts observations (e.g. request durations) in buckets.
+type Histogram struct {
+	registry *Registry
+	metric   *metric
+}
+
+// The default buckets for durations in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Register a new Histogram with the name, help text, the upper bounds of the buckets and the names of the labels.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{registry: r, metric: r.register(&metric{name: name, help: help, kind: kindHistogram,
+		labelNames: labelNames, buckets: sorted})}
+}
+
+// Add the observed value to the series with the label values.
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+
+	s := h.metric.get(labelValues)
+	for i, bound := range h.metric.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Write all the metrics in the Prometheus text exposition format (version 0.0.4).
+func (r *Registry) Write(out io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+	for _, m := range r.metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+
+		keys := make([]string, 0, len(m.series))
+		for key := range m.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := m.series[key]
+			if m.kind != kindHistogram {
+				fmt.Fprintf(w, "%s%s %s\n", m.name, labels(m.labelNames, s.labelValues), formatFloat(s.value))
+				continue
+			}
+
+			bucketNames := append(append([]string(nil), m.labelNames...), "le")
+			bucketValues := append(append([]string(nil), s.labelValues...), "")
+			for i, bound := range m.buckets {
+				bucketValues[len(bucketValues)-1] = formatFloat(bound)
+				fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, labels(bucketNames, bucketValues), s.counts[i])
+			}
+			bucketValues[len(bucketValues)-1] = "+Inf"
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, labels(bucketNames, bucketValues), s.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", m.name, labels(m.labelNames, s.labelValues), formatFloat(s.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", m.name, labels(m.labelNames, s.labelValues), s.count)
+		}
+	}
+	return w.Flush()
+}
+
+// Return an http.Handler that serves the metrics, e.g. on /metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// Write the metrics to the file used by the node_exporter textfile collector.
+// The file is written atomically (to a temporary file that is renamed) so that a partial file is never collected.
+func (r *Registry) WriteTextfile(filePath string) error {
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := r.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+func labels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", name, labelEscaper.Replace(values[i]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	registry := NewRegistry()
+
+	sends := registry.NewCounter("ajtweet_sends_total", "The number of tweets sent by outcome.", "outcome")
+	sends.Inc("sent")
+	sends.Add(2, "failed")
+	sends.Inc("sent")
+
+	due := registry.NewGauge("ajtweet_due_tweets", "The number of tweets that are due.")
+	due.Set(3)
+
+	latency := registry.NewHistogram("ajtweet_api_request_duration_seconds", "API latency.", []float64{1, 0.5}, "service")
+	latency.Observe(0.25, "twitter")
+	latency.Observe(0.75, "twitter")
+	latency.Observe(2, "twitter")
+
+	var buffer bytes.Buffer
+	if err := registry.Write(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `# HELP ajtweet_sends_total The number of tweets sent by outcome.
+# TYPE ajtweet_sends_total counter
+ajtweet_sends_total{outcome="failed"} 2
+ajtweet_sends_total{outcome="sent"} 2
+# HELP ajtweet_due_tweets The number of tweets that are due.
+# TYPE ajtweet_due_tweets gauge
+ajtweet_due_tweets 3
+# HELP ajtweet_api_request_duration_seconds API latency.
+# TYPE ajtweet_api_request_duration_seconds histogram
+ajtweet_api_request_duration_seconds_bucket{service="twitter",le="0.5"} 1
+ajtweet_api_request_duration_seconds_bucket{service="twitter",le="1"} 2
+ajtweet_api_request_duration_seconds_bucket{service="twitter",le="+Inf"} 3
+ajtweet_api_request_duration_seconds_sum{service="twitter"} 3
+ajtweet_api_request_duration_seconds_count{service="twitter"} 3
+`
+	if buffer.String() != expected {
+		t.Fatalf("Expected:\n%s\nResult:\n%s", expected, buffer.String())
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("test", "Test.", "account").Set(1, "a\"b\\c\n")
+
+	var buffer bytes.Buffer
+	registry.Write(&buffer)
+
+	if !strings.Contains(buffer.String(), `test{account="a\"b\\c\n"} 1`) {
+		t.Fatalf("Expected the label value to be escaped. Result: %s", buffer.String())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("test", "Test.").Set(1)
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/plain; version=0.0.4") ||
+		!strings.Contains(recorder.Body.String(), "test 1\n") {
+		t.Fatalf("Unexpected response: %v %s", recorder.Header(), recorder.Body.String())
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGauge("test", "Test.").Set(1)
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "ajtweet.prom")
+	if err := registry.WriteTextfile(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "test 1\n") {
+		t.Fatalf("Unexpected file content: %s", data)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected the temporary file to be removed. Result: %v", entries)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// secrets is an internal package that stores named secrets (e.g. API keys) in a file encrypted with
+// NaCl secretbox (XSalsa20 and Poly1305) using a key derived from a passphrase with scrypt.
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// The prefix used in the configuration to reference a secret by name, e.g. secret://twitter-api-key
+const Scheme = "secret://"
+
+var (
+	ErrNotFound        = errors.New("secret not found")
+	ErrInvalidName     = errors.New("invalid secret name")
+	ErrNoPassphrase    = errors.New("no passphrase")
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupted secrets file")
+	ErrUnsupported     = errors.New("unsupported secrets file")
+)
+
+const (
+	fileVersion = 1
+	kdfScrypt   = "scrypt"
+	saltSize    = 16
+	keySize     = 32
+	nonceSize   = 24
+)
+
+// The scrypt cost parameters used when the secrets are saved.
+var (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// The file as stored on disk. Only the salt, cost parameters and nonce are stored in plaintext.
+type file struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Box     []byte `json:"box"`
+}
+
+// Store of named secrets that is encrypted when saved to a file.
+type Store struct {
+	path       string
+	passphrase []byte
+	secrets    map[string]string
+}
+
+// Open the secrets file and decrypt it using the passphrase.
+// An empty Store is returned when the file does not exist yet.
+func Open(filePath string, passphrase []byte) (*Store, error) {
+	if len(passphrase) == 0 {
+		return nil, ErrNoPassphrase
+	}
+
+	store := &Store{
+		path:       filePath,
+		passphrase: passphrase,
+		secrets:    make(map[string]string),
+	}
+
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+
+	if f.Version != fileVersion || f.KDF != kdfScrypt || len(f.Nonce) != nonceSize {
+		return nil, fmt.Errorf("%w: version %d, kdf %q", ErrUnsupported, f.Version, f.KDF)
+	}
+
+	key, err := deriveKey(passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], f.Nonce)
+
+	plaintext, ok := secretbox.Open(nil, f.Box, &nonce, key)
+	if !ok {
+		return nil, ErrWrongPassphrase
+	}
+
+	if err := json.Unmarshal(plaintext, &store.secrets); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+	return store, nil
+}
+
+// Return the value of the named secret.
+func (s *Store) Get(name string) (string, error) {
+	value, exists := s.secrets[name]
+	if !exists {
+		return "", fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return value, nil
+}
+
+// Set the value of the named secret. Call Save to write the change to the file.
+func (s *Store) Set(name string, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	s.secrets[name] = value
+	return nil
+}
+
+// Delete the named secret. Call Save to write the change to the file.
+func (s *Store) Delete(name string) error {
+	if _, exists := s.secrets[name]; !exists {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	delete(s.secrets, name)
+	return nil
+}
+
+// Return the names of the secrets in alphabetical order.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.secrets))
+	for name := range s.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encrypt the secrets and write them to the file (readable only by the owner).
+// A new salt and nonce are used each time the secrets are saved.
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return err
+	}
+
+	f := file{
+		Version: fileVersion,
+		KDF:     kdfScrypt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltSize),
+		Nonce:   make([]byte, nonceSize),
+	}
+
+	if _, err := io.ReadFull(rand.Reader, f.Salt); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(rand.Reader, f.Nonce); err != nil {
+		return err
+	}
+
+	key, err := deriveKey(s.passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return err
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], f.Nonce)
+	f.Box = secretbox.Seal(nil, plaintext, &nonce, key)
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.path, data)
+}
+
+// Check that the name can be used to reference a secret, e.g. twitter-api-key
+func ValidateName(name string) error {
+	if name == "" || strings.TrimSpace(name) != name || strings.ContainsAny(name, "/\\\n\r\t") {
+		return fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	return nil
+}
+
+// Return the name of the secret referenced by the value (e.g. secret://twitter-api-key) and
+// whether the value is a reference.
+func ParseRef(value string) (string, bool) {
+	if !strings.HasPrefix(value, Scheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, Scheme), true
+}
+
+// Read the passphrase from the key file. Trailing new lines are ignored.
+func ReadKeyFile(filePath string) ([]byte, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := []byte(strings.TrimRight(string(data), "\r\n"))
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("%w: the key file %q is empty", ErrNoPassphrase, filePath)
+	}
+	return passphrase, nil
+}
+
+func deriveKey(passphrase []byte, salt []byte, n int, r int, p int) (*[keySize]byte, error) {
+	derived, err := scrypt.Key(passphrase, salt, n, r, p, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+
+	var key [keySize]byte
+	copy(key[:], derived)
+	return &key, nil
+}
+
+// Atomically replace the file so that the secrets are never left half written.
+func writeFile(filePath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func init() {
+	// Keep the tests fast, the cost is stored in the file
+	scryptN = 1 << 10
+}
+
+func TestStore(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.json")
+	passphrase := []byte("correct horse battery staple")
+
+	store, err := Open(filePath, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.Names()) != 0 {
+		t.Fatalf("Expected an empty store. Result: %v", store.Names())
+	}
+
+	if err := store.Set("twitter-api-key", "key-1234"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("mastodon-token", "token-5678"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Fatalf("Expected the file to only be readable by the owner. Result: %v", mode)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("key-1234")) || bytes.Contains(data, []byte("twitter-api-key")) {
+		t.Fatalf("Expected the secrets to be encrypted. Result: %s", data)
+	}
+
+	reopened, err := Open(filePath, passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := reopened.Names(); !reflect.DeepEqual(names, []string{"mastodon-token", "twitter-api-key"}) {
+		t.Fatalf("Unexpected names: %v", names)
+	}
+
+	if value, err := reopened.Get("twitter-api-key"); err != nil || value != "key-1234" {
+		t.Fatalf("Unexpected value: %q, error: %v", value, err)
+	}
+
+	if err := reopened.Delete("mastodon-token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("mastodon-token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNotFound, err)
+	}
+	if err := reopened.Delete("mastodon-token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNotFound, err)
+	}
+}
+
+func TestOpenWrongPassphrase(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "secrets.json")
+
+	store, err := Open(filePath, []byte("right"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("name", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open(filePath, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrWrongPassphrase, err)
+	}
+
+	if _, err := Open(filePath, nil); !errors.Is(err, ErrNoPassphrase) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNoPassphrase, err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"", " name", "a/b", "line\nbreak"} {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidName) {
+			t.Fatalf("Expected error for %q. Result: %v", name, err)
+		}
+	}
+
+	if err := ValidateName("twitter-api-key"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	if name, ok := ParseRef("secret://twitter-api-key"); !ok || name != "twitter-api-key" {
+		t.Fatalf("Unexpected reference: %q, %v", name, ok)
+	}
+
+	if _, ok := ParseRef("plain-value"); ok {
+		t.Fatal("Expected a plain value not to be a reference")
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "key")
+	if err := os.WriteFile(filePath, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if passphrase, err := ReadKeyFile(filePath); err != nil || string(passphrase) != "s3cret" {
+		t.Fatalf("Unexpected passphrase: %q, error: %v", passphrase, err)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadKeyFile(empty); !errors.Is(err, ErrNoPassphrase) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrNoPassphrase, err)
+	}
+}
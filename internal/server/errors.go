@@ -0,0 +1,93 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+// An error along with the HTTP status code it is reported with.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+func statusError(status int, err error) error {
+	return &httpError{status: status, err: err}
+}
+
+// Report the error returned by the Application as a bad request, unless it is known to mean something else.
+func badRequest(err error) error {
+	if err == nil {
+		return nil
+	}
+	return statusError(http.StatusBadRequest, err)
+}
+
+// The JSON body of an error response.
+type errorBody struct {
+	Error errorDetails `json:"error"`
+}
+
+type errorDetails struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Return the HTTP status code for the error.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, tweet.ErrNotExists):
+		return http.StatusNotFound
+	case errors.Is(err, app.ErrLockfileExists):
+		return http.StatusLocked
+	case errors.Is(err, app.ErrAlreadyDelivered):
+		return http.StatusConflict
+	}
+
+	var statusErr *httpError
+	if errors.As(err, &statusErr) {
+		return statusErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := errorStatus(err)
+	if status == http.StatusLocked {
+		w.Header().Set("Retry-After", "1")
+	}
+	writeJSON(w, status, errorBody{Error: errorDetails{Status: status, Message: err.Error()}})
+}
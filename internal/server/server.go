@@ -0,0 +1,378 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// server is an internal package that provides a REST API for managing the scheduled tweets.
+// Every request acquires the same lock as the CLI commands so that the API and the CLI can be used at the same time.
+package server
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+)
+
+// The OpenAPI document describing the API, served on /openapi.json
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// The maximum size of a request body.
+const maxBodySize = 1 << 20
+
+var (
+	ErrMissingToken = errors.New("a bearer token is required")
+	// Media is read from the server's file system when the tweet is sent, which would allow any file readable
+	// by the server to be uploaded publicly.
+	ErrMediaNotAllowed = errors.New("media can not be attached using the REST API, use ajtweet add --media instead")
+)
+
+// Server is the http.Handler serving the REST API.
+type Server struct {
+	application *app.Application
+	token       string
+	mux         *http.ServeMux
+
+	// The Application is not safe for concurrent use, requests are handled one at a time.
+	mu sync.Mutex
+}
+
+// Create a new Server for the Application. The clients must send the token as the bearer token.
+func New(application *app.Application, token string) (*Server, error) {
+	if token == "" {
+		return nil, ErrMissingToken
+	}
+
+	server := &Server{application: application, token: token, mux: http.NewServeMux()}
+	server.mux.HandleFunc("/openapi.json", server.handleOpenAPI)
+	server.mux.Handle("/tweets", server.authenticated(server.handleTweets))
+	server.mux.Handle("/tweets/", server.authenticated(server.handleTweet))
+	server.mux.Handle("/send", server.authenticated(server.handleSend))
+	server.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, statusError(http.StatusNotFound, fmt.Errorf("%s was not found", r.URL.Path)))
+	})
+	return server, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mux.ServeHTTP(w, r)
+}
+
+// Require the bearer token.
+func (server *Server) authenticated(handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := cutPrefixFold(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(server.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ajtweet"`)
+			writeError(w, statusError(http.StatusUnauthorized, errors.New("a valid bearer token is required")))
+			return
+		}
+		handler(w, r)
+	})
+}
+
+// Call fn while holding the lock shared with the CLI commands, using the latest tweets from the datastore.
+func (server *Server) withLock(fn func() error) error {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.application.WithLock(fn)
+}
+
+func (server *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPI)
+}
+
+// GET /tweets and POST /tweets
+func (server *Server) handleTweets(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		filter, err := parseFilter(r)
+		if err != nil {
+			writeError(w, statusError(http.StatusBadRequest, err))
+			return
+		}
+
+		var tweets []tweet.Tweet
+		err = server.withLock(func() error {
+			var err error
+			tweets, err = server.application.Query(filter)
+			return badRequest(err)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if tweets == nil {
+			tweets = []tweet.Tweet{}
+		}
+		writeJSON(w, http.StatusOK, tweetList{Tweets: tweets})
+		return
+	}
+
+	var request addRequest
+	if err := decodeBody(r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	if len(request.Media) > 0 {
+		writeError(w, badRequest(ErrMediaNotAllowed))
+		return
+	}
+
+	var added tweet.Tweet
+	err := server.withLock(func() error {
+		var err error
+		if added, err = server.application.AddTweet(request.Message, request.ScheduledTime, request.options()); err != nil {
+			return badRequest(err)
+		}
+		return server.application.Save()
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", "/tweets/"+added.Id.String())
+	writeJSON(w, http.StatusCreated, added)
+}
+
+// GET, PATCH and DELETE /tweets/{id}
+func (server *Server) handleTweet(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete) {
+		return
+	}
+
+	idString := strings.TrimPrefix(r.URL.Path, "/tweets/")
+	if idString == "" || strings.Contains(idString, "/") {
+		writeError(w, statusError(http.StatusNotFound, fmt.Errorf("%s was not found", r.URL.Path)))
+		return
+	}
+
+	var changes app.TweetChanges
+	if r.Method == http.MethodPatch {
+		var request editRequest
+		if err := decodeBody(r, &request); err != nil {
+			writeError(w, err)
+			return
+		}
+		changes = request.changes()
+	}
+
+	var result tweet.Tweet
+	err := server.withLock(func() error {
+		tw, err := server.application.Get(idString)
+		if err != nil {
+			return badRequest(err)
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			result = tw
+			return nil
+
+		case http.MethodPatch:
+			if result, err = server.application.Edit(tw.Id, changes); err != nil {
+				return badRequest(err)
+			}
+
+		case http.MethodDelete:
+			if err := server.application.Delete(tw.Id.String()); err != nil {
+				return badRequest(err)
+			}
+		}
+		return server.application.Save()
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// POST /send?dry_run=true
+func (server *Server) handleSend(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+
+	options := app.SendOptions{}
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, statusError(http.StatusBadRequest, fmt.Errorf("dry_run: %w", err)))
+			return
+		}
+		options.DryRun = dryRun
+	}
+
+	var report *app.SendReport
+	err := server.withLock(func() error {
+		// Failures are described by the report
+		report, _ = server.application.SendWith(r.Context(), io.Discard, options)
+		return nil
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+type tweetList struct {
+	Tweets []tweet.Tweet `json:"tweets"`
+}
+
+// The body of POST /tweets
+type addRequest struct {
+	Message       string   `json:"message"`
+	ScheduledTime string   `json:"scheduledTime"`
+	Account       string   `json:"account"`
+	To            []string `json:"to"`
+	Media         []string `json:"media"` // Rejected, see ErrMediaNotAllowed.
+	InReplyTo     string   `json:"inReplyTo"`
+	Tags          []string `json:"tags"`
+	Draft         bool     `json:"draft"`
+	NeedsApproval bool     `json:"needsApproval"`
+}
+
+func (request addRequest) options() app.AddOptions {
+	return app.AddOptions{
+		Draft:         request.Draft,
+		NeedsApproval: request.NeedsApproval,
+		Account:       request.Account,
+		Destinations:  request.To,
+		InReplyTo:     request.InReplyTo,
+		Tags:          request.Tags,
+	}
+}
+
+// The body of PATCH /tweets/{id}
+type editRequest struct {
+	Message       *string   `json:"message"`
+	ScheduledTime *string   `json:"scheduledTime"`
+	Tags          *[]string `json:"tags"`
+	Status        *string   `json:"status"`
+	Force         bool      `json:"force"`
+}
+
+func (request editRequest) changes() app.TweetChanges {
+	return app.TweetChanges{
+		Message:       request.Message,
+		ScheduledTime: request.ScheduledTime,
+		Tags:          request.Tags,
+		Status:        request.Status,
+		Force:         request.Force,
+	}
+}
+
+// Parse the query parameters of GET /tweets into a Filter.
+func parseFilter(r *http.Request) (app.Filter, error) {
+	query := r.URL.Query()
+	filter := app.Filter{
+		Before:   query.Get("before"),
+		After:    query.Get("after"),
+		Contains: query.Get("contains"),
+		Tags:     query["tag"],
+		Accounts: query["account"],
+		Statuses: query["status"],
+	}
+
+	if value := query.Get("due"); value != "" {
+		due, err := strconv.ParseBool(value)
+		if err != nil {
+			return app.Filter{}, fmt.Errorf("due: %w", err)
+		}
+		filter.Due = due
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return app.Filter{}, fmt.Errorf("limit: %q is not a positive number", value)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// Decode the JSON request body, unknown fields are rejected to catch typos.
+func decodeBody(r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return statusError(http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+	}
+	return nil
+}
+
+// Respond with 405 Method Not Allowed when the request's method is not one of the methods.
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, statusError(http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method)))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func cutPrefixFold(value string, prefix string) (string, bool) {
+	if len(value) < len(prefix) || !strings.EqualFold(value[:len(prefix)], prefix) {
+		return "", false
+	}
+	return value[len(prefix):], true
+}
@@ -0,0 +1,319 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andrejacobs/ajtweet-cli/app"
+	"github.com/andrejacobs/ajtweet-cli/internal/tweet"
+	"github.com/andrejacobs/ajtweet-cli/internal/twittertest"
+)
+
+const testToken = "secret-token"
+
+type testServer struct {
+	*httptest.Server
+	config app.Config
+	mock   *twittertest.Server
+}
+
+func newTestServer(t *testing.T) *testServer {
+	mock := twittertest.NewServer(twittertest.Options{})
+	mockServer := httptest.NewServer(mock)
+	t.Cleanup(mockServer.Close)
+
+	dir := t.TempDir()
+	config := app.NewConfig()
+	config.Datastore.Filepath = filepath.Join(dir, "tweets.json")
+	config.Lockfile = filepath.Join(dir, "ajtweet.lock")
+	config.Send.Delay = 0
+	config.Send.APIBaseURL = mockServer.URL
+	config.Send.Authentication = app.Authentication{
+		APIKey:    "key",
+		APISecret: "secret",
+		OAuth1:    app.OAuth1{Token: "token", Secret: "token-secret"},
+	}
+
+	application := &app.Application{}
+	if err := application.Configure(config); err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := New(application, testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &testServer{Server: server, config: config, mock: mock}
+}
+
+// Make the request with the token and decode the JSON response into result (unless nil).
+func (server *testServer) do(t *testing.T, method string, path string, body interface{}, result interface{}) *http.Response {
+	t.Helper()
+
+	var reader bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reader).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request, err := http.NewRequest(method, server.URL+path, &reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set("Authorization", "Bearer "+testToken)
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if result != nil {
+		if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return response
+}
+
+func TestNewRequiresToken(t *testing.T) {
+	if _, err := New(&app.Application{}, ""); !errors.Is(err, ErrMissingToken) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrMissingToken, err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server := newTestServer(t)
+
+	for _, authorization := range []string{"", "Bearer wrong", "Basic " + testToken} {
+		request, _ := http.NewRequest(http.MethodGet, server.URL+"/tweets", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		response, err := server.Client().Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusUnauthorized || response.Header.Get("WWW-Authenticate") == "" {
+			t.Fatalf("%q: Expected 401 Unauthorized. Result: %s", authorization, response.Status)
+		}
+	}
+
+	// The OpenAPI document does not need the token
+	response, err := server.Client().Get(server.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	var document map[string]interface{}
+	if err := json.NewDecoder(response.Body).Decode(&document); err != nil || document["openapi"] != "3.0.3" {
+		t.Fatalf("Unexpected OpenAPI document: %v, error: %v", document, err)
+	}
+}
+
+func TestTweets(t *testing.T) {
+	server := newTestServer(t)
+
+	// Add
+	var added tweet.Tweet
+	response := server.do(t, http.MethodPost, "/tweets", map[string]interface{}{
+		"message":       "Hello from the API",
+		"scheduledTime": "2030-01-01T10:00:00Z",
+		"tags":          []string{"campaign-x"},
+	}, &added)
+	if response.StatusCode != http.StatusCreated || response.Header.Get("Location") != "/tweets/"+added.Id.String() ||
+		added.Message != "Hello from the API" || added.Status != tweet.StatusScheduled || len(added.Tags) != 1 {
+		t.Fatalf("Unexpected response: %s %v", response.Status, added)
+	}
+
+	// The tweet was saved to the datastore used by the CLI
+	saved := tweet.TweetList{}
+	if err := saved.Load(server.config.Datastore.Filepath); err != nil || len(saved.Tweets) != 1 {
+		t.Fatalf("Expected the tweet to be saved. Result: %v, error: %v", saved.Tweets, err)
+	}
+
+	// List
+	var list tweetList
+	server.do(t, http.MethodGet, "/tweets?tag=campaign-x", nil, &list)
+	if len(list.Tweets) != 1 || list.Tweets[0].Id != added.Id {
+		t.Fatalf("Unexpected list: %v", list)
+	}
+
+	server.do(t, http.MethodGet, "/tweets?due=true", nil, &list)
+	if len(list.Tweets) != 0 {
+		t.Fatalf("Expected no due tweets. Result: %v", list)
+	}
+
+	// Get using the short identifier
+	var got tweet.Tweet
+	response = server.do(t, http.MethodGet, "/tweets/"+added.Id.String()[:8], nil, &got)
+	if response.StatusCode != http.StatusOK || got.Id != added.Id {
+		t.Fatalf("Unexpected response: %s %v", response.Status, got)
+	}
+
+	// Edit
+	var edited tweet.Tweet
+	response = server.do(t, http.MethodPatch, "/tweets/"+added.Id.String(), map[string]interface{}{
+		"message": "Hello again",
+		"status":  "paused",
+	}, &edited)
+	if response.StatusCode != http.StatusOK || edited.Message != "Hello again" || edited.Status != tweet.StatusPaused ||
+		!edited.ScheduledTime.Equal(added.ScheduledTime) {
+		t.Fatalf("Unexpected response: %s %v", response.Status, edited)
+	}
+
+	// Delete
+	response = server.do(t, http.MethodDelete, "/tweets/"+added.Id.String(), nil, nil)
+	if response.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204 No Content. Result: %s", response.Status)
+	}
+
+	var errorResponse errorBody
+	response = server.do(t, http.MethodGet, "/tweets/"+added.Id.String(), nil, &errorResponse)
+	if response.StatusCode != http.StatusNotFound || errorResponse.Error.Status != http.StatusNotFound ||
+		!strings.Contains(errorResponse.Error.Message, "does not exist") {
+		t.Fatalf("Unexpected response: %s %v", response.Status, errorResponse)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{http.MethodPost, "/tweets", map[string]interface{}{"message": "Hello", "scheduledTime": "tomorrow"}, http.StatusBadRequest},
+		{http.MethodPost, "/tweets", map[string]interface{}{"message": "Hello", "when": "2030-01-01T10:00:00Z"}, http.StatusBadRequest},
+		{http.MethodPost, "/tweets", map[string]interface{}{"message": "Hello", "scheduledTime": "2030-01-01T10:00:00Z", "account": "unknown"}, http.StatusBadRequest},
+		{http.MethodPost, "/tweets", map[string]interface{}{"message": "Hello", "scheduledTime": "2030-01-01T10:00:00Z", "media": []string{"/etc/passwd"}}, http.StatusBadRequest},
+		{http.MethodGet, "/tweets?limit=-1", nil, http.StatusBadRequest},
+		{http.MethodGet, "/tweets?status=unknown", nil, http.StatusBadRequest},
+		{http.MethodGet, "/tweets/abc", nil, http.StatusBadRequest},
+		{http.MethodPut, "/tweets", nil, http.StatusMethodNotAllowed},
+		{http.MethodGet, "/send", nil, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/send?dry_run=maybe", nil, http.StatusBadRequest},
+		{http.MethodGet, "/unknown", nil, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		var errorResponse errorBody
+		response := server.do(t, test.method, test.path, test.body, &errorResponse)
+		if response.StatusCode != test.status || errorResponse.Error.Status != test.status || errorResponse.Error.Message == "" {
+			t.Fatalf("%s %s: Expected status %d. Result: %s %v", test.method, test.path, test.status, response.Status, errorResponse)
+		}
+	}
+}
+
+func TestEditDeliveredTweet(t *testing.T) {
+	server := newTestServer(t)
+
+	// A tweet that was sent before the server started and is kept as a record
+	sent := tweet.New("Hello", time.Now())
+	sent.Status = tweet.StatusSent
+	saved := tweet.TweetList{}
+	if err := saved.Add(sent); err != nil {
+		t.Fatal(err)
+	}
+	if err := saved.Save(server.config.Datastore.Filepath); err != nil {
+		t.Fatal(err)
+	}
+
+	var errorResponse errorBody
+	response := server.do(t, http.MethodPatch, "/tweets/"+sent.Id.String(), map[string]interface{}{"message": "Hello again"}, &errorResponse)
+	if response.StatusCode != http.StatusConflict || !strings.Contains(errorResponse.Error.Message, "forced") {
+		t.Fatalf("Unexpected response: %s %v", response.Status, errorResponse)
+	}
+
+	var edited tweet.Tweet
+	response = server.do(t, http.MethodPatch, "/tweets/"+sent.Id.String(), map[string]interface{}{"message": "Hello again", "force": true}, &edited)
+	if response.StatusCode != http.StatusOK || edited.Message != "Hello again" {
+		t.Fatalf("Unexpected response: %s %v", response.Status, edited)
+	}
+}
+
+func TestLocked(t *testing.T) {
+	server := newTestServer(t)
+
+	// Another instance (e.g. ajtweet send) holds the lock
+	if err := os.WriteFile(server.config.Lockfile, []byte("pid: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var errorResponse errorBody
+	response := server.do(t, http.MethodGet, "/tweets", nil, &errorResponse)
+	if response.StatusCode != http.StatusLocked || response.Header.Get("Retry-After") == "" {
+		t.Fatalf("Expected 423 Locked. Result: %s %v", response.Status, errorResponse)
+	}
+
+	// The lock is released after each request
+	os.Remove(server.config.Lockfile)
+	server.do(t, http.MethodGet, "/tweets", nil, nil)
+	if _, err := os.Stat(server.config.Lockfile); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the lock to be released. Result: %v", err)
+	}
+}
+
+func TestSend(t *testing.T) {
+	server := newTestServer(t)
+
+	now := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	server.do(t, http.MethodPost, "/tweets", map[string]interface{}{"message": "Send me", "scheduledTime": now}, nil)
+
+	var report app.SendReport
+	response := server.do(t, http.MethodPost, "/send?dry_run=true", nil, &report)
+	if response.StatusCode != http.StatusOK || !report.DryRun || report.Totals.DryRun != 1 || len(server.mock.Posts()) != 0 {
+		t.Fatalf("Unexpected response: %s %+v", response.Status, report)
+	}
+
+	response = server.do(t, http.MethodPost, "/send", nil, &report)
+	if response.StatusCode != http.StatusOK || report.Result != app.ResultAllSent || len(server.mock.Posts()) != 1 {
+		t.Fatalf("Unexpected response: %s %+v", response.Status, report)
+	}
+
+	var list tweetList
+	server.do(t, http.MethodGet, "/tweets", nil, &list)
+	if len(list.Tweets) != 0 {
+		t.Fatalf("Expected the sent tweet to be removed. Result: %v", list)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrApprovalNotRequired = errors.New("Tweet does not need to be approved")
+	ErrAlreadyApproved     = errors.New("Tweet has already been approved")
+	ErrApproverRequired    = errors.New("The name of the approver is required")
+)
+
+// Approval records who approved a tweet to be sent and when.
+type Approval struct {
+	By   string    `json:"by"`   // The name of the approver.
+	Time time.Time `json:"time"` // The time at which the tweet was approved.
+}
+
+// Require the tweet to be approved before it will be sent.
+// A scheduled tweet will be pending until it is approved.
+func (tweet *Tweet) RequireApproval() {
+	tweet.NeedsApproval = true
+	tweet.Approval = nil
+	if tweet.Status == StatusScheduled {
+		tweet.Status = StatusPending
+	}
+}
+
+// Return true if the tweet does not need to be approved or has been approved.
+func (tweet Tweet) IsApproved() bool {
+	return !tweet.NeedsApproval || tweet.Approval != nil
+}
+
+// Approve the tweet by the specified approver at the specified time.
+// A pending tweet will be scheduled to be sent.
+func (tweet *Tweet) Approve(by string, now time.Time) error {
+	by = strings.TrimSpace(by)
+	if by == "" {
+		return ErrApproverRequired
+	}
+
+	if !tweet.NeedsApproval {
+		return fmt.Errorf("%w: %q", ErrApprovalNotRequired, tweet.Id)
+	}
+
+	if tweet.Approval != nil {
+		return fmt.Errorf("%w: %q by %s", ErrAlreadyApproved, tweet.Id, tweet.Approval.By)
+	}
+
+	tweet.Approval = &Approval{By: by, Time: now}
+	if tweet.Status == StatusPending {
+		tweet.Status = StatusScheduled
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApprove(t *testing.T) {
+	tw := New("Tweet", time.Now())
+
+	if err := tw.Approve("alice", time.Now()); !errors.Is(err, ErrApprovalNotRequired) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrApprovalNotRequired, err)
+	}
+
+	tw.RequireApproval()
+	if tw.Status != StatusPending || tw.IsApproved() {
+		t.Fatalf("Expected the tweet to be pending approval. Result: %s", tw)
+	}
+
+	if err := tw.Approve(" ", time.Now()); !errors.Is(err, ErrApproverRequired) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrApproverRequired, err)
+	}
+
+	now := time.Date(2022, 5, 16, 19, 42, 0, 0, time.UTC)
+	if err := tw.Approve("alice", now); err != nil {
+		t.Fatal(err)
+	}
+
+	if tw.Status != StatusScheduled || !tw.IsApproved() {
+		t.Fatalf("Expected the tweet to be scheduled. Result: %s", tw)
+	}
+
+	if tw.Approval.By != "alice" || !tw.Approval.Time.Equal(now) {
+		t.Fatalf("Expected the approval to be recorded. Result: %v", tw.Approval)
+	}
+
+	if err := tw.Approve("bob", time.Now()); !errors.Is(err, ErrAlreadyApproved) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrAlreadyApproved, err)
+	}
+}
+
+func TestApproveDraft(t *testing.T) {
+	tw := New("Tweet", time.Now())
+	tw.Status = StatusDraft
+	tw.RequireApproval()
+
+	if tw.Status != StatusDraft {
+		t.Fatalf("Expected status: %q. Result: %q", StatusDraft, tw.Status)
+	}
+
+	// Resuming an unapproved draft makes it pending
+	if err := tw.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusPending {
+		t.Fatalf("Expected status: %q. Result: %q", StatusPending, tw.Status)
+	}
+
+	// Approving a draft does not schedule it
+	tw.Status = StatusDraft
+	if err := tw.Approve("alice", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusDraft {
+		t.Fatalf("Expected status: %q. Result: %q", StatusDraft, tw.Status)
+	}
+
+	if err := tw.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusScheduled {
+		t.Fatalf("Expected status: %q. Result: %q", StatusScheduled, tw.Status)
+	}
+}
+
+func TestToSendOnlyApproved(t *testing.T) {
+	list := TweetList{}
+
+	tw1 := New("Tweet1", time.Now().Add(-time.Minute))
+	tw1.RequireApproval()
+	list.Add(tw1)
+
+	// Not sent even when the status was changed without approval
+	tw2 := New("Tweet2", time.Now().Add(-time.Minute))
+	tw2.NeedsApproval = true
+	list.Add(tw2)
+
+	tw3 := New("Tweet3", time.Now().Add(-time.Minute))
+	tw3.RequireApproval()
+	tw3.Approve("alice", time.Now())
+	list.Add(tw3)
+
+	result := list.ToSend(100, time.Now())
+	if len(result) != 1 || result[0].Id != tw3.Id {
+		t.Fatalf("Expected only the approved tweet. Result: %v", result)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+// DestinationStatus is the delivery status of a tweet to one of its destinations.
+type DestinationStatus string
+
+const (
+	DestinationQueued DestinationStatus = "queued" // Still needs to be sent.
+	DestinationSent   DestinationStatus = "sent"   // Has been sent.
+	DestinationFailed DestinationStatus = "failed" // The last attempt at sending failed.
+)
+
+// Destination is an account the tweet will be sent to along with the delivery status.
+type Destination struct {
+	Account  string            `json:"account"`            // Reference to the account, e.g. mastodon:product.
+	Status   DestinationStatus `json:"status"`             // The delivery status.
+	RemoteId string            `json:"remoteId,omitempty"` // The identifier assigned by the service once sent.
+	URL      string            `json:"url,omitempty"`      // The URL at which the post can be viewed once sent.
+	Error    string            `json:"error,omitempty"`    // The error of the last failed attempt.
+}
+
+// Create destinations for the specified accounts that still need to be sent.
+func NewDestinations(accounts ...string) []Destination {
+	destinations := make([]Destination, len(accounts))
+	for i, account := range accounts {
+		destinations[i] = Destination{Account: account, Status: DestinationQueued}
+	}
+	return destinations
+}
+
+// Return the destinations the tweet will be sent to.
+// A tweet without destinations is only sent to its account.
+func (tweet Tweet) Targets() []Destination {
+	if len(tweet.Destinations) > 0 {
+		result := make([]Destination, len(tweet.Destinations))
+		copy(result, tweet.Destinations)
+		return result
+	}
+	return NewDestinations(tweet.Account)
+}
+
+// Return the accounts the tweet will be sent to, either the destinations or the tweet's account.
+func (tweet Tweet) Accounts() []string {
+	if len(tweet.Destinations) == 0 {
+		if tweet.Account == "" {
+			return nil
+		}
+		return []string{tweet.Account}
+	}
+
+	accounts := make([]string, len(tweet.Destinations))
+	for i, destination := range tweet.Destinations {
+		accounts[i] = destination.Account
+	}
+	return accounts
+}
+
+// Return true when the tweet is cross-posted and has been sent to all of its destinations.
+func (tweet Tweet) IsDelivered() bool {
+	if len(tweet.Destinations) == 0 {
+		return false
+	}
+	for _, destination := range tweet.Destinations {
+		if destination.Status != DestinationSent {
+			return false
+		}
+	}
+	return true
+}
+
+// Return true when the tweet has been sent to any of its destinations, e.g. a cross-post that failed for some of them.
+func (tweet Tweet) IsPartlyDelivered() bool {
+	if tweet.Status == StatusSent {
+		return true
+	}
+	for _, destination := range tweet.Destinations {
+		if destination.Status == DestinationSent {
+			return true
+		}
+	}
+	return false
+}
+
+// Record that the tweet was sent to the destination's account.
+func (tweet *Tweet) Delivered(account string, remoteId string, url string) {
+	tweet.updateDestination(account, func(destination *Destination) {
+		destination.Status = DestinationSent
+		destination.RemoteId = remoteId
+		destination.URL = url
+		destination.Error = ""
+	})
+}
+
+// Record that sending the tweet to the destination's account failed.
+func (tweet *Tweet) DeliveryFailed(account string, err error) {
+	tweet.updateDestination(account, func(destination *Destination) {
+		destination.Status = DestinationFailed
+		destination.Error = err.Error()
+	})
+}
+
+func (tweet *Tweet) updateDestination(account string, update func(destination *Destination)) {
+	for i := range tweet.Destinations {
+		if tweet.Destinations[i].Account == account {
+			update(&tweet.Destinations[i])
+		}
+	}
+}
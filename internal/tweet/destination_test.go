@@ -0,0 +1,109 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTargets(t *testing.T) {
+	tw := New("Tweet", time.Now())
+	if targets := tw.Targets(); len(targets) != 1 || targets[0].Account != "" || targets[0].Status != DestinationQueued {
+		t.Fatalf("Expected the default account. Result: %v", targets)
+	}
+	if accounts := tw.Accounts(); len(accounts) != 0 {
+		t.Fatalf("Expected no accounts. Result: %v", accounts)
+	}
+
+	tw.Account = "mastodon:product"
+	if accounts := tw.Accounts(); !reflect.DeepEqual(accounts, []string{"mastodon:product"}) {
+		t.Fatalf("Unexpected accounts: %v", accounts)
+	}
+
+	tw.Account = ""
+	tw.Destinations = NewDestinations("twitter:product", "mastodon:product")
+	if accounts := tw.Accounts(); !reflect.DeepEqual(accounts, []string{"twitter:product", "mastodon:product"}) {
+		t.Fatalf("Unexpected accounts: %v", accounts)
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	tw := New("Tweet", time.Now())
+	tw.Destinations = NewDestinations("twitter:product", "mastodon:product")
+
+	if tw.IsPartlyDelivered() {
+		t.Fatal("Expected the tweet to not have been sent to any destination")
+	}
+
+	tw.Delivered("twitter:product", "42", "https://twitter.com/i/web/status/42")
+	tw.DeliveryFailed("mastodon:product", errors.New("unavailable"))
+	if !tw.IsPartlyDelivered() || tw.IsDelivered() {
+		t.Fatal("Expected the tweet to have been sent to some of its destinations")
+	}
+
+	expected := []Destination{
+		{Account: "twitter:product", Status: DestinationSent, RemoteId: "42", URL: "https://twitter.com/i/web/status/42"},
+		{Account: "mastodon:product", Status: DestinationFailed, Error: "unavailable"},
+	}
+	if !reflect.DeepEqual(tw.Destinations, expected) {
+		t.Fatalf("Expected: %v. Result: %v", expected, tw.Destinations)
+	}
+
+	// The targets are a copy
+	targets := tw.Targets()
+	targets[0].Status = DestinationQueued
+	if tw.Destinations[0].Status != DestinationSent {
+		t.Fatal("Expected the targets to be a copy of the destinations")
+	}
+
+	tw.Delivered("mastodon:product", "101", "")
+	if tw.Destinations[1].Status != DestinationSent || tw.Destinations[1].Error != "" {
+		t.Fatalf("Expected the error to be cleared. Result: %v", tw.Destinations[1])
+	}
+}
+
+func TestForAccountWithDestinations(t *testing.T) {
+	tw := New("Tweet", time.Now())
+	tw.Destinations = NewDestinations("twitter:product", "bluesky:news")
+
+	tests := []struct {
+		account  string
+		expected bool
+	}{
+		{"news", true},
+		{"Bluesky:News", true},
+		{"mastodon:news", false},
+		{"product", true},
+		{"duct", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if result := ForAccount(test.account)(tw); result != test.expected {
+			t.Fatalf("%q: Expected: %t. Result: %t", test.account, test.expected, result)
+		}
+	}
+}
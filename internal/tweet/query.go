@@ -0,0 +1,148 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Predicate determines if a tweet matches (true) the criteria of a query or not (false).
+type Predicate func(tweet Tweet) bool
+
+// Return a slice of tweets that match all the predicates, ordered by which tweets need to be sent first.
+// limit Is the maximum number of tweets to return. Zero (or less) means there is no limit.
+func (list *TweetList) Query(limit int, predicates ...Predicate) []Tweet {
+	result := list.filter(filterFunc(All(predicates...)))
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].ScheduledTime.Before(result[j].ScheduledTime)
+	})
+
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// Match tweets that match all of the predicates.
+// If no predicates are specified then all tweets will match.
+func All(predicates ...Predicate) Predicate {
+	return func(tweet Tweet) bool {
+		for _, predicate := range predicates {
+			if !predicate(tweet) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Match tweets that are scheduled before the specified time.
+func Before(t time.Time) Predicate {
+	return func(tweet Tweet) bool {
+		return tweet.ScheduledTime.Before(t)
+	}
+}
+
+// Match tweets that are scheduled after the specified time.
+func After(t time.Time) Predicate {
+	return func(tweet Tweet) bool {
+		return tweet.ScheduledTime.After(t)
+	}
+}
+
+// Match tweets that need to be sent given the specified time, the same tweets that will be sent.
+// Paused, draft, pending and failed tweets are never due.
+func Due(now time.Time) Predicate {
+	return func(tweet Tweet) bool {
+		return tweet.IsDue(now)
+	}
+}
+
+// Match tweets of which the message contains the specified text (case insensitive).
+func Contains(text string) Predicate {
+	text = strings.ToLower(text)
+	return func(tweet Tweet) bool {
+		return strings.Contains(strings.ToLower(tweet.Message), text)
+	}
+}
+
+// Match tweets of which the message matches the regular expression.
+func Matches(re *regexp.Regexp) Predicate {
+	return func(tweet Tweet) bool {
+		return re.MatchString(tweet.Message)
+	}
+}
+
+// Match tweets that have at least one of the specified tags (case insensitive).
+func HasTag(tags ...string) Predicate {
+	return func(tweet Tweet) bool {
+		for _, tag := range tweet.Tags {
+			if containsFold(tags, tag) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Match tweets that will be sent from one of the specified accounts (case insensitive).
+// The account may be specified as service:name or only the name, e.g. product matches mastodon:product.
+func ForAccount(accounts ...string) Predicate {
+	return func(tweet Tweet) bool {
+		for _, destination := range tweet.Targets() {
+			account := destination.Account
+			for _, match := range accounts {
+				if strings.EqualFold(match, account) ||
+					(!strings.Contains(match, ":") && strings.HasSuffix(strings.ToLower(account), ":"+strings.ToLower(match))) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// Match tweets that have one of the specified statuses.
+func HasStatus(statuses ...Status) Predicate {
+	return func(tweet Tweet) bool {
+		for _, status := range statuses {
+			if tweet.Status == status {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
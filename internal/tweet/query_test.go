@@ -0,0 +1,124 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestQuery(t *testing.T) {
+	now := time.Now()
+
+	tw1 := New("Launch day!", now.Add(-5*time.Minute))
+	tw1.Tags = []string{"launch"}
+	tw1.Account = "product"
+
+	tw2 := New("Hello world", now.Add(5*time.Minute))
+	tw2.Tags = []string{"Launch", "campaign-x"}
+
+	tw3 := New("Another day", now.Add(10*time.Minute))
+	tw3.Account = "personal"
+
+	list := TweetList{}
+	list.Add(tw3)
+	list.Add(tw2)
+	list.Add(tw1)
+
+	testCases := []struct {
+		name       string
+		limit      int
+		predicates []Predicate
+		expected   []Tweet
+	}{
+		{"All sorted", 0, nil, []Tweet{tw1, tw2, tw3}},
+		{"Limit", 2, nil, []Tweet{tw1, tw2}},
+		{"Before", 0, []Predicate{Before(now)}, []Tweet{tw1}},
+		{"After", 0, []Predicate{After(now)}, []Tweet{tw2, tw3}},
+		{"Before and after", 0, []Predicate{After(now), Before(now.Add(7 * time.Minute))}, []Tweet{tw2}},
+		{"Due", 0, []Predicate{Due(now)}, []Tweet{tw1}},
+		{"Contains", 0, []Predicate{Contains("DAY")}, []Tweet{tw1, tw3}},
+		{"Matches", 0, []Predicate{Matches(regexp.MustCompile(`^(Hello|Another)`))}, []Tweet{tw2, tw3}},
+		{"Tag", 0, []Predicate{HasTag("launch")}, []Tweet{tw1, tw2}},
+		{"Any tag", 0, []Predicate{HasTag("nope", "campaign-x")}, []Tweet{tw2}},
+		{"Account", 0, []Predicate{ForAccount("Product", "personal")}, []Tweet{tw1, tw3}},
+		{"No account", 0, []Predicate{ForAccount("")}, []Tweet{tw2}},
+		{"No match", 0, []Predicate{Contains("day"), HasTag("campaign-x")}, []Tweet{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := list.Query(tc.limit, tc.predicates...)
+			if len(result) != len(tc.expected) {
+				t.Fatalf("Expected %d tweets. Result: %d", len(tc.expected), len(result))
+			}
+
+			for i, tw := range result {
+				if tw.Id != tc.expected[i].Id {
+					t.Errorf("Expected %q. Result %q", tc.expected[i].Message, tw.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-5 * time.Minute)
+
+	due := New("Due", past)
+	notYet := New("Not yet", now.Add(5*time.Minute))
+
+	paused := New("Paused", past)
+	if err := paused.Pause(); err != nil {
+		t.Fatal(err)
+	}
+
+	draft := New("Draft", past)
+	draft.Status = StatusDraft
+
+	pending := New("Pending", past)
+	pending.RequireApproval()
+
+	failed := New("Failed", past)
+	failed.Status = StatusFailed
+
+	list := TweetList{}
+	for _, tw := range []Tweet{due, notYet, paused, draft, pending, failed} {
+		list.Add(tw)
+	}
+
+	result := list.Query(0, Due(now))
+	if len(result) != 1 || result[0].Id != due.Id {
+		t.Fatalf("Expected only the scheduled tweet to be due. Result: %v", result)
+	}
+
+	// The list, the stats and send agree on which tweets are due
+	if stats := list.Stats(now); stats.Due != len(result) {
+		t.Fatalf("Expected %d due tweet(s) in the stats. Result: %d", len(result), stats.Due)
+	}
+	if sendable := list.ToSend(10, now); len(sendable) != 1 || sendable[0].Id != due.Id {
+		t.Fatalf("Expected only the due tweet to be sent. Result: %v", sendable)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAmbiguous      = errors.New("Tweet identifier is ambiguous")
+	ErrPrefixTooShort = errors.New("Tweet identifier is too short")
+)
+
+const (
+	// The minimum number of characters used when displaying short identifiers.
+	ShortIdLength = 7
+	// The minimum number of characters required when resolving an identifier prefix.
+	MinPrefixLength = 4
+)
+
+// Resolve a full identifier or a unique prefix of one into the identifier of a tweet in the list.
+// If the prefix matches more than one tweet then ErrAmbiguous is returned along with the candidates.
+func (list *TweetList) Resolve(idString string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(idString); err == nil {
+		if found, _ := list.Find(id); !found {
+			return uuid.Nil, fmt.Errorf("%w: %q", ErrNotExists, id)
+		}
+		return id, nil
+	}
+
+	prefix := strings.ToLower(strings.TrimSpace(idString))
+	if len(prefix) < MinPrefixLength {
+		return uuid.Nil, fmt.Errorf("%w: %q must be at least %d characters", ErrPrefixTooShort, idString, MinPrefixLength)
+	}
+
+	var candidates []uuid.UUID
+	for _, tweet := range list.Tweets {
+		if strings.HasPrefix(tweet.Id.String(), prefix) {
+			candidates = append(candidates, tweet.Id)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return uuid.Nil, fmt.Errorf("%w: %q", ErrNotExists, idString)
+	case 1:
+		return candidates[0], nil
+	}
+
+	names := make([]string, len(candidates))
+	for i, id := range candidates {
+		names[i] = id.String()
+	}
+	sort.Strings(names)
+	return uuid.Nil, fmt.Errorf("%w: %q matches %s", ErrAmbiguous, idString, strings.Join(names, ", "))
+}
+
+// Return the shortest unique prefix of each tweet's identifier, similar to git's short hashes.
+// minLength Is the minimum number of characters used for each prefix.
+func (list *TweetList) ShortIds(minLength int) map[uuid.UUID]string {
+	ids := make([]string, len(list.Tweets))
+	for i, tweet := range list.Tweets {
+		ids[i] = tweet.Id.String()
+	}
+	sort.Strings(ids)
+
+	// Since the identifiers are sorted, only the neighbours can share the longest common prefix
+	result := make(map[uuid.UUID]string, len(ids))
+	for i, id := range ids {
+		length := minLength
+		if i > 0 {
+			length = max(length, commonPrefixLength(id, ids[i-1])+1)
+		}
+		if i < len(ids)-1 {
+			length = max(length, commonPrefixLength(id, ids[i+1])+1)
+		}
+		length = min(length, len(id))
+
+		result[uuid.MustParse(id)] = id[:length]
+	}
+
+	return result
+}
+
+func commonPrefixLength(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,110 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newWithId(id string) Tweet {
+	tw := New("Tweet", time.Now())
+	tw.Id = uuid.MustParse(id)
+	return tw
+}
+
+func TestResolve(t *testing.T) {
+	list := TweetList{}
+	tw1 := newWithId("28cf75a1-e7b3-4401-a878-4362bdc4befe")
+	tw2 := newWithId("28cf75b2-0b61-4a89-b52e-82deae2e3aa8")
+	tw3 := newWithId("a2fdb340-0b61-4a89-b52e-82deae2e3aa8")
+	list.Add(tw1)
+	list.Add(tw2)
+	list.Add(tw3)
+
+	testCases := []struct {
+		name     string
+		idString string
+		expected uuid.UUID
+		err      error
+	}{
+		{"Full identifier", tw1.Id.String(), tw1.Id, nil},
+		{"Unique prefix", "28cf75a", tw1.Id, nil},
+		{"Upper case prefix", "A2FD", tw3.Id, nil},
+		{"Prefix with dash", "28cf75b2-0b", tw2.Id, nil},
+		{"Ambiguous", "28cf", uuid.Nil, ErrAmbiguous},
+		{"Too short", "a2f", uuid.Nil, ErrPrefixTooShort},
+		{"Not found", "ffff", uuid.Nil, ErrNotExists},
+		{"Full identifier not found", uuid.NewString(), uuid.Nil, ErrNotExists},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, err := list.Resolve(tc.idString)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("Expected error: %v. Result: %v", tc.err, err)
+			}
+			if id != tc.expected {
+				t.Fatalf("Expected: %q. Result: %q", tc.expected, id)
+			}
+		})
+	}
+
+	// The candidates must be listed when the prefix is ambiguous
+	_, err := list.Resolve("28cf")
+	if !strings.Contains(err.Error(), tw1.Id.String()) || !strings.Contains(err.Error(), tw2.Id.String()) {
+		t.Fatalf("Expected the candidates to be listed. Result: %s", err)
+	}
+}
+
+func TestShortIds(t *testing.T) {
+	list := TweetList{}
+	tw1 := newWithId("28cf75a1-e7b3-4401-a878-4362bdc4befe")
+	tw2 := newWithId("28cf75a1-e7b9-4a89-b52e-82deae2e3aa8")
+	tw3 := newWithId("a2fdb340-0b61-4a89-b52e-82deae2e3aa8")
+	list.Add(tw1)
+	list.Add(tw2)
+	list.Add(tw3)
+
+	result := list.ShortIds(ShortIdLength)
+
+	expected := map[uuid.UUID]string{
+		tw1.Id: "28cf75a1-e7b3",
+		tw2.Id: "28cf75a1-e7b9",
+		tw3.Id: "a2fdb34",
+	}
+
+	for id, short := range expected {
+		if result[id] != short {
+			t.Errorf("Expected: %q. Result: %q", short, result[id])
+		}
+
+		if resolved, err := list.Resolve(short); err != nil || resolved != id {
+			t.Errorf("Expected %q to resolve to %q. Result: %q, %v", short, id, resolved, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Status of a tweet.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"     // Not ready to be sent.
+	StatusPending   Status = "pending"   // Waiting to be approved before it will be sent.
+	StatusScheduled Status = "scheduled" // Will be sent once the scheduled time has passed.
+	StatusPaused    Status = "paused"    // Will not be sent until it is resumed.
+	StatusSending   Status = "sending"   // In the process of being sent.
+	StatusSent      Status = "sent"      // Has been sent to all of its destinations and is kept as a record.
+	StatusFailed    Status = "failed"    // Failed to be sent too many times.
+	StatusExpired   Status = "expired"   // Was not sent before it expired.
+)
+
+var (
+	ErrInvalidStatus     = errors.New("Invalid tweet status")
+	ErrInvalidTransition = errors.New("Invalid tweet status transition")
+)
+
+// All the valid statuses.
+var Statuses = []Status{
+	StatusDraft, StatusPending, StatusScheduled, StatusPaused, StatusSending, StatusSent, StatusFailed, StatusExpired,
+}
+
+// Parse the status from a string, e.g. "paused".
+func ParseStatus(value string) (Status, error) {
+	status := Status(strings.ToLower(strings.TrimSpace(value)))
+	for _, s := range Statuses {
+		if s == status {
+			return status, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidStatus, value)
+}
+
+// Pause a scheduled tweet so that it will not be sent until it is resumed.
+func (tweet *Tweet) Pause() error {
+	return tweet.transition(StatusPaused, StatusScheduled)
+}
+
+// Resume sending a tweet that is a draft, paused, failed or expired, or that is still marked as being sent
+// because the send was killed.
+// A tweet that still needs to be approved will be pending instead of scheduled.
+func (tweet *Tweet) Resume() error {
+	to := StatusScheduled
+	if !tweet.IsApproved() {
+		to = StatusPending
+	}
+
+	if err := tweet.transition(to, StatusDraft, StatusPaused, StatusFailed, StatusExpired, StatusSending); err != nil {
+		return err
+	}
+
+	tweet.Attempts = 0
+	tweet.Error = ""
+	return nil
+}
+
+// Change the status to the specified status if the current status is one of the allowed statuses.
+func (tweet *Tweet) transition(to Status, from ...Status) error {
+	for _, status := range from {
+		if tweet.Status == status {
+			tweet.Status = to
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q can not be changed from %s to %s", ErrInvalidTransition, tweet.Id, tweet.Status, to)
+}
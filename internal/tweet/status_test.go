@@ -0,0 +1,162 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package tweet
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseStatus(t *testing.T) {
+	for _, status := range Statuses {
+		if result, err := ParseStatus(string(status)); err != nil || result != status {
+			t.Fatalf("Expected: %q. Result: %q, %v", status, result, err)
+		}
+	}
+
+	if result, err := ParseStatus(" Paused "); err != nil || result != StatusPaused {
+		t.Fatalf("Expected: %q. Result: %q, %v", StatusPaused, result, err)
+	}
+
+	if _, err := ParseStatus("unknown"); !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidStatus, err)
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	tw := New("Tweet", time.Now())
+
+	if err := tw.Resume(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidTransition, err)
+	}
+
+	if err := tw.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusPaused {
+		t.Fatalf("Expected status: %q. Result: %q", StatusPaused, tw.Status)
+	}
+
+	if err := tw.Pause(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidTransition, err)
+	}
+
+	if err := tw.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusScheduled {
+		t.Fatalf("Expected status: %q. Result: %q", StatusScheduled, tw.Status)
+	}
+
+	// Resuming a failed tweet resets the attempts
+	tw.Status = StatusFailed
+	tw.Attempts = 3
+	tw.Error = "failed"
+	if err := tw.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.Status != StatusScheduled || tw.Attempts != 0 || tw.Error != "" {
+		t.Fatalf("Expected the failed attempts to be reset. Result: %s", tw)
+	}
+
+	// Tweets left being sent by a send that was killed can be resumed
+	tw.Status = StatusSending
+	if err := tw.Resume(); err != nil || tw.Status != StatusScheduled {
+		t.Fatalf("Expected the tweet to be resumed. Result: %s, error: %v", tw, err)
+	}
+
+	// Pending tweets can not be resumed
+	tw.Status = StatusPending
+	if err := tw.Resume(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidTransition, err)
+	}
+
+	// Sent tweets can not be resumed or paused
+	tw.Status = StatusSent
+	if err := tw.Resume(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidTransition, err)
+	}
+	if err := tw.Pause(); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("Expected error: %q. Result: %q", ErrInvalidTransition, err)
+	}
+}
+
+func TestToSendOnlyScheduled(t *testing.T) {
+	list := TweetList{}
+
+	for _, status := range Statuses {
+		tw := New(string(status), time.Now().Add(-time.Minute))
+		tw.Status = status
+		list.Add(tw)
+	}
+
+	result := list.ToSend(100, time.Now())
+	if len(result) != 1 || result[0].Status != StatusScheduled {
+		t.Fatalf("Expected only the scheduled tweet. Result: %v", result)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	list := TweetList{}
+	tw1 := New("Tweet1", time.Now().Add(-48*time.Hour))
+	tw2 := New("Tweet2", time.Now().Add(-1*time.Hour))
+	tw3 := New("Tweet3", time.Now().Add(-48*time.Hour))
+	tw3.Status = StatusPaused
+	list.Add(tw1)
+	list.Add(tw2)
+	list.Add(tw3)
+
+	expired := list.Expire(time.Now().Add(-24 * time.Hour))
+	if len(expired) != 1 || expired[0].Id != tw1.Id {
+		t.Fatalf("Expected only %q to expire. Result: %v", tw1.Id, expired)
+	}
+
+	if list.Tweets[0].Status != StatusExpired || list.Tweets[1].Status != StatusScheduled || list.Tweets[2].Status != StatusPaused {
+		t.Fatalf("Statuses do not meet expectations. Result: %v", list.Tweets)
+	}
+}
+
+func TestLoadWithoutStatus(t *testing.T) {
+	tempFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %s", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	data := `{"Tweets":[{"id":"28cf75a1-e7b3-4401-a878-4362bdc4befe","message":"Tweet1","scheduledTime":"2022-05-24T21:55:00Z"}]}`
+	if _, err := tempFile.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+	tempFile.Close()
+
+	list := TweetList{}
+	if err := list.Load(tempFile.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Tweets[0].Status != StatusScheduled {
+		t.Fatalf("Expected status: %q. Result: %q", StatusScheduled, list.Tweets[0].Status)
+	}
+}
@@ -32,9 +32,19 @@ import (
 
 // Tweet represents a single scheduled tweet to be sent to Twitter.
 type Tweet struct {
-	Id            uuid.UUID `json:"id"`            // The unique identifier for the tweet.
-	Message       string    `json:"message"`       // The message to be posted to twitter.
-	ScheduledTime time.Time `json:"scheduledTime"` // The preferred scheduled time at which the tweet needs to be sent.
+	Id            uuid.UUID     `json:"id"`                      // The unique identifier for the tweet.
+	Message       string        `json:"message"`                 // The message to be posted to twitter.
+	ScheduledTime time.Time     `json:"scheduledTime"`           // The preferred scheduled time at which the tweet needs to be sent.
+	Account       string        `json:"account,omitempty"`       // The account the tweet will be sent from.
+	Tags          []string      `json:"tags,omitempty"`          // Tags used for organising the tweets, e.g. a campaign name.
+	Status        Status        `json:"status"`                  // The status of the tweet, only scheduled tweets will be sent.
+	Attempts      int           `json:"attempts,omitempty"`      // The number of failed attempts at sending the tweet.
+	Error         string        `json:"error,omitempty"`         // The error of the last failed attempt.
+	NeedsApproval bool          `json:"needsApproval,omitempty"` // The tweet needs to be approved before it will be sent.
+	Approval      *Approval     `json:"approval,omitempty"`      // Who approved the tweet and when.
+	Media         []string      `json:"media,omitempty"`         // Paths of the media files (e.g. images) to be attached.
+	InReplyTo     string        `json:"inReplyTo,omitempty"`     // Identifier of the post (on the account's service) this is a reply to.
+	Destinations  []Destination `json:"destinations,omitempty"`  // The accounts to cross-post to, instead of only the tweet's account.
 }
 
 // Create a new Tweet given the specified message and preferred scheduled time.
@@ -43,6 +53,7 @@ func New(message string, scheduledTime time.Time) Tweet {
 		Id:            uuid.New(),
 		Message:       message,
 		ScheduledTime: scheduledTime,
+		Status:        StatusScheduled,
 	}
 	return tweet
 }
@@ -52,12 +63,23 @@ func (tweet Tweet) SendNow() bool {
 	return tweet.ScheduledTime.Before(time.Now())
 }
 
+// Return true if the tweet is scheduled to be sent, i.e. it is not a draft, paused, sent etc.
+func (tweet Tweet) IsScheduled() bool {
+	return tweet.Status == StatusScheduled
+}
+
 // Return true if the tweet needs to be sent given the specified time.
 func (tweet Tweet) SendWhen(now time.Time) bool {
 	return tweet.ScheduledTime.Before(now)
 }
 
+// Return true if the tweet is due to be sent given the specified time, i.e. it is scheduled, approved and
+// the scheduled time has passed.
+func (tweet Tweet) IsDue(now time.Time) bool {
+	return tweet.IsScheduled() && tweet.IsApproved() && tweet.SendWhen(now)
+}
+
 // Stringer implementation.
 func (tweet Tweet) String() string {
-	return fmt.Sprintf("id: %s, time: %s, tweet: %s", tweet.Id, tweet.ScheduledTime, tweet.Message)
+	return fmt.Sprintf("id: %s, time: %s, status: %s, tweet: %s", tweet.Id, tweet.ScheduledTime, tweet.Status, tweet.Message)
 }
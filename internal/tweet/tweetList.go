@@ -67,6 +67,23 @@ func (list *TweetList) Add(tweet Tweet) error {
 	return nil
 }
 
+// Add all the tweets to the list.
+// Either all the tweets are added or none of them are, in which case an error will be returned.
+func (list *TweetList) AddAll(tweets []Tweet) error {
+	original := list.Tweets
+	list.Tweets = make([]Tweet, len(original), len(original)+len(tweets))
+	copy(list.Tweets, original)
+
+	for _, tweet := range tweets {
+		if err := list.Add(tweet); err != nil {
+			list.Tweets = original
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Delete the tweet matching the specified identifier.
 // If the tweet could not be found then an error will be returned.
 func (list *TweetList) Delete(id uuid.UUID) error {
@@ -80,6 +97,24 @@ func (list *TweetList) Delete(id uuid.UUID) error {
 	return nil
 }
 
+// Update the tweet matching the specified identifier by calling the update function with the tweet.
+// If the update function returns an error then the tweet will not be changed.
+// If the tweet could not be found then an error will be returned.
+func (list *TweetList) Update(id uuid.UUID, update func(tweet *Tweet) error) error {
+	found, index := list.Find(id)
+	if !found {
+		return fmt.Errorf("%w: %q", ErrNotExists, id)
+	}
+
+	tweet := list.Tweets[index]
+	if err := update(&tweet); err != nil {
+		return err
+	}
+
+	list.Tweets[index] = tweet
+	return nil
+}
+
 // Delete all the tweets from the list
 func (list *TweetList) DeleteAll() error {
 	list.Tweets = nil
@@ -107,7 +142,7 @@ func (list *TweetList) ToSend(max int, now time.Time) []Tweet {
 	}
 
 	sendable := list.filter(func(tweet Tweet) bool {
-		return tweet.SendWhen(now)
+		return tweet.IsDue(now)
 	})
 
 	sort.SliceStable(sendable, func(i, j int) bool {
@@ -121,6 +156,70 @@ func (list *TweetList) ToSend(max int, now time.Time) []Tweet {
 	return result
 }
 
+// Change the status of the scheduled tweets that should have been sent before the specified time to expired.
+// Return the tweets that have expired.
+func (list *TweetList) Expire(before time.Time) []Tweet {
+	var expired []Tweet
+	for i, tweet := range list.Tweets {
+		if tweet.IsScheduled() && tweet.SendWhen(before) {
+			list.Tweets[i].Status = StatusExpired
+			expired = append(expired, list.Tweets[i])
+		}
+	}
+	return expired
+}
+
+// Change the status of the tweets that are still being sent to failed, with the reason as the error.
+// Used when a previous send was killed before it could record the outcome, e.g. by a second interrupt.
+// The cross-posted tweets that were recorded as sent to all of their destinations are changed to sent instead.
+// Return the tweets that have been sent and the tweets that have failed.
+func (list *TweetList) RecoverSending(reason string) (sent []Tweet, failed []Tweet) {
+	for i, tweet := range list.Tweets {
+		if tweet.Status != StatusSending {
+			continue
+		}
+
+		if tweet.IsDelivered() {
+			list.Tweets[i].Status = StatusSent
+			list.Tweets[i].Error = ""
+			sent = append(sent, list.Tweets[i])
+		} else {
+			list.Tweets[i].Status = StatusFailed
+			list.Tweets[i].Error = reason
+			failed = append(failed, list.Tweets[i])
+		}
+	}
+	return sent, failed
+}
+
+// Stats summarises the tweets in a TweetList at a point in time.
+type Stats struct {
+	ByStatus      map[Status]int // The number of tweets with each of the statuses.
+	Due           int            // The number of tweets that are due to be sent but have not been sent yet.
+	OldestOverdue time.Duration  // How long the oldest due tweet has been waiting to be sent (0 when none are due).
+}
+
+// Return the Stats of the tweets in the list at the specified time.
+func (list *TweetList) Stats(now time.Time) Stats {
+	stats := Stats{ByStatus: make(map[Status]int, len(Statuses))}
+	for _, status := range Statuses {
+		stats.ByStatus[status] = 0
+	}
+
+	for _, tweet := range list.Tweets {
+		stats.ByStatus[tweet.Status]++
+
+		if tweet.IsDue(now) {
+			stats.Due++
+			if overdue := now.Sub(tweet.ScheduledTime); overdue > stats.OldestOverdue {
+				stats.OldestOverdue = overdue
+			}
+		}
+	}
+
+	return stats
+}
+
 // Load the list of tweets from a JSON encoded file at the specified filePath.
 func (list *TweetList) Load(filePath string) error {
 	data, err := os.ReadFile(filePath)
@@ -135,7 +234,17 @@ func (list *TweetList) Load(filePath string) error {
 		return nil
 	}
 
-	return json.Unmarshal(data, list)
+	if err := json.Unmarshal(data, list); err != nil {
+		return err
+	}
+
+	// Tweets saved before the status was introduced are all scheduled
+	for i := range list.Tweets {
+		if list.Tweets[i].Status == "" {
+			list.Tweets[i].Status = StatusScheduled
+		}
+	}
+	return nil
 }
 
 // Save the list of tweets to a JSON encoded file at the specified filePath.
@@ -156,6 +265,13 @@ func min(a, b int) int {
 	return b
 }
 
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // Determine if a tweet needs to be kept (true) or removed (false)
 type filterFunc func(tweet Tweet) bool
 
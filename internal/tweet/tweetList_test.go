@@ -26,6 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -51,11 +52,11 @@ func TestAdd(t *testing.T) {
 		t.Fatal("Expected 2 tweets to be added")
 	}
 
-	if list.Tweets[0] != tw1 {
+	if !reflect.DeepEqual(list.Tweets[0], tw1) {
 		t.Fatalf("Expected tweet Id %q at index 0", tw1.Id)
 	}
 
-	if list.Tweets[1] != tw2 {
+	if !reflect.DeepEqual(list.Tweets[1], tw2) {
 		t.Fatalf("Expected tweet Id %q at index 1", tw2.Id)
 	}
 
@@ -260,3 +261,103 @@ func TestLoadNoFile(t *testing.T) {
 		t.Errorf("Not expecting an error. Result: %q", err)
 	}
 }
+
+func TestAddAll(t *testing.T) {
+	list := TweetList{}
+	tw1 := New("Tweet1", time.Now())
+	tw2 := New("Tweet2", time.Now())
+	tw3 := New("Tweet3", time.Now())
+
+	if err := list.Add(tw1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := list.AddAll([]Tweet{tw2, tw3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := len(list.Tweets); count != 3 {
+		t.Fatalf("Expected %d tweets. Result: %d", 3, count)
+	}
+
+	// Nothing may be added when one of the tweets is invalid
+	tw4 := New("Tweet4", time.Now())
+	if err := list.AddAll([]Tweet{tw4, tw1}); !errors.Is(err, ErrExists) {
+		t.Fatalf("Expected error: %q, Result: %q", ErrExists, err)
+	}
+
+	if count := len(list.Tweets); count != 3 {
+		t.Fatalf("Expected %d tweets. Result: %d", 3, count)
+	}
+
+	if found, _ := list.Find(tw4.Id); found {
+		t.Fatal("Tweet should not have been added to the list")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	list := TweetList{}
+	tw := New("Tweet1", time.Now())
+	list.Add(tw)
+
+	if err := list.Update(tw.Id, func(tweet *Tweet) error {
+		tweet.Message = "Updated"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Tweets[0].Message != "Updated" {
+		t.Fatalf("Expected the message to be updated. Result: %q", list.Tweets[0].Message)
+	}
+
+	// Nothing may be changed when the update fails
+	expectedErr := errors.New("failed")
+	if err := list.Update(tw.Id, func(tweet *Tweet) error {
+		tweet.Message = "Failed"
+		return expectedErr
+	}); err != expectedErr {
+		t.Fatalf("Expected error: %q, Result: %q", expectedErr, err)
+	}
+
+	if list.Tweets[0].Message != "Updated" {
+		t.Fatalf("Expected the message to remain unchanged. Result: %q", list.Tweets[0].Message)
+	}
+
+	if err := list.Update(New("Tweet2", time.Now()).Id, func(tweet *Tweet) error {
+		return nil
+	}); !errors.Is(err, ErrNotExists) {
+		t.Fatalf("Expected error: %q, Result: %q", ErrNotExists, err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var list TweetList
+	list.Add(New("Overdue", now.Add(-time.Hour)))
+	list.Add(New("Due", now.Add(-time.Minute)))
+	list.Add(New("Later", now.Add(time.Hour)))
+
+	paused := New("Paused", now.Add(-2*time.Hour))
+	paused.Status = StatusPaused
+	list.Add(paused)
+
+	pending := New("Pending", now.Add(-3*time.Hour))
+	pending.RequireApproval()
+	list.Add(pending)
+
+	stats := list.Stats(now)
+
+	if stats.ByStatus[StatusScheduled] != 3 || stats.ByStatus[StatusPaused] != 1 || stats.ByStatus[StatusPending] != 1 {
+		t.Fatalf("Unexpected counts: %v", stats.ByStatus)
+	}
+
+	if _, exists := stats.ByStatus[StatusFailed]; !exists {
+		t.Fatal("Expected all the statuses to be counted")
+	}
+
+	if stats.Due != 2 || stats.OldestOverdue != time.Hour {
+		t.Fatalf("Expected 2 due tweets, the oldest overdue by 1h. Result: %d, %s", stats.Due, stats.OldestOverdue)
+	}
+}
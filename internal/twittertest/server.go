@@ -0,0 +1,389 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+// twittertest is an internal package that provides a local mock of the Twitter API.
+// It is used by the tests and by the mock-server command to try out the app without posting real tweets.
+package twittertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CreateTweetPath = "/2/tweets"              // The v2 endpoint used to create a tweet.
+	MediaUploadPath = "/1.1/media/upload.json" // The v1.1 endpoint used to upload media.
+
+	DefaultRateLimit       = 200              // The number of tweets that may be created per window (user context).
+	DefaultRateLimitWindow = 15 * time.Minute // The window after which the rate limit is reset.
+
+	maxTweetLength = 280
+	firstId        = 1000000000000000000
+)
+
+// Options used to configure the behaviour of the Server.
+type Options struct {
+	RateLimit       int              // The number of tweets that may be created per window. Default is DefaultRateLimit.
+	RateLimitWindow time.Duration    // The duration of the rate limit window. Default is DefaultRateLimitWindow.
+	FailEvery       int              // Fail every nth request to create a tweet with a 503 Service Unavailable (0 = never).
+	Log             io.Writer        // The requests received are logged to this writer (nil = no logging).
+	Now             func() time.Time // Return the current time. Default is time.Now.
+}
+
+// Post is a tweet that was created on the Server.
+type Post struct {
+	Id        string
+	Text      string
+	InReplyTo string
+	MediaIds  []string
+	Time      time.Time
+}
+
+// Upload is a media file that was uploaded to the Server.
+type Upload struct {
+	MediaId     string
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// Server is an http.Handler that mimics the parts of the Twitter API used by the app.
+// It supports creating tweets (including replies and media), uploading media, rate limit headers and
+// the error payloads returned by the Twitter API.
+type Server struct {
+	options Options
+	logger  *log.Logger
+
+	mu          sync.Mutex
+	posts       []Post
+	uploads     []Upload
+	failures    []int // Status codes of the forced failures for the next requests to create a tweet.
+	requests    int   // The number of requests to create a tweet.
+	nextId      int64
+	windowStart time.Time
+	remaining   int
+}
+
+// Create a new Server using the specified options.
+func NewServer(options Options) *Server {
+	if options.RateLimit <= 0 {
+		options.RateLimit = DefaultRateLimit
+	}
+	if options.RateLimitWindow <= 0 {
+		options.RateLimitWindow = DefaultRateLimitWindow
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+	if options.Log == nil {
+		options.Log = io.Discard
+	}
+
+	return &Server{
+		options:   options,
+		logger:    log.New(options.Log, "", log.LstdFlags),
+		nextId:    firstId,
+		remaining: options.RateLimit,
+	}
+}
+
+// Return the tweets that have been created.
+func (s *Server) Posts() []Post {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Post(nil), s.posts...)
+}
+
+// Return the media that has been uploaded.
+func (s *Server) Uploads() []Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Upload(nil), s.uploads...)
+}
+
+// Make the next request to create a tweet fail with the error payload for the HTTP status code,
+// e.g. http.StatusTooManyRequests. Multiple failures are used in the order they were added.
+func (s *Server) FailNext(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statusCode)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case CreateTweetPath:
+		s.createTweet(w, r)
+	case MediaUploadPath:
+		s.uploadMedia(w, r)
+	default:
+		s.logger.Printf("%s %s not found", r.Method, r.URL.Path)
+		writeProblem(w, http.StatusNotFound, "Not Found Error", "Sorry, that page does not exist.")
+	}
+}
+
+// The JSON body of the request to create a tweet.
+type createRequest struct {
+	Text  string `json:"text"`
+	Reply *struct {
+		InReplyToTweetID string `json:"in_reply_to_tweet_id"`
+	} `json:"reply,omitempty"`
+	Media *struct {
+		MediaIDs []string `json:"media_ids"`
+	} `json:"media,omitempty"`
+}
+
+func (s *Server) createTweet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, "Method Not Allowed", "The method is not allowed for this endpoint.")
+		return
+	}
+
+	if !authorized(r) {
+		s.logger.Printf("POST %s unauthorized", r.URL.Path)
+		writeProblem(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if !s.takeRateLimit(w) {
+		s.logger.Printf("POST %s rate limit exceeded", r.URL.Path)
+		writeProblem(w, http.StatusTooManyRequests, "Too Many Requests", "Too Many Requests")
+		return
+	}
+
+	if statusCode, failed := s.nextFailure(); failed {
+		s.logger.Printf("POST %s failing with %d", r.URL.Path, statusCode)
+		writeFailure(w, statusCode)
+		return
+	}
+
+	var req createRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidRequest(w, "$.body", err.Error())
+		return
+	}
+
+	post := Post{Text: req.Text, Time: s.options.Now()}
+	if req.Reply != nil {
+		post.InReplyTo = req.Reply.InReplyToTweetID
+	}
+	if req.Media != nil {
+		post.MediaIds = req.Media.MediaIDs
+	}
+
+	if message := s.validate(post); message != "" {
+		s.logger.Printf("POST %s invalid: %s", r.URL.Path, message)
+		writeInvalidRequest(w, "text", message)
+		return
+	}
+
+	for _, existing := range s.posts {
+		if existing.Text == post.Text {
+			s.logger.Printf("POST %s duplicate: %q", r.URL.Path, post.Text)
+			writeProblem(w, http.StatusForbidden, "Forbidden", "You are not allowed to create a Tweet with duplicate content.")
+			return
+		}
+	}
+
+	post.Id = s.newId()
+	s.posts = append(s.posts, post)
+	s.logger.Printf("POST %s id=%s reply_to=%q media=%v text=%q", r.URL.Path, post.Id, post.InReplyTo, post.MediaIds, post.Text)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]string{"id": post.Id, "text": post.Text},
+	})
+}
+
+// Return a description of why the post is invalid or the empty string when it is valid.
+func (s *Server) validate(post Post) string {
+	if strings.TrimSpace(post.Text) == "" && len(post.MediaIds) == 0 {
+		return "Tweet text or media is required."
+	}
+
+	if length := len([]rune(post.Text)); length > maxTweetLength {
+		return fmt.Sprintf("Tweet text is too long (%d > %d characters).", length, maxTweetLength)
+	}
+
+	for _, id := range post.MediaIds {
+		if !s.uploaded(id) {
+			return fmt.Sprintf("Media id %s is invalid.", id)
+		}
+	}
+	return ""
+}
+
+func (s *Server) uploaded(mediaId string) bool {
+	for _, upload := range s.uploads {
+		if upload.MediaId == mediaId {
+			return true
+		}
+	}
+	return false
+}
+
+// Consume one request from the rate limit window and set the rate limit headers.
+// Return false when the rate limit has been exceeded.
+func (s *Server) takeRateLimit(w http.ResponseWriter) bool {
+	now := s.options.Now()
+	if s.windowStart.IsZero() || !now.Before(s.windowStart.Add(s.options.RateLimitWindow)) {
+		s.windowStart = now
+		s.remaining = s.options.RateLimit
+	}
+
+	allowed := s.remaining > 0
+	if allowed {
+		s.remaining--
+	}
+
+	reset := s.windowStart.Add(s.options.RateLimitWindow)
+	w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(s.options.RateLimit))
+	w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(s.remaining))
+	w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return allowed
+}
+
+// Return the status code of the forced failure for the current request, if any.
+func (s *Server) nextFailure() (int, bool) {
+	if len(s.failures) > 0 {
+		statusCode := s.failures[0]
+		s.failures = s.failures[1:]
+		return statusCode, true
+	}
+
+	if s.options.FailEvery > 0 && s.requests%s.options.FailEvery == 0 {
+		return http.StatusServiceUnavailable, true
+	}
+	return 0, false
+}
+
+func (s *Server) newId() string {
+	s.nextId++
+	return strconv.FormatInt(s.nextId, 10)
+}
+
+func (s *Server) uploadMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeV1Error(w, http.StatusMethodNotAllowed, 0, "The method is not allowed for this endpoint.")
+		return
+	}
+
+	if !authorized(r) {
+		s.logger.Printf("POST %s unauthorized", r.URL.Path)
+		writeV1Error(w, http.StatusUnauthorized, 32, "Could not authenticate you.")
+		return
+	}
+
+	file, header, err := r.FormFile("media")
+	if err != nil {
+		writeV1Error(w, http.StatusBadRequest, 38, "media parameter is missing.")
+		return
+	}
+	defer file.Close()
+
+	size, err := io.Copy(io.Discard, file)
+	if err != nil {
+		writeV1Error(w, http.StatusBadRequest, 324, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload := Upload{
+		MediaId:     s.newId(),
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        size,
+	}
+	s.uploads = append(s.uploads, upload)
+	s.logger.Printf("POST %s media_id=%s file=%q type=%s size=%d", r.URL.Path, upload.MediaId, upload.Filename, upload.ContentType, upload.Size)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"media_id":        json.Number(upload.MediaId),
+		"media_id_string": upload.MediaId,
+		"size":            upload.Size,
+	})
+}
+
+// Only check that an OAuth 1.0a header is present since the signature can not be verified without the secrets.
+func authorized(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Authorization"), "OAuth ")
+}
+
+// Write the problem payload returned by the v2 API.
+func writeProblem(w http.ResponseWriter, statusCode int, title string, detail string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"title":  title,
+		"detail": detail,
+		"type":   "about:blank",
+		"status": statusCode,
+	})
+}
+
+func writeInvalidRequest(w http.ResponseWriter, parameter string, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{
+			{"parameters": map[string][]string{parameter: {}}, "message": message},
+		},
+		"title":  "Invalid Request",
+		"detail": "One or more parameters to your request was invalid.",
+		"type":   "https://api.twitter.com/2/problems/invalid-request",
+	})
+}
+
+// Write the error payload for a forced failure.
+func writeFailure(w http.ResponseWriter, statusCode int) {
+	switch statusCode {
+	case http.StatusBadRequest:
+		writeInvalidRequest(w, "text", "The request is invalid.")
+	case http.StatusForbidden:
+		writeProblem(w, statusCode, "Forbidden", "You are not allowed to create a Tweet with duplicate content.")
+	default:
+		writeProblem(w, statusCode, http.StatusText(statusCode), http.StatusText(statusCode))
+	}
+}
+
+// Write the error payload returned by the v1.1 API.
+func writeV1Error(w http.ResponseWriter, statusCode int, code int, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]interface{}{{"code": code, "message": message}},
+	})
+}
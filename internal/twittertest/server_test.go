@@ -0,0 +1,164 @@
+/*
+Copyright © 2022 André Jacobs
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+
+package twittertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTweet(t *testing.T, server *httptest.Server, body string, authorization string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, server.URL+CreateTweetPath, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func TestCreateTweet(t *testing.T) {
+	var log bytes.Buffer
+	mock := NewServer(Options{Log: &log})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	res := createTweet(t, server, `{"text":"Hello","reply":{"in_reply_to_tweet_id":"42"}}`, "OAuth test")
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201. Result: %d", res.StatusCode)
+	}
+
+	var result struct {
+		Data struct {
+			Id   string `json:"id"`
+			Text string `json:"text"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := mock.Posts()
+	if len(posts) != 1 || posts[0].Id != result.Data.Id || posts[0].Text != "Hello" || posts[0].InReplyTo != "42" {
+		t.Fatalf("Unexpected posts: %v, result: %v", posts, result)
+	}
+
+	if res.Header.Get("X-Rate-Limit-Limit") != "200" || res.Header.Get("X-Rate-Limit-Remaining") != "199" {
+		t.Fatalf("Unexpected rate limit headers: %v", res.Header)
+	}
+
+	if !strings.Contains(log.String(), `text="Hello"`) {
+		t.Fatalf("Expected the post to be logged. Result: %q", log.String())
+	}
+}
+
+func TestCreateTweetErrors(t *testing.T) {
+	mock := NewServer(Options{})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		body          string
+		authorization string
+		expected      int
+	}{
+		{"Unauthorized", `{"text":"Hello"}`, "", http.StatusUnauthorized},
+		{"Empty text", `{"text":" "}`, "OAuth test", http.StatusBadRequest},
+		{"Too long", `{"text":"` + strings.Repeat("a", 281) + `"}`, "OAuth test", http.StatusBadRequest},
+		{"Unknown media", `{"text":"Hello","media":{"media_ids":["1"]}}`, "OAuth test", http.StatusBadRequest},
+		{"Created", `{"text":"Hello"}`, "OAuth test", http.StatusCreated},
+		{"Duplicate", `{"text":"Hello"}`, "OAuth test", http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := createTweet(t, server, tc.body, tc.authorization)
+			if res.StatusCode != tc.expected {
+				t.Fatalf("Expected %d. Result: %d", tc.expected, res.StatusCode)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	mock := NewServer(Options{RateLimit: 2, RateLimitWindow: time.Minute, Now: func() time.Time { return now }})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	for i, text := range []string{"one", "two"} {
+		if res := createTweet(t, server, `{"text":"`+text+`"}`, "OAuth test"); res.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected request %d to succeed. Result: %d", i+1, res.StatusCode)
+		}
+	}
+
+	res := createTweet(t, server, `{"text":"three"}`, "OAuth test")
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429. Result: %d", res.StatusCode)
+	}
+
+	expectedReset := "1654084860"
+	if res.Header.Get("X-Rate-Limit-Remaining") != "0" || res.Header.Get("X-Rate-Limit-Reset") != expectedReset {
+		t.Fatalf("Unexpected rate limit headers: %v", res.Header)
+	}
+
+	now = now.Add(time.Minute)
+	if res := createTweet(t, server, `{"text":"three"}`, "OAuth test"); res.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected the rate limit to be reset. Result: %d", res.StatusCode)
+	}
+}
+
+func TestForcedFailures(t *testing.T) {
+	mock := NewServer(Options{FailEvery: 3})
+	server := httptest.NewServer(mock)
+	defer server.Close()
+
+	mock.FailNext(http.StatusInternalServerError)
+
+	expected := []int{http.StatusInternalServerError, http.StatusCreated, http.StatusServiceUnavailable, http.StatusCreated}
+	for i, statusCode := range expected {
+		body := `{"text":"tweet ` + strconv.Itoa(i) + `"}`
+		if res := createTweet(t, server, body, "OAuth test"); res.StatusCode != statusCode {
+			t.Fatalf("Expected request %d to return %d. Result: %d", i+1, statusCode, res.StatusCode)
+		}
+	}
+
+	if count := len(mock.Posts()); count != 2 {
+		t.Fatalf("Expected 2 posts. Result: %d", count)
+	}
+}